@@ -19,17 +19,28 @@ import (
 	_ "net/http/pprof"
 	"os"
 
+	pclog "github.com/pingcap/log"
+	"github.com/pingcap/tidb/br/pkg/storage"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/spf13/pflag"
 	"go.uber.org/zap"
 
 	"github.com/pingcap/dumpling/v4/cli"
 	"github.com/pingcap/dumpling/v4/export"
+	"github.com/pingcap/dumpling/v4/log"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "verify" {
+		verify(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "server" {
+		server(os.Args[2:])
+		return
+	}
 	pflag.Usage = func() {
-		fmt.Fprint(os.Stderr, "Dumpling is a CLI tool that helps you dump MySQL/TiDB data\n\nUsage:\n  dumpling [flags]\n\nFlags:\n")
+		fmt.Fprint(os.Stderr, "Dumpling is a CLI tool that helps you dump MySQL/TiDB data\n\nUsage:\n  dumpling [flags]\n  dumpling verify -o <dir> [flags]\n  dumpling server [flags]\n\nFlags:\n")
 		pflag.PrintDefaults()
 	}
 	printVersion := pflag.BoolP("version", "V", false, "Print Dumpling version")
@@ -71,7 +82,9 @@ func main() {
 		fmt.Printf("\ncreate dumper failed: %s\n", err.Error())
 		os.Exit(1)
 	}
+	stopHandleSignals := handleThreadSignals(dumper)
 	err = dumper.Dump()
+	stopHandleSignals()
 	dumper.Close()
 	if err != nil {
 		dumper.L().Error("dump failed error stack info", zap.Error(err))
@@ -80,3 +93,77 @@ func main() {
 	}
 	dumper.L().Info("dump data successfully, dumpling will exit now")
 }
+
+// verify checks the files of a dump against its manifest.json
+func verify(args []string) {
+	flags := pflag.NewFlagSet("verify", pflag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprint(os.Stderr, "Verify the sizes and checksums of the files of a dump against its manifest.json\n\nUsage:\n  dumpling verify -o <dir> [flags]\n\nFlags:\n")
+		flags.PrintDefaults()
+	}
+	output := flags.StringP("output", "o", "", "The directory or external storage URL of the dump to verify")
+	storage.DefineFlags(flags)
+	_ = flags.Parse(args)
+	if *output == "" || flags.NArg() > 0 {
+		flags.Usage()
+		os.Exit(1)
+	}
+
+	conf := export.DefaultConfig()
+	conf.OutputDirPath = *output
+	if err := conf.BackendOptions.ParseFromFlags(flags); err != nil {
+		fmt.Printf("\nparse arguments failed: %+v\n", err)
+		os.Exit(1)
+	}
+	if err := export.VerifyManifest(context.Background(), conf); err != nil {
+		fmt.Printf("\nverify failed: %s\n", err.Error())
+		os.Exit(1)
+	}
+	fmt.Println("all the files match the manifest")
+}
+
+// server runs the dump jobs submitted over HTTP until it's killed
+func server(args []string) {
+	flags := pflag.NewFlagSet("server", pflag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprint(os.Stderr, "Run the dump jobs submitted to /api/v1/jobs over HTTP\n\nUsage:\n  dumpling server [flags]\n\nFlags:\n")
+		flags.PrintDefaults()
+	}
+	statusAddr := flags.String("status-addr", "127.0.0.1:8281", "The address of the job API, metrics and pprof")
+	token := flags.String("token", "", "The token required by the job API as the header \"Authorization: Bearer <token>\", it must be set unless --status-addr is a loopback address")
+	maxHistory := flags.Int("max-history", 100, "The max number of finished jobs to keep")
+	logLevel := flags.String("loglevel", "info", "Log level: {debug|info|warn|error|dpanic|panic|fatal}")
+	logFile := flags.StringP("logfile", "L", "", "Log file `path`, leave empty to write to console")
+	logFormat := flags.String("logfmt", "text", "Log `format`: {text|json}")
+	_ = flags.Parse(args)
+	if flags.NArg() > 0 {
+		flags.Usage()
+		os.Exit(1)
+	}
+
+	logger, props, err := log.InitAppLogger(&log.Config{
+		Level:  *logLevel,
+		File:   *logFile,
+		Format: *logFormat,
+	})
+	if err != nil {
+		fmt.Printf("\ninit logger failed: %s\n", err.Error())
+		os.Exit(1)
+	}
+	pclog.ReplaceGlobals(logger.Logger, props)
+	cli.LogLongVersion(logger)
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}))
+	registry.MustRegister(prometheus.NewGoCollector())
+	export.InitMetricsVector(prometheus.Labels{export.ServerJobLabel: ""})
+	export.RegisterMetrics(registry)
+	prometheus.DefaultGatherer = registry
+
+	s := export.NewServer(context.Background(), logger.Logger, *maxHistory, *token)
+	if err = s.Run(*statusAddr); err != nil {
+		logger.Error("dumpling server exits with error", zap.Error(err))
+		fmt.Printf("\ndumpling server failed: %s\n", err.Error())
+		os.Exit(1)
+	}
+}
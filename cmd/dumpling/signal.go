@@ -0,0 +1,49 @@
+// Copyright 2021 PingCAP, Inc. Licensed under Apache-2.0.
+
+//go:build !windows
+// +build !windows
+
+package main
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	"go.uber.org/zap"
+
+	"github.com/pingcap/dumpling/v4/export"
+)
+
+// handleThreadSignals adds a writer on SIGUSR1 and retires one on SIGUSR2 until stop is called
+func handleThreadSignals(dumper *export.Dumper) (stop func()) {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGUSR1, syscall.SIGUSR2)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case sig := <-sigCh:
+				threads := dumper.Threads()
+				if threads == 0 {
+					dumper.L().Warn("ignore signal because dumpling is not dumping data", zap.Stringer("signal", sig))
+					continue
+				}
+				if sig == syscall.SIGUSR1 {
+					threads++
+				} else if threads > 1 {
+					threads--
+				}
+				if err := dumper.SetThreads(threads); err != nil {
+					dumper.L().Warn("fail to change threads", zap.Stringer("signal", sig), zap.Error(err))
+				}
+			}
+		}
+	}()
+	return func() {
+		signal.Stop(sigCh)
+		close(done)
+	}
+}
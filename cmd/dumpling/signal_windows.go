@@ -0,0 +1,10 @@
+// Copyright 2021 PingCAP, Inc. Licensed under Apache-2.0.
+
+package main
+
+import "github.com/pingcap/dumpling/v4/export"
+
+// handleThreadSignals does nothing because there is no SIGUSR1 or SIGUSR2 on windows
+func handleThreadSignals(*export.Dumper) (stop func()) {
+	return func() {}
+}
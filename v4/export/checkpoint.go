@@ -0,0 +1,344 @@
+// Copyright 2021 PingCAP, Inc. Licensed under Apache-2.0.
+
+package export
+
+import (
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	tcontext "github.com/pingcap/dumpling/v4/context"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tidb/br/pkg/storage"
+	"go.uber.org/zap"
+)
+
+const (
+	checkpointFileName = "dumpling.checkpoint"
+	// checkpointFlushInterval is the min interval between two flushes of finished chunks
+	checkpointFlushInterval = time.Second
+)
+
+// checkpointMeta records the configurations which decide the content of output files.
+// A dump can only be resumed with the same configurations.
+type checkpointMeta struct {
+	Snapshot    string `json:"snapshot"`
+	Consistency string `json:"consistency"`
+	FileType    string `json:"file-type"`
+	Compress    string `json:"compress"`
+	Rows        uint64 `json:"rows"`
+	FileSize    uint64 `json:"file-size"`
+	Where       string `json:"where"`
+	Checksum    bool   `json:"checksum"`
+}
+
+func newCheckpointMeta(conf *Config) checkpointMeta {
+	return checkpointMeta{
+		Snapshot:    conf.Snapshot,
+		Consistency: conf.Consistency,
+		FileType:    conf.FileType,
+		Compress:    compressTypeName(conf.compressType()),
+		Rows:        conf.Rows,
+		FileSize:    conf.FileSize,
+		Where:       conf.Where,
+		Checksum:    conf.Checksum,
+	}
+}
+
+// checkpointChunk records a finished TaskTableData and the files written for it
+type checkpointChunk struct {
+	Database   string `json:"database"`
+	Table      string `json:"table"`
+	ChunkIndex int    `json:"chunk-index"`
+	// Queries are the queries selecting the data of the chunk, which contain its WHERE range
+	Queries  []string        `json:"queries"`
+	Files    []*ManifestFile `json:"files"`
+	Checksum *rowChecksum    `json:"checksum,omitempty"`
+}
+
+// checkpointData is the content of the checkpoint file
+type checkpointData struct {
+	checkpointMeta
+	Chunks []*checkpointChunk `json:"chunks"`
+	// CreatedFiles are the data files created by this and the resumed dumps, the ones not owned by a
+	// finished chunk are stale
+	CreatedFiles []string `json:"created-files,omitempty"`
+}
+
+type checkpointChunkKey struct {
+	database   string
+	table      string
+	chunkIndex int
+}
+
+func newCheckpointChunkKey(t *TaskTableData) checkpointChunkKey {
+	return checkpointChunkKey{database: t.Meta.DatabaseName(), table: t.Meta.TableName(), chunkIndex: t.ChunkIndex}
+}
+
+// checkpoint persists the finished table chunks of a dump in checkpointFileName if --checkpoint or --resume
+// is specified, so that a failed dump can be resumed by --resume. A resumed dump splits the tables again against the same snapshot, chunks
+// whose queries are the same as the finished ones are skipped and the others are dumped again.
+// All the methods are safe to be called on a nil checkpoint, which means checkpoint is disabled.
+type checkpoint struct {
+	mu       sync.Mutex
+	extStore storage.ExternalStorage
+	conf     *Config
+
+	chunks map[checkpointChunkKey]*checkpointChunk
+	// confirmed are the chunks skipped or finished in this run
+	confirmed map[checkpointChunkKey]struct{}
+	// createdFiles are the data files created by this and the resumed dumps, the ones not owned by a
+	// confirmed chunk are removed at last
+	createdFiles map[string]struct{}
+	dirty        bool
+	lastFlush    time.Time
+}
+
+func newCheckpoint(extStore storage.ExternalStorage, conf *Config) *checkpoint {
+	return &checkpoint{
+		extStore:     extStore,
+		conf:         conf,
+		chunks:       make(map[checkpointChunkKey]*checkpointChunk),
+		confirmed:    make(map[checkpointChunkKey]struct{}),
+		createdFiles: make(map[string]struct{}),
+	}
+}
+
+// loadCheckpoint loads the checkpoint of the last dump and adopts its snapshot. It returns a new checkpoint
+// if the last dump didn't leave a checkpoint.
+func loadCheckpoint(tctx *tcontext.Context, extStore storage.ExternalStorage, conf *Config) (*checkpoint, error) {
+	cp := newCheckpoint(extStore, conf)
+	exist, err := extStore.FileExists(tctx, checkpointFileName)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if !exist {
+		tctx.L().Info("no checkpoint found, dump from the beginning", zap.String("checkpoint", checkpointFileName))
+		return cp, nil
+	}
+	content, err := extStore.ReadFile(tctx, checkpointFileName)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	var data checkpointData
+	if err = json.Unmarshal(content, &data); err != nil {
+		return nil, errors.Annotatef(err, "fail to parse checkpoint %s, please remove it and dump again", checkpointFileName)
+	}
+
+	if conf.Snapshot == "" {
+		conf.Snapshot = data.Snapshot
+	}
+	if meta := newCheckpointMeta(conf); meta != data.checkpointMeta {
+		return nil, errors.Errorf("can't resume the dump because the configurations are different from the checkpoint, checkpoint: %+v, current: %+v",
+			data.checkpointMeta, meta)
+	}
+	for _, file := range data.CreatedFiles {
+		cp.createdFiles[file] = struct{}{}
+	}
+	for _, chunk := range data.Chunks {
+		cp.chunks[checkpointChunkKey{database: chunk.Database, table: chunk.Table, chunkIndex: chunk.ChunkIndex}] = chunk
+		for _, file := range chunk.Files {
+			cp.createdFiles[file.Name] = struct{}{}
+		}
+	}
+	tctx.L().Info("resume dump from checkpoint",
+		zap.String("snapshot", data.Snapshot),
+		zap.Int("finished chunks", len(data.Chunks)))
+	return cp, nil
+}
+
+// tableDataQueries returns the queries of a table chunk, or nil if the chunk can't be recorded in checkpoint
+func tableDataQueries(ir TableDataIR) []string {
+	switch td := ir.(type) {
+	case *tableData:
+		return []string{td.query}
+	case *multiQueriesChunk:
+		return td.queries
+	case *keyRangeTableData:
+		return []string{td.query()}
+	default:
+		return nil
+	}
+}
+
+func equalQueries(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// finishedChunk returns the table chunk if it's finished with the same queries in the last dump
+func (cp *checkpoint) finishedChunk(t *TaskTableData) (*checkpointChunk, bool) {
+	if cp == nil {
+		return nil, false
+	}
+	queries := tableDataQueries(t.Data)
+	if queries == nil {
+		return nil, false
+	}
+	key := newCheckpointChunkKey(t)
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	chunk, ok := cp.chunks[key]
+	if !ok || !equalQueries(chunk.Queries, queries) {
+		return nil, false
+	}
+	cp.confirmed[key] = struct{}{}
+	return chunk, true
+}
+
+// startChunk forgets the finished chunk of the last dump with the same chunk index, because its files will be overwritten
+func (cp *checkpoint) startChunk(tctx *tcontext.Context, t *TaskTableData) error {
+	if cp == nil {
+		return nil
+	}
+	key := newCheckpointChunkKey(t)
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	chunk, ok := cp.chunks[key]
+	if !ok {
+		return nil
+	}
+	for _, file := range chunk.Files {
+		cp.createdFiles[file.Name] = struct{}{}
+	}
+	delete(cp.chunks, key)
+	delete(cp.confirmed, key)
+	return cp.flushLocked(tctx)
+}
+
+// createFile records a data file before it's created, so that it's removed when the dump finishes if the
+// chunk writing it doesn't finish, e.g. the dump fails and the resumed dump splits the table differently.
+// The file is flushed with the finished chunks, only the files created in the last checkpointFlushInterval
+// before the dump is killed may be left.
+func (cp *checkpoint) createFile(tctx *tcontext.Context, name string) error {
+	if cp == nil {
+		return nil
+	}
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	if _, ok := cp.createdFiles[name]; ok {
+		return nil
+	}
+	cp.createdFiles[name] = struct{}{}
+	cp.dirty = true
+	return cp.flushIfDueLocked(tctx)
+}
+
+// finishChunk records the finished table chunk and its files
+func (cp *checkpoint) finishChunk(tctx *tcontext.Context, t *TaskTableData) error {
+	if cp == nil {
+		return nil
+	}
+	queries := tableDataQueries(t.Data)
+	if queries == nil {
+		return nil
+	}
+	key := newCheckpointChunkKey(t)
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	cp.chunks[key] = &checkpointChunk{
+		Database:   key.database,
+		Table:      key.table,
+		ChunkIndex: key.chunkIndex,
+		Queries:    queries,
+		Files:      t.Files,
+		Checksum:   t.Checksum,
+	}
+	cp.confirmed[key] = struct{}{}
+	cp.dirty = true
+	return cp.flushIfDueLocked(tctx)
+}
+
+// flushIfDueLocked flushes the checkpoint if it isn't flushed in checkpointFlushInterval
+func (cp *checkpoint) flushIfDueLocked(tctx *tcontext.Context) error {
+	if time.Since(cp.lastFlush) < checkpointFlushInterval {
+		return nil
+	}
+	return cp.flushLocked(tctx)
+}
+
+// flush writes the unsaved finished chunks to the checkpoint file
+func (cp *checkpoint) flush(tctx *tcontext.Context) error {
+	if cp == nil {
+		return nil
+	}
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	if !cp.dirty {
+		return nil
+	}
+	return cp.flushLocked(tctx)
+}
+
+func (cp *checkpoint) flushLocked(tctx *tcontext.Context) error {
+	data := checkpointData{
+		checkpointMeta: newCheckpointMeta(cp.conf),
+		Chunks:         make([]*checkpointChunk, 0, len(cp.chunks)),
+	}
+	for _, chunk := range cp.chunks {
+		data.Chunks = append(data.Chunks, chunk)
+	}
+	sort.Slice(data.Chunks, func(i, j int) bool {
+		a, b := data.Chunks[i], data.Chunks[j]
+		if a.Database != b.Database {
+			return a.Database < b.Database
+		}
+		if a.Table != b.Table {
+			return a.Table < b.Table
+		}
+		return a.ChunkIndex < b.ChunkIndex
+	})
+	for file := range cp.createdFiles {
+		data.CreatedFiles = append(data.CreatedFiles, file)
+	}
+	sort.Strings(data.CreatedFiles)
+	content, err := json.Marshal(data)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if err = cp.extStore.WriteFile(tctx, checkpointFileName, content); err != nil {
+		return errors.Annotatef(err, "fail to write checkpoint %s", checkpointFileName)
+	}
+	cp.dirty = false
+	cp.lastFlush = time.Now()
+	return nil
+}
+
+// finish is called after the dump succeeds. It removes the data files which are not owned by the chunks
+// finished or skipped in this run, and then removes the checkpoint file.
+func (cp *checkpoint) finish(tctx *tcontext.Context) error {
+	if cp == nil {
+		return nil
+	}
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	for key := range cp.confirmed {
+		for _, file := range cp.chunks[key].Files {
+			delete(cp.createdFiles, file.Name)
+		}
+	}
+	for file := range cp.createdFiles {
+		tctx.L().Info("remove stale data file", zap.String("file", file))
+		if err := removeFileIfExists(tctx, cp.extStore, file); err != nil {
+			return errors.Annotatef(err, "fail to remove stale file %s", file)
+		}
+	}
+	return removeFileIfExists(tctx, cp.extStore, checkpointFileName)
+}
+
+func removeFileIfExists(tctx *tcontext.Context, extStore storage.ExternalStorage, name string) error {
+	exist, err := extStore.FileExists(tctx, name)
+	if err != nil || !exist {
+		return errors.Trace(err)
+	}
+	return errors.Trace(extStore.DeleteFile(tctx, name))
+}
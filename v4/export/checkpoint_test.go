@@ -0,0 +1,113 @@
+// Copyright 2021 PingCAP, Inc. Licensed under Apache-2.0.
+
+package export
+
+import (
+	"context"
+	"testing"
+
+	tcontext "github.com/pingcap/dumpling/v4/context"
+
+	"github.com/pingcap/tidb/br/pkg/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckpointResume(t *testing.T) {
+	t.Parallel()
+
+	tctx := tcontext.Background()
+	store, err := storage.NewLocalStorage(t.TempDir())
+	require.NoError(t, err)
+	meta := newMockTableIR("test", "t", nil, nil, nil)
+	newTask := func(query string, chunkIndex int, files ...string) *TaskTableData {
+		task := NewTaskTableData(meta, newTableData(query, 1, false), chunkIndex, 3)
+		for _, file := range files {
+			task.Files = append(task.Files, &ManifestFile{Name: file, Type: ManifestFileTypeData, ChunkIndex: chunkIndex})
+		}
+		return task
+	}
+	for _, file := range []string{"test.t.000000000.sql", "test.t.000000001.sql", "test.t.000000002.sql", "test.t.000000003.sql"} {
+		require.NoError(t, store.WriteFile(tctx, file, []byte("INSERT INTO `t` VALUES (1);\n")))
+	}
+
+	conf := defaultConfigForTest(t)
+	conf.Snapshot = "430000000000000000"
+	conf.Consistency = consistencyTypeSnapshot
+	cp := newCheckpoint(store, conf)
+	require.NoError(t, cp.finishChunk(tctx, newTask("SELECT * FROM `test`.`t` WHERE `a`<10", 0, "test.t.000000000.sql")))
+	require.NoError(t, cp.finishChunk(tctx, newTask("SELECT * FROM `test`.`t` WHERE `a`>=10 AND `a`<20", 1, "test.t.000000001.sql")))
+	require.NoError(t, cp.finishChunk(tctx, newTask("SELECT * FROM `test`.`t` WHERE `a`>=20", 2, "test.t.000000002.sql")))
+	// the dump fails while chunk 3 is being written, and flushes the checkpoint
+	require.NoError(t, cp.createFile(tctx, "test.t.000000003.sql"))
+	require.NoError(t, cp.flush(tctx))
+	exist, err := store.FileExists(tctx, checkpointFileName)
+	require.NoError(t, err)
+	require.True(t, exist)
+
+	// the configurations must be the same as the checkpoint
+	conf2 := defaultConfigForTest(t)
+	conf2.Consistency = consistencyTypeSnapshot
+	conf2.Rows = 100
+	_, err = loadCheckpoint(tctx, store, conf2)
+	require.Error(t, err)
+
+	conf2.Rows = conf.Rows
+	cp, err = loadCheckpoint(tctx, store, conf2)
+	require.NoError(t, err)
+	require.Equal(t, conf.Snapshot, conf2.Snapshot)
+
+	chunk, ok := cp.finishedChunk(newTask("SELECT * FROM `test`.`t` WHERE `a`<10", 0))
+	require.True(t, ok)
+	require.Len(t, chunk.Files, 1)
+	require.Equal(t, "test.t.000000000.sql", chunk.Files[0].Name)
+	// chunk 1 is split differently, dump it again
+	task := newTask("SELECT * FROM `test`.`t` WHERE `a`>=10", 1)
+	_, ok = cp.finishedChunk(task)
+	require.False(t, ok)
+	require.NoError(t, cp.startChunk(tctx, task))
+	task.Files = newTask("", 1, "test.t.000000001.sql").Files
+	require.NoError(t, cp.finishChunk(tctx, task))
+
+	// the file of chunk 2 isn't confirmed in this run and chunk 3 never finishes,
+	// their files are removed with the checkpoint
+	require.NoError(t, cp.finish(tctx))
+	for file, expected := range map[string]bool{
+		"test.t.000000000.sql": true,
+		"test.t.000000001.sql": true,
+		"test.t.000000002.sql": false,
+		"test.t.000000003.sql": false,
+		checkpointFileName:     false,
+	} {
+		exist, err = store.FileExists(context.Background(), file)
+		require.NoError(t, err)
+		require.Equal(t, expected, exist, file)
+	}
+
+	// resume without checkpoint dumps from the beginning
+	conf3 := defaultConfigForTest(t)
+	cp, err = loadCheckpoint(tctx, store, conf3)
+	require.NoError(t, err)
+	_, ok = cp.finishedChunk(newTask("SELECT * FROM `test`.`t` WHERE `a`<10", 0))
+	require.False(t, ok)
+
+	// nil checkpoint means checkpoint is disabled
+	var nilCheckpoint *checkpoint
+	_, ok = nilCheckpoint.finishedChunk(task)
+	require.False(t, ok)
+	require.NoError(t, nilCheckpoint.finishChunk(tctx, task))
+	require.NoError(t, nilCheckpoint.createFile(tctx, "test.t.000000000.sql"))
+	require.NoError(t, nilCheckpoint.finish(tctx))
+}
+
+func TestInitCheckpoint(t *testing.T) {
+	t.Parallel()
+
+	conf := defaultConfigForTest(t)
+	d := &Dumper{tctx: tcontext.Background(), conf: conf}
+	require.NoError(t, initCheckpoint(d))
+	require.Nil(t, d.checkpoint)
+
+	conf.Checkpoint = true
+	require.NoError(t, initCheckpoint(d))
+	require.NotNil(t, d.checkpoint)
+}
@@ -0,0 +1,267 @@
+// Copyright 2021 PingCAP, Inc. Licensed under Apache-2.0.
+
+package export
+
+import (
+	"database/sql"
+	"encoding/binary"
+	"fmt"
+	"hash/crc64"
+	"sort"
+	"sync"
+
+	tcontext "github.com/pingcap/dumpling/v4/context"
+
+	"github.com/pingcap/errors"
+	"go.uber.org/zap"
+)
+
+// The algorithms of table checksums
+const (
+	// ChecksumAlgorithmTiDB is the checksum calculated by `ADMIN CHECKSUM TABLE` at the snapshot of the dump
+	ChecksumAlgorithmTiDB = "tidb-admin-checksum"
+	// ChecksumAlgorithmCRC64XOR is the xor of the CRC64 (ECMA) of every dumped row. A row is encoded by
+	// concatenating its values in the order of selected fields, a NULL value is encoded as a 0 byte and
+	// any other value is encoded as a 1 byte, the uvarint of its length and the value in text protocol.
+	ChecksumAlgorithmCRC64XOR = "crc64-xor"
+)
+
+var crc64Table = crc64.MakeTable(crc64.ECMA)
+
+// rowChecksum is the checksum of some rows calculated by ChecksumAlgorithmCRC64XOR
+type rowChecksum struct {
+	Checksum uint64 `json:"checksum"`
+	Rows     uint64 `json:"rows"`
+	Bytes    uint64 `json:"bytes"`
+	buf      []byte
+	lenBuf   [binary.MaxVarintLen64]byte
+}
+
+func (c *rowChecksum) update(args []interface{}) {
+	c.buf = c.buf[:0]
+	for _, arg := range args {
+		value := boundBytes(arg)
+		if value == nil {
+			c.buf = append(c.buf, 0)
+			continue
+		}
+		c.buf = append(c.buf, 1)
+		n := binary.PutUvarint(c.lenBuf[:], uint64(len(value)))
+		c.buf = append(c.buf, c.lenBuf[:n]...)
+		c.buf = append(c.buf, value...)
+		c.Bytes += uint64(len(value))
+	}
+	c.Checksum ^= crc64.Checksum(c.buf, crc64Table)
+	c.Rows++
+}
+
+// boundBytes returns the value scanned into the address bound by a RowReceiver
+func boundBytes(arg interface{}) []byte {
+	switch v := arg.(type) {
+	case *sql.RawBytes:
+		return *v
+	case *[]byte:
+		return *v
+	}
+	return nil
+}
+
+// checksumRowReceiver remembers the addresses the row is decoded into
+type checksumRowReceiver struct {
+	RowReceiver
+	args []interface{}
+}
+
+// BindAddress implements RowReceiver.BindAddress
+func (r *checksumRowReceiver) BindAddress(args []interface{}) {
+	r.RowReceiver.BindAddress(args)
+	r.args = args
+}
+
+// checksumRowIter calculates the checksum of all the decoded rows
+type checksumRowIter struct {
+	SQLRowIter
+	receiver checksumRowReceiver
+	checksum *rowChecksum
+}
+
+// Decode implements SQLRowIter.Decode
+func (it *checksumRowIter) Decode(row RowReceiver) error {
+	it.receiver.RowReceiver = row
+	if err := it.SQLRowIter.Decode(&it.receiver); err != nil {
+		return err
+	}
+	it.checksum.update(it.receiver.args)
+	return nil
+}
+
+// checksumTableData is a TableDataIR which calculates the checksum of its rows. It should be created after Start.
+type checksumTableData struct {
+	TableDataIR
+	iter *checksumRowIter
+}
+
+func newChecksumTableData(ir TableDataIR, checksum *rowChecksum) *checksumTableData {
+	return &checksumTableData{
+		TableDataIR: ir,
+		iter:        &checksumRowIter{SQLRowIter: ir.Rows(), checksum: checksum},
+	}
+}
+
+// Rows implements TableDataIR.Rows
+func (td *checksumTableData) Rows() SQLRowIter {
+	return td.iter
+}
+
+// tableChecksum is the checksum of the data of a table
+type tableChecksum struct {
+	Database  string `json:"database"`
+	Table     string `json:"table"`
+	Algorithm string `json:"algorithm"`
+	Checksum  uint64 `json:"checksum"`
+	// Count and Bytes are Total_kvs and Total_bytes for ChecksumAlgorithmTiDB,
+	// or the number of rows and the length of all values for ChecksumAlgorithmCRC64XOR
+	Count uint64 `json:"count"`
+	Bytes uint64 `json:"bytes"`
+}
+
+// rowChecksumCollector merges the row checksums of the table chunks finished by writers.
+// All the methods are safe to be called on a nil rowChecksumCollector, which means row checksum is disabled.
+type rowChecksumCollector struct {
+	mu        sync.Mutex
+	checksums map[tableKey]*tableChecksum
+}
+
+// tableKey identifies a table in maps
+type tableKey struct {
+	database string
+	table    string
+}
+
+func newRowChecksumCollector() *rowChecksumCollector {
+	return &rowChecksumCollector{checksums: make(map[tableKey]*tableChecksum)}
+}
+
+func (c *rowChecksumCollector) add(t *TaskTableData) {
+	if c == nil || t.Checksum == nil {
+		return
+	}
+	db, tbl := t.Meta.DatabaseName(), t.Meta.TableName()
+	key := tableKey{database: db, table: tbl}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	checksum, ok := c.checksums[key]
+	if !ok {
+		checksum = &tableChecksum{Database: db, Table: tbl, Algorithm: ChecksumAlgorithmCRC64XOR}
+		c.checksums[key] = checksum
+	}
+	checksum.Checksum ^= t.Checksum.Checksum
+	checksum.Count += t.Checksum.Rows
+	checksum.Bytes += t.Checksum.Bytes
+}
+
+func (c *rowChecksumCollector) tableChecksums() []*tableChecksum {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	checksums := make([]*tableChecksum, 0, len(c.checksums))
+	for _, checksum := range c.checksums {
+		checksums = append(checksums, checksum)
+	}
+	c.mu.Unlock()
+	sortTableChecksums(checksums)
+	return checksums
+}
+
+func sortTableChecksums(checksums []*tableChecksum) {
+	sort.Slice(checksums, func(i, j int) bool {
+		if checksums[i].Database != checksums[j].Database {
+			return checksums[i].Database < checksums[j].Database
+		}
+		return checksums[i].Table < checksums[j].Table
+	})
+}
+
+// useTiDBChecksum returns whether the table checksums can be calculated by `ADMIN CHECKSUM TABLE`,
+// which checksums the whole table at the snapshot of the dump
+func useTiDBChecksum(conf *Config) bool {
+	return conf.ServerInfo.ServerType == ServerTypeTiDB && conf.ServerInfo.HasTiKV &&
+		conf.Consistency == consistencyTypeSnapshot && conf.Snapshot != "" && conf.Where == ""
+}
+
+// tidbChecksumTables runs `ADMIN CHECKSUM TABLE` for all the dumped tables in conf.Threads connections.
+// The connections of pool must have set tidb_snapshot to the snapshot of the dump.
+func tidbChecksumTables(tctx *tcontext.Context, conf *Config, pool *sql.DB) ([]*tableChecksum, error) {
+	var checksums []*tableChecksum
+	for db, tables := range conf.Tables {
+		for _, tbl := range tables {
+			if tbl.Type == TableTypeBase {
+				checksums = append(checksums, &tableChecksum{Database: db, Table: tbl.Name, Algorithm: ChecksumAlgorithmTiDB})
+			}
+		}
+	}
+	sortTableChecksums(checksums)
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	checksumCh := make(chan *tableChecksum, len(checksums))
+	for _, checksum := range checksums {
+		checksumCh <- checksum
+	}
+	close(checksumCh)
+	threads := conf.Threads
+	if threads < 1 {
+		threads = 1
+	}
+	for i := 0; i < threads; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for checksum := range checksumCh {
+				if err := tidbChecksumTable(tctx, pool, checksum); err != nil {
+					mu.Lock()
+					if firstErr == nil {
+						firstErr = err
+					}
+					mu.Unlock()
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return checksums, nil
+}
+
+func tidbChecksumTable(tctx *tcontext.Context, pool *sql.DB, checksum *tableChecksum) error {
+	// mysql> ADMIN CHECKSUM TABLE `test`.`t`;
+	// +---------+------------+---------------------+-----------+-------------+
+	// | Db_name | Table_name | Checksum_crc64_xor  | Total_kvs | Total_bytes |
+	// +---------+------------+---------------------+-----------+-------------+
+	// | test    | t          | 5355771745493218496 |         3 |          99 |
+	// +---------+------------+---------------------+-----------+-------------+
+	query := fmt.Sprintf("ADMIN CHECKSUM TABLE `%s`.`%s`", escapeString(checksum.Database), escapeString(checksum.Table))
+	tctx.L().Debug("checksum table", zap.String("query", query))
+	var dbName, tableName string
+	err := pool.QueryRowContext(tctx, query).Scan(&dbName, &tableName, &checksum.Checksum, &checksum.Count, &checksum.Bytes)
+	return errors.Annotatef(err, "sql: %s", query)
+}
+
+func (m *globalMetadata) recordTableChecksums(checksums []*tableChecksum) {
+	m.checksums = checksums
+	for _, checksum := range checksums {
+		fmt.Fprintf(&m.buffer, "CHECKSUM TABLE `%s`.`%s`:\n\tAlgorithm: %s\n\tChecksum: %d\n\tCount: %d\n\tBytes: %d\n\n",
+			escapeString(checksum.Database), escapeString(checksum.Table),
+			checksum.Algorithm, checksum.Checksum, checksum.Count, checksum.Bytes)
+	}
+	if len(checksums) > 0 {
+		m.tctx.L().Info("table checksums are recorded", zap.Int("tables", len(checksums)))
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright 2021 PingCAP, Inc. Licensed under Apache-2.0.
+
+package export
+
+import (
+	"database/sql/driver"
+	"hash/crc64"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	tcontext "github.com/pingcap/dumpling/v4/context"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRowChecksum(t *testing.T) {
+	t.Parallel()
+
+	config := defaultConfigForTest(t)
+	config.OutputDirPath = t.TempDir()
+	writer, clean := createTestWriter(config, t)
+	defer clean()
+	writer.checksums = newRowChecksumCollector()
+
+	colTypes := []string{"INT", "VARCHAR"}
+	chunks := [][][]driver.Value{
+		{{"1", "a"}, {"2", nil}},
+		{{"3", ""}},
+	}
+	// the checksum doesn't depend on the order of chunks
+	for i := len(chunks) - 1; i >= 0; i-- {
+		tableIR := newMockTableIR("test", "t", chunks[i], nil, colTypes)
+		task := NewTaskTableData(tableIR, tableIR, i, len(chunks))
+		require.NoError(t, writer.handleTask(task))
+		require.NotNil(t, task.Checksum)
+		require.Equal(t, uint64(len(chunks[i])), task.Checksum.Rows)
+	}
+
+	rowCRC := func(row []byte) uint64 {
+		return crc64.Checksum(row, crc64.MakeTable(crc64.ECMA))
+	}
+	expected := rowCRC([]byte{1, 1, '1', 1, 1, 'a'}) ^
+		rowCRC([]byte{1, 1, '2', 0}) ^
+		rowCRC([]byte{1, 1, '3', 1, 0})
+	checksums := writer.checksums.tableChecksums()
+	require.Equal(t, []*tableChecksum{{
+		Database:  "test",
+		Table:     "t",
+		Algorithm: ChecksumAlgorithmCRC64XOR,
+		Checksum:  expected,
+		Count:     3,
+		Bytes:     4,
+	}}, checksums)
+
+	m := newGlobalMetadata(tcontext.Background(), nil, "")
+	m.recordTableChecksums(checksums)
+	require.Contains(t, m.String(), "CHECKSUM TABLE `test`.`t`:\n\tAlgorithm: crc64-xor\n")
+	require.Contains(t, m.String(), "\tCount: 3\n\tBytes: 4\n")
+}
+
+func TestTiDBChecksumTables(t *testing.T) {
+	t.Parallel()
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, db.Close())
+	}()
+
+	conf := defaultConfigForTest(t)
+	conf.Threads = 1
+	conf.Tables = DatabaseTables{"test": {
+		{Name: "t", Type: TableTypeBase},
+		{Name: "v", Type: TableTypeView},
+	}}
+	conf.Snapshot = "430000000000000000"
+	conf.Consistency = consistencyTypeSnapshot
+	conf.ServerInfo = ServerInfo{ServerType: ServerTypeTiDB, HasTiKV: true}
+	require.True(t, useTiDBChecksum(conf))
+
+	mock.ExpectQuery("ADMIN CHECKSUM TABLE `test`.`t`").WillReturnRows(
+		sqlmock.NewRows([]string{"Db_name", "Table_name", "Checksum_crc64_xor", "Total_kvs", "Total_bytes"}).
+			AddRow("test", "t", "5355771745493218496", 3, 99))
+	checksums, err := tidbChecksumTables(tcontext.Background(), conf, db)
+	require.NoError(t, err)
+	require.Equal(t, []*tableChecksum{{
+		Database:  "test",
+		Table:     "t",
+		Algorithm: ChecksumAlgorithmTiDB,
+		Checksum:  5355771745493218496,
+		Count:     3,
+		Bytes:     99,
+	}}, checksums)
+	require.NoError(t, mock.ExpectationsWereMet())
+	mock.ExpectClose()
+
+	conf.Where = "a > 1"
+	require.False(t, useTiDBChecksum(conf))
+}
@@ -0,0 +1,215 @@
+// Copyright 2021 PingCAP, Inc. Licensed under Apache-2.0.
+
+package export
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+	"github.com/klauspost/pgzip"
+	"github.com/pierrec/lz4/v4"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tidb/br/pkg/storage"
+)
+
+// CompressType is the compress type of the output files. Dumpling compresses the files by itself
+// because br's storage package only supports gzip. Config.CompressCodec is set to the compress types
+// which storage.CompressType doesn't support, and overrides Config.CompressType if it's not CompressNone.
+type CompressType int8
+
+const (
+	// CompressNone doesn't compress the output files
+	CompressNone CompressType = iota
+	// CompressGzip will compress given bytes in gzip format
+	CompressGzip
+	// CompressZstd will compress given bytes in zstd format
+	CompressZstd
+	// CompressSnappy will compress given bytes in snappy framing format
+	CompressSnappy
+	// CompressLZ4 will compress given bytes in lz4 frame format
+	CompressLZ4
+)
+
+const (
+	// DefaultCompressLevel means using the default compression level of the compress type
+	DefaultCompressLevel = 0
+	// DefaultCompressConcurrency compresses every output file in one goroutine
+	DefaultCompressConcurrency = 1
+
+	// pgzipBlockSize is the size of blocks compressed in parallel when gzip compress concurrency is greater than 1
+	pgzipBlockSize = 1 << 20
+)
+
+// compressOption describes how an output file is compressed
+type compressOption struct {
+	compressType CompressType
+	level        int
+	// concurrency is the number of goroutines used to compress a single file, 0 means DefaultCompressConcurrency
+	concurrency int
+}
+
+var noCompressOption = compressOption{compressType: CompressNone}
+
+func (conf *Config) compressOption() compressOption {
+	return compressOption{
+		compressType: conf.compressType(),
+		level:        conf.CompressLevel,
+		concurrency:  conf.CompressConcurrency,
+	}
+}
+
+var lz4Levels = []lz4.CompressionLevel{
+	lz4.Level1, lz4.Level2, lz4.Level3, lz4.Level4, lz4.Level5, lz4.Level6, lz4.Level7, lz4.Level8, lz4.Level9,
+}
+
+// validateCompressLevel checks whether the compress level is valid for the compress type
+func validateCompressLevel(compressType CompressType, level int) error {
+	if level == DefaultCompressLevel {
+		return nil
+	}
+	var minLevel, maxLevel int
+	switch compressType {
+	case CompressGzip:
+		minLevel, maxLevel = gzip.BestSpeed, gzip.BestCompression
+	case CompressZstd:
+		minLevel, maxLevel = 1, 22
+	case CompressLZ4:
+		minLevel, maxLevel = 1, len(lz4Levels)
+	default:
+		return errors.Errorf("compress level is not supported when compress type is '%s'", compressTypeName(compressType))
+	}
+	if level < minLevel || level > maxLevel {
+		return errors.Errorf("invalid compress level %d for compress type '%s', should be in [%d, %d]",
+			level, compressTypeName(compressType), minLevel, maxLevel)
+	}
+	return nil
+}
+
+func compressTypeName(compressType CompressType) string {
+	switch compressType {
+	case CompressNone:
+		return "no-compression"
+	case CompressGzip:
+		return "gzip"
+	case CompressZstd:
+		return "zstd"
+	case CompressSnappy:
+		return "snappy"
+	case CompressLZ4:
+		return "lz4"
+	default:
+		return "unknown"
+	}
+}
+
+func newCompressWriter(opt compressOption, w io.Writer) (io.WriteCloser, error) {
+	level, concurrency := opt.level, opt.concurrency
+	if concurrency < 1 {
+		concurrency = DefaultCompressConcurrency
+	}
+	switch opt.compressType {
+	case CompressGzip:
+		if level == DefaultCompressLevel {
+			level = gzip.DefaultCompression
+		}
+		if concurrency == 1 {
+			cw, err := gzip.NewWriterLevel(w, level)
+			return cw, errors.Trace(err)
+		}
+		// pgzip compresses blocks in parallel like pigz and still emits a standard gzip stream
+		cw, err := pgzip.NewWriterLevel(w, level)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if err = cw.SetConcurrency(pgzipBlockSize, concurrency); err != nil {
+			return nil, errors.Trace(err)
+		}
+		return cw, nil
+	case CompressZstd:
+		opts := []zstd.EOption{zstd.WithEncoderConcurrency(concurrency)}
+		if level != DefaultCompressLevel {
+			opts = append(opts, zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(level)))
+		}
+		cw, err := zstd.NewWriter(w, opts...)
+		return cw, errors.Trace(err)
+	case CompressSnappy:
+		return snappy.NewBufferedWriter(w), nil
+	case CompressLZ4:
+		cw := lz4.NewWriter(w)
+		opts := []lz4.Option{lz4.ConcurrencyOption(concurrency)}
+		if level != DefaultCompressLevel {
+			opts = append(opts, lz4.CompressionLevelOption(lz4Levels[level-1]))
+		}
+		if err := cw.Apply(opts...); err != nil {
+			return nil, errors.Trace(err)
+		}
+		return cw, nil
+	default:
+		return nil, errors.Errorf("unknown compress type %d", opt.compressType)
+	}
+}
+
+// fileIOWriter adapts storage.ExternalFileWriter to io.Writer. The compressors may call Write from their own
+// goroutines, so ctx is the context of the file which never changes after the file is created.
+type fileIOWriter struct {
+	ctx context.Context
+	w   storage.ExternalFileWriter
+}
+
+// Write implements io.Writer
+func (fw *fileIOWriter) Write(p []byte) (int, error) {
+	return fw.w.Write(fw.ctx, p)
+}
+
+// compressFileWriter is a storage.ExternalFileWriter which compresses the written bytes
+type compressFileWriter struct {
+	fw *fileIOWriter
+	cw io.WriteCloser
+}
+
+func newCompressFileWriter(ctx context.Context, w storage.ExternalFileWriter, opt compressOption) (storage.ExternalFileWriter, error) {
+	if opt.compressType == CompressNone {
+		return w, nil
+	}
+	fw := &fileIOWriter{ctx: ctx, w: w}
+	cw, err := newCompressWriter(opt, fw)
+	if err != nil {
+		return nil, err
+	}
+	return &compressFileWriter{fw: fw, cw: cw}, nil
+}
+
+// Write implements storage.ExternalFileWriter.Write. The compressed bytes are written with the context of the file.
+func (c *compressFileWriter) Write(ctx context.Context, p []byte) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, errors.Trace(err)
+	}
+	n, err := c.cw.Write(p)
+	return n, errors.Trace(err)
+}
+
+// Close implements storage.ExternalFileWriter.Close. It flushes the compressed data and closes the file
+func (c *compressFileWriter) Close(ctx context.Context) error {
+	if err := c.cw.Close(); err != nil {
+		_ = c.fw.w.Close(ctx)
+		return errors.Trace(err)
+	}
+	return c.fw.w.Close(ctx)
+}
+
+// createFile creates a file in the external storage which compresses written data as opt describes
+func createFile(ctx context.Context, s storage.ExternalStorage, fileName string, opt compressOption) (storage.ExternalFileWriter, error) {
+	w, err := s.Create(ctx, fileName)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	cw, err := newCompressFileWriter(ctx, w, opt)
+	if err != nil {
+		_ = w.Close(ctx)
+		return nil, err
+	}
+	return cw, nil
+}
@@ -0,0 +1,103 @@
+// Copyright 2021 PingCAP, Inc. Licensed under Apache-2.0.
+
+package export
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+	"github.com/pierrec/lz4/v4"
+	"github.com/pingcap/tidb/br/pkg/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompressFileWriter(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	// make the data larger than pgzipBlockSize so that it is compressed in several blocks
+	data := []byte(strings.Repeat("INSERT INTO `t` VALUES (1,'dumpling');\n", 3*pgzipBlockSize/40))
+	cases := []struct {
+		compress    string
+		level       int
+		concurrency int
+		suffix      string
+		reader      func(io.Reader) (io.Reader, error)
+	}{
+		{"gzip", DefaultCompressLevel, 0, ".gz", func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) }},
+		{"gzip", 9, 1, ".gz", func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) }},
+		{"gzip", DefaultCompressLevel, 4, ".gz", func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) }},
+		{"zstd", DefaultCompressLevel, 1, ".zst", func(r io.Reader) (io.Reader, error) { return zstd.NewReader(r) }},
+		{"zstd", 19, 4, ".zst", func(r io.Reader) (io.Reader, error) { return zstd.NewReader(r) }},
+		{"snappy", DefaultCompressLevel, 4, ".snappy", func(r io.Reader) (io.Reader, error) { return snappy.NewReader(r), nil }},
+		{"lz4", DefaultCompressLevel, 1, ".lz4", func(r io.Reader) (io.Reader, error) { return lz4.NewReader(r), nil }},
+		{"lz4", 9, 4, ".lz4", func(r io.Reader) (io.Reader, error) { return lz4.NewReader(r), nil }},
+	}
+	for _, c := range cases {
+		compressType, err := parseCompressCodec(c.compress)
+		require.NoError(t, err)
+		require.Equal(t, c.suffix, compressFileSuffix(compressType))
+		require.NoError(t, validateCompressLevel(compressType, c.level))
+
+		bf := storage.NewBufferWriter()
+		w, err := newCompressFileWriter(ctx, bf, compressOption{compressType: compressType, level: c.level, concurrency: c.concurrency})
+		require.NoError(t, err)
+		_, err = w.Write(ctx, data)
+		require.NoError(t, err)
+		require.NoError(t, w.Close(ctx))
+		require.Less(t, len(bf.Bytes()), len(data))
+
+		r, err := c.reader(bytes.NewReader(bf.Bytes()))
+		require.NoError(t, err)
+		decompressed, err := io.ReadAll(r)
+		require.NoError(t, err)
+		require.Equal(t, data, decompressed)
+		if d, ok := r.(*zstd.Decoder); ok {
+			d.Close()
+		}
+	}
+}
+
+func TestValidateCompressLevel(t *testing.T) {
+	t.Parallel()
+
+	require.NoError(t, validateCompressLevel(CompressNone, DefaultCompressLevel))
+	require.Error(t, validateCompressLevel(CompressNone, 1))
+	require.Error(t, validateCompressLevel(CompressSnappy, 1))
+	require.Error(t, validateCompressLevel(CompressGzip, 10))
+	require.Error(t, validateCompressLevel(CompressZstd, 23))
+	require.Error(t, validateCompressLevel(CompressLZ4, -1))
+
+	_, err := parseCompressCodec("brotli")
+	require.Error(t, err)
+}
+
+func TestConfigCompressType(t *testing.T) {
+	t.Parallel()
+
+	conf := DefaultConfig()
+	require.Equal(t, CompressNone, conf.compressType())
+	// the embedders still set the compress type of br's storage
+	conf.CompressType = storage.Gzip
+	require.Equal(t, CompressGzip, conf.compressType())
+
+	conf.setCompressCodec(CompressZstd)
+	require.Equal(t, storage.NoCompression, conf.CompressType)
+	require.Equal(t, CompressZstd, conf.compressType())
+	conf.setCompressCodec(CompressGzip)
+	require.Equal(t, storage.Gzip, conf.CompressType)
+	require.Equal(t, CompressNone, conf.CompressCodec)
+	require.Equal(t, CompressGzip, conf.compressType())
+
+	compressType, err := ParseCompressType("gz")
+	require.NoError(t, err)
+	require.Equal(t, storage.Gzip, compressType)
+	_, err = ParseCompressType("zstd")
+	require.Error(t, err)
+}
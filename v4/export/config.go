@@ -8,10 +8,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"math"
 	"net/http"
 	"regexp"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"text/template"
 	"time"
 
@@ -24,6 +26,7 @@ import (
 	filter "github.com/pingcap/tidb-tools/pkg/table-filter"
 	"github.com/pingcap/tidb-tools/pkg/utils"
 	"github.com/pingcap/tidb/br/pkg/storage"
+	"github.com/pingcap/tidb/br/pkg/summary"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/spf13/pflag"
 	"go.uber.org/zap"
@@ -48,6 +51,7 @@ const (
 	flagSnapshot                 = "snapshot"
 	flagNoViews                  = "no-views"
 	flagStatusAddr               = "status-addr"
+	flagStatusToken              = "status-token"
 	flagRows                     = "rows"
 	flagWhere                    = "where"
 	flagEscapeBackslash          = "escape-backslash"
@@ -72,6 +76,32 @@ const (
 	flagReadTimeout              = "read-timeout"
 	flagTransactionalConsistency = "transactional-consistency"
 	flagCompress                 = "compress"
+	flagCompressLevel            = "compress-level"
+	flagCompressConcurrency      = "compress-concurrency"
+	flagAvroCodec                = "avro-codec"
+	flagEncryptKeyFile           = "encrypt-key-file"
+	flagResume                   = "resume"
+	flagCheckpoint               = "checkpoint"
+	flagChecksum                 = "checksum"
+	flagChunkSplitMode           = "chunk-split-mode"
+	flagWorkStealing             = "work-stealing"
+	flagRetryMaxAttempts         = "retry-max-attempts"
+	flagRetryBaseBackoff         = "retry-base-backoff"
+	flagRetryMaxBackoff          = "retry-max-backoff"
+	flagRetryJitter              = "retry-jitter"
+	flagRetryableErrorCodes      = "retryable-error-codes"
+	flagFatalErrorCodes          = "fatal-error-codes"
+	flagMaxBytesPerSec           = "max-bytes-per-sec"
+	flagMaxRowsPerSec            = "max-rows-per-sec"
+	flagThrottleCheckInterval    = "throttle-check-interval"
+	flagMaxThreadsRunning        = "max-threads-running"
+	flagMaxReplicationLag        = "max-replication-lag"
+	flagMaxTiDBMemoryUsage       = "max-tidb-memory-usage"
+	flagRoutines                 = "routines"
+	flagTriggers                 = "triggers"
+	flagEvents                   = "events"
+	flagDumpUsers                = "dump-users"
+	flagUsersFilter              = "users-filter"
 
 	// FlagHelp represents the help flag
 	FlagHelp = "help"
@@ -92,7 +122,18 @@ type Config struct {
 	EscapeBackslash          bool
 	DumpEmptyDatabase        bool
 	PosAfterConnect          bool
+	Resume                   bool
+	Checkpoint               bool
+	Checksum                 bool
+	WorkStealing             bool
+	Routines                 bool
+	Triggers                 bool
+	Events                   bool
+	DumpUsers                bool
 	CompressType             storage.CompressType
+	CompressCodec            CompressType
+	CompressLevel            int
+	CompressConcurrency      int
 
 	Host     string
 	Port     int
@@ -113,14 +154,27 @@ type Config struct {
 	LogFormat     string
 	OutputDirPath string
 	StatusAddr    string
+	StatusToken   string `json:"-"`
 	Snapshot      string
 	Consistency   string
 	CsvNullValue  string
 	SQL           string
 	CsvSeparator  string
 	CsvDelimiter  string
+	AvroCodec     string
 	Databases     []string
 
+	// EncryptKeyFile is the path of the key file used by FileKeyProvider
+	EncryptKeyFile string
+	// KeyProvider provides the key to encrypt all the output files. No file will be encrypted if it's nil.
+	KeyProvider KeyProvider `json:"-"`
+	// EventListener receives the events of the dump, see WithEventListener
+	EventListener EventListener `json:"-"`
+	// RowSink receives the rows of the tables instead of the data files if it's set, see WithRowSink
+	RowSink RowSink `json:"-"`
+	// UsersFilter selects the accounts to dump if DumpUsers is set, like `app_*@%` or `!mysql.*@*`
+	UsersFilter []string
+
 	TableFilter        filter.Filter `json:"-"`
 	Where              string
 	FileType           string
@@ -128,13 +182,33 @@ type Config struct {
 	Logger             *zap.Logger        `json:"-"`
 	OutputFileTemplate *template.Template `json:"-"`
 	Rows               uint64
+	ChunkSplitMode     string
 	ReadTimeout        time.Duration
+	RetryPolicy        RetryPolicy
+	MaxBytesPerSec     uint64
+	MaxRowsPerSec      uint64
 	TiDBMemQuotaQuery  uint64
 	FileSize           uint64
 	StatementSize      uint64
 	SessionParams      map[string]interface{}
 	Labels             prometheus.Labels `json:"-"`
 	Tables             DatabaseTables
+
+	// the thresholds of source server load to pause dumping, 0 means disabled
+	ThrottleCheckInterval time.Duration
+	MaxThreadsRunning     int
+	MaxReplicationLag     time.Duration
+	MaxTiDBMemoryUsage    float64
+
+	// usersFilter is parsed from UsersFilter
+	usersFilter userFilter
+	// throttle is shared by all the writers to limit MaxBytesPerSec and MaxRowsPerSec, and to pause them
+	throttle *throttle
+	// summary collects the summary log of the dump, it's set by Dumper.Dump
+	summary summary.LogCollector
+	// tlsConfigName is the name of the TLS config registered to the MySQL driver for this dump, it's unique
+	// among the dumps in the process, so the dumps with different certificates don't overwrite each other
+	tlsConfigName string
 }
 
 // DefaultConfig returns the default export Config for dumpling
@@ -159,18 +233,25 @@ func DefaultConfig() *Config {
 		Consistency:        consistencyTypeAuto,
 		NoViews:            true,
 		Rows:               UnspecifiedSize,
+		ChunkSplitMode:     ChunkSplitModeStep,
 		Where:              "",
 		FileType:           "",
 		NoHeader:           false,
 		NoSchemas:          false,
 		NoData:             false,
 		CsvNullValue:       "\\N",
+		AvroCodec:          AvroCodecNull,
 		SQL:                "",
 		TableFilter:        allFilter,
 		DumpEmptyDatabase:  true,
 		SessionParams:      make(map[string]interface{}),
 		OutputFileTemplate: DefaultOutputFileTemplate,
 		PosAfterConnect:    false,
+		RetryPolicy:        DefaultRetryPolicy(),
+		EventListener:      NopEventListener{},
+		UsersFilter:        []string{"*@*", DefaultUsersFilter},
+
+		ThrottleCheckInterval: time.Second,
 	}
 }
 
@@ -190,7 +271,7 @@ func (conf *Config) GetDSN(db string) string {
 	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?collation=utf8mb4_general_ci&readTimeout=%s&writeTimeout=30s&interpolateParams=true&maxAllowedPacket=0",
 		conf.User, conf.Password, conf.Host, conf.Port, db, conf.ReadTimeout)
 	if len(conf.Security.CAPath) > 0 {
-		dsn += "&tls=dumpling-tls-target"
+		dsn += "&tls=" + conf.tlsConfigName
 	}
 	if conf.AllowCleartextPasswords {
 		dsn += "&allowCleartextPasswords=1"
@@ -223,11 +304,12 @@ func (conf *Config) DefineFlags(flags *pflag.FlagSet) {
 	flags.String(flagSnapshot, "", "Snapshot position (uint64 or MySQL style string timestamp). Valid only when consistency=snapshot")
 	flags.BoolP(flagNoViews, "W", true, "Do not dump views")
 	flags.String(flagStatusAddr, ":8281", "dumpling API server and pprof addr")
+	flags.String(flagStatusToken, "", "The token required by the API to pause, resume, cancel the dump or change its threads, as the header \"Authorization: Bearer <token>\". The API is disabled if it's not set")
 	flags.Uint64P(flagRows, "r", UnspecifiedSize, "If specified, dumpling will split table into chunks and concurrently dump them to different files to improve efficiency. For TiDB v3.0+, specify this will make dumpling split table with each file one TiDB region(no matter how many rows is).\n"+
 		"If not specified, dumpling will dump table without inner-concurrency which could be relatively slow. default unlimited")
 	flags.String(flagWhere, "", "Dump only selected records")
 	flags.Bool(flagEscapeBackslash, true, "use backslash to escape special characters")
-	flags.String(flagFiletype, "", "The type of export file (sql/csv)")
+	flags.String(flagFiletype, "", "The type of export file (sql/csv/parquet/jsonl/avro)")
 	flags.Bool(flagNoHeader, false, "whether not to dump CSV table header")
 	flags.BoolP(flagNoSchemas, "m", false, "Do not dump table schemas with the data")
 	flags.BoolP(flagNoData, "d", false, "Do not dump table data")
@@ -251,7 +333,34 @@ func (conf *Config) DefineFlags(flags *pflag.FlagSet) {
 	_ = flags.MarkHidden(flagReadTimeout)
 	flags.Bool(flagTransactionalConsistency, true, "Only support transactional consistency")
 	_ = flags.MarkHidden(flagTransactionalConsistency)
-	flags.StringP(flagCompress, "c", "", "Compress output file type, support 'gzip', 'no-compression' now")
+	flags.StringP(flagCompress, "c", "", "Compress output file type, support 'gzip', 'zstd', 'snappy', 'lz4', 'no-compression' now")
+	flags.Int(flagCompressLevel, DefaultCompressLevel, "The compression level of output files, 0 means the default level of the compress type. gzip: 1-9, zstd: 1-22, lz4: 1-9")
+	flags.Int(flagCompressConcurrency, DefaultCompressConcurrency, "The number of goroutines used to compress a single output file in 'gzip', 'zstd' or 'lz4', useful when dumping a few huge tables")
+	flags.String(flagEncryptKeyFile, "", "The path of the file containing an AES-256 key (32 raw bytes or 64 hex characters). If specified, all the output files except manifest.json will be encrypted with AES-256-GCM")
+	flags.Bool(flagResume, false, "Resume the dump from the checkpoint left in the output directory by a failed dump, finished chunks will be skipped")
+	flags.Bool(flagCheckpoint, false, "Record the finished chunks in the checkpoint in the output directory, so that the dump can be resumed by --resume if it fails")
+	flags.Bool(flagChecksum, false, "Record the checksum of every dumped table in metadata. TiDB runs 'ADMIN CHECKSUM TABLE' at the snapshot, MySQL checksums the dumped rows by CRC64")
+	flags.String(flagChunkSplitMode, ChunkSplitModeStep, "How to split tables into chunks with --rows, support 'step', 'sample'. 'step' divides [min, max] of the integer key into equal steps, 'sample' walks through the key to pick boundaries with roughly equal rows, which suits sparse or skewed keys")
+	flags.Bool(flagWorkStealing, false, "After all the chunks are dispatched, split the remaining key range of running chunks and hand the tails to idle writers. The split chunks are selected by pages ordered by their keys")
+	defaultRetryPolicy := DefaultRetryPolicy()
+	flags.Int(flagRetryMaxAttempts, defaultRetryPolicy.MaxAttempts, "The max number of attempts to dump a table chunk or lock tables, including the first one")
+	flags.Duration(flagRetryBaseBackoff, defaultRetryPolicy.BaseBackoff, "The backoff before the first retry, which is doubled after every retry")
+	flags.Duration(flagRetryMaxBackoff, defaultRetryPolicy.MaxBackoff, "The max backoff between two retries")
+	flags.Float64(flagRetryJitter, defaultRetryPolicy.Jitter, "The fraction of the retry backoff which is randomly cut off, in [0, 1]")
+	flags.UintSlice(flagRetryableErrorCodes, nil, "MySQL error codes which are always retried, e.g. '1105,9007'")
+	flags.UintSlice(flagFatalErrorCodes, nil, "MySQL error codes which are never retried")
+	flags.String(flagMaxBytesPerSec, "", "The max bytes written per second by all the threads before compression, e.g. '100MiB'. No limit if it's empty")
+	flags.Uint64(flagMaxRowsPerSec, 0, "The max rows dumped per second by all the threads. No limit if it's 0")
+	flags.Duration(flagThrottleCheckInterval, time.Second, "The interval to check the load of source server for --max-threads-running, --max-replication-lag and --max-tidb-memory-usage")
+	flags.Int(flagMaxThreadsRunning, 0, "Pause dumping while Threads_running of source server exceeds this value. Disabled if it's 0")
+	flags.Duration(flagMaxReplicationLag, 0, "Pause dumping while Seconds_Behind_Master of source server exceeds this value or is NULL. Disabled if it's 0")
+	flags.Float64(flagMaxTiDBMemoryUsage, 0, "Pause dumping while the memory usage ratio of any TiDB server host exceeds this value, e.g. 0.8. Disabled if it's 0")
+	flags.Bool(flagRoutines, false, "Dump the stored procedures and functions of the dumped databases")
+	flags.Bool(flagTriggers, false, "Dump the triggers of the dumped tables")
+	flags.Bool(flagEvents, false, "Dump the events of the dumped databases")
+	flags.Bool(flagDumpUsers, false, "Dump the accounts, their privileges and roles to users.sql")
+	flags.StringSlice(flagUsersFilter, []string{"*@*", DefaultUsersFilter}, "filter to select which accounts to dump, like 'app_*@%'")
+	flags.String(flagAvroCodec, AvroCodecNull, "The compression codec of data blocks in avro files, support 'null', 'deflate', 'snappy'")
 }
 
 // ParseFromFlags parses dumpling's export.Config from flags
@@ -322,6 +431,10 @@ func (conf *Config) ParseFromFlags(flags *pflag.FlagSet) error {
 	if err != nil {
 		return errors.Trace(err)
 	}
+	conf.StatusToken, err = flags.GetString(flagStatusToken)
+	if err != nil {
+		return errors.Trace(err)
+	}
 	conf.Rows, err = flags.GetUint64(flagRows)
 	if err != nil {
 		return errors.Trace(err)
@@ -462,7 +575,124 @@ func (conf *Config) ParseFromFlags(flags *pflag.FlagSet) error {
 	if err != nil {
 		return errors.Trace(err)
 	}
-	conf.CompressType, err = ParseCompressType(compressType)
+	codec, err := parseCompressCodec(compressType)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	conf.setCompressCodec(codec)
+	conf.CompressLevel, err = flags.GetInt(flagCompressLevel)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	conf.CompressConcurrency, err = flags.GetInt(flagCompressConcurrency)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	conf.EncryptKeyFile, err = flags.GetString(flagEncryptKeyFile)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	conf.Resume, err = flags.GetBool(flagResume)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	conf.Checkpoint, err = flags.GetBool(flagCheckpoint)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	conf.Checksum, err = flags.GetBool(flagChecksum)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	conf.WorkStealing, err = flags.GetBool(flagWorkStealing)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	conf.RetryPolicy.MaxAttempts, err = flags.GetInt(flagRetryMaxAttempts)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	conf.RetryPolicy.BaseBackoff, err = flags.GetDuration(flagRetryBaseBackoff)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	conf.RetryPolicy.MaxBackoff, err = flags.GetDuration(flagRetryMaxBackoff)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	conf.RetryPolicy.Jitter, err = flags.GetFloat64(flagRetryJitter)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	conf.RetryPolicy.RetryableErrorCodes, err = getErrorCodesFlag(flags, flagRetryableErrorCodes)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	conf.RetryPolicy.FatalErrorCodes, err = getErrorCodesFlag(flags, flagFatalErrorCodes)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	maxBytesPerSec, err := flags.GetString(flagMaxBytesPerSec)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	conf.MaxBytesPerSec, err = ParseBytesPerSec(maxBytesPerSec)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	conf.MaxRowsPerSec, err = flags.GetUint64(flagMaxRowsPerSec)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	conf.ThrottleCheckInterval, err = flags.GetDuration(flagThrottleCheckInterval)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	conf.MaxThreadsRunning, err = flags.GetInt(flagMaxThreadsRunning)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	conf.MaxReplicationLag, err = flags.GetDuration(flagMaxReplicationLag)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	conf.MaxTiDBMemoryUsage, err = flags.GetFloat64(flagMaxTiDBMemoryUsage)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	conf.Routines, err = flags.GetBool(flagRoutines)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	conf.Triggers, err = flags.GetBool(flagTriggers)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	conf.Events, err = flags.GetBool(flagEvents)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	conf.DumpUsers, err = flags.GetBool(flagDumpUsers)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	conf.UsersFilter, err = flags.GetStringSlice(flagUsersFilter)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	chunkSplitMode, err := flags.GetString(flagChunkSplitMode)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	conf.ChunkSplitMode, err = ParseChunkSplitMode(chunkSplitMode)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	avroCodec, err := flags.GetString(flagAvroCodec)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	conf.AvroCodec, err = ParseAvroCodec(avroCodec)
 	if err != nil {
 		return errors.Trace(err)
 	}
@@ -479,6 +709,21 @@ func (conf *Config) ParseFromFlags(flags *pflag.FlagSet) error {
 	return nil
 }
 
+func getErrorCodesFlag(flags *pflag.FlagSet, flag string) ([]uint16, error) {
+	values, err := flags.GetUintSlice(flag)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	codes := make([]uint16, 0, len(values))
+	for _, v := range values {
+		if v > math.MaxUint16 {
+			return nil, errors.Errorf("invalid error code %d in --%s", v, flag)
+		}
+		codes = append(codes, uint16(v))
+	}
+	return codes, nil
+}
+
 // ParseFileSize parses file size from tables-list and filter arguments
 func ParseFileSize(fileSizeStr string) (uint64, error) {
 	if len(fileSizeStr) == 0 {
@@ -527,6 +772,48 @@ func ParseCompressType(compressType string) (storage.CompressType, error) {
 	}
 }
 
+// parseCompressCodec parses compressType string to CompressType, including the compress types
+// which storage.CompressType doesn't support
+func parseCompressCodec(compressType string) (CompressType, error) {
+	switch compressType {
+	case "", "no-compression":
+		return CompressNone, nil
+	case "gzip", "gz":
+		return CompressGzip, nil
+	case "zstd", "zst":
+		return CompressZstd, nil
+	case "snappy":
+		return CompressSnappy, nil
+	case "lz4":
+		return CompressLZ4, nil
+	default:
+		return CompressNone, errors.Errorf("unknown compress type %s", compressType)
+	}
+}
+
+// setCompressCodec sets CompressType for gzip to keep it readable by the embedders, and CompressCodec for the others
+func (conf *Config) setCompressCodec(codec CompressType) {
+	conf.CompressType, conf.CompressCodec = storage.NoCompression, CompressNone
+	switch codec {
+	case CompressNone:
+	case CompressGzip:
+		conf.CompressType = storage.Gzip
+	default:
+		conf.CompressCodec = codec
+	}
+}
+
+// compressType returns the compress type of the output files
+func (conf *Config) compressType() CompressType {
+	if conf.CompressCodec != CompressNone {
+		return conf.CompressCodec
+	}
+	if conf.CompressType == storage.Gzip {
+		return CompressGzip
+	}
+	return CompressNone
+}
+
 func (conf *Config) createExternalStorage(ctx context.Context) (storage.ExternalStorage, error) {
 	b, err := storage.ParseBackend(conf.OutputDirPath, &conf.BackendOptions)
 	if err != nil {
@@ -558,6 +845,8 @@ const (
 	TiDBMemQuotaQueryName = "tidb_mem_quota_query"
 	// DefaultTableFilter is the default exclude table filter. It will exclude all system databases
 	DefaultTableFilter = "!/^(mysql|sys|INFORMATION_SCHEMA|PERFORMANCE_SCHEMA|METRICS_SCHEMA|INSPECTION_SCHEMA)$/.*"
+	// DefaultUsersFilter is the default exclude users filter. It will exclude the built-in accounts of MySQL
+	DefaultUsersFilter = "!mysql.*@*"
 
 	defaultDumpThreads        = 128
 	defaultDumpGCSafePointTTL = 5 * 60
@@ -566,6 +855,26 @@ const (
 	dumplingServiceSafePointPrefix = "dumpling"
 )
 
+// The modes to split tables into chunks
+const (
+	// ChunkSplitModeStep divides [min, max] of the integer key into equal steps
+	ChunkSplitModeStep = "step"
+	// ChunkSplitModeSample walks through the integer key in order and picks a boundary every conf.Rows rows
+	ChunkSplitModeSample = "sample"
+)
+
+// ParseChunkSplitMode checks whether the given chunk split mode is supported
+func ParseChunkSplitMode(mode string) (string, error) {
+	switch mode {
+	case "", ChunkSplitModeStep:
+		return ChunkSplitModeStep, nil
+	case ChunkSplitModeSample:
+		return mode, nil
+	default:
+		return "", errors.Errorf("unknown chunk split mode %s", mode)
+	}
+}
+
 var (
 	decodeRegionVersion = semver.New("3.0.0")
 	gcSafePointVersion  = semver.New("4.0.0")
@@ -672,6 +981,9 @@ func adjustConfig(conf *Config, fns ...func(*Config) error) error {
 	return nil
 }
 
+// tlsConfigSeq generates the suffixes of the TLS config names
+var tlsConfigSeq int64
+
 func registerTLSConfig(conf *Config) error {
 	if len(conf.Security.CAPath) > 0 {
 		var err error
@@ -700,7 +1012,8 @@ func registerTLSConfig(conf *Config) error {
 		if conf.Host == "127.0.0.1" {
 			tlsConfig.InsecureSkipVerify = true
 		}
-		err = mysql.RegisterTLSConfig("dumpling-tls-target", tlsConfig)
+		conf.tlsConfigName = fmt.Sprintf("dumpling-tls-target-%d", atomic.AddInt64(&tlsConfigSeq, 1))
+		err = mysql.RegisterTLSConfig(conf.tlsConfigName, tlsConfig)
 		if err != nil {
 			return errors.Trace(err)
 		}
@@ -712,6 +1025,12 @@ func validateSpecifiedSQL(conf *Config) error {
 	if conf.SQL != "" && conf.Where != "" {
 		return errors.New("can't specify both --sql and --where at the same time. Please try to combine them into --sql")
 	}
+	if conf.SQL != "" && conf.Resume {
+		return errors.New("can't resume a dump with --sql")
+	}
+	if conf.SQL != "" && conf.Checksum {
+		return errors.New("can't record table checksums with --sql")
+	}
 	return nil
 }
 
@@ -728,13 +1047,34 @@ func adjustFileFormat(conf *Config) error {
 		if conf.SQL != "" {
 			return errors.Errorf("unsupported config.FileType '%s' when we specify --sql, please unset --filetype or set it to 'csv'", conf.FileType)
 		}
-	case FileFormatCSVString:
+	case FileFormatCSVString, FileFormatParquetString, FileFormatJSONLString:
+	case FileFormatAvroString:
+		if conf.compressType() != CompressNone {
+			return errors.Errorf("unsupported config.CompressType when config.FileType is '%s', please use --avro-codec to compress the data blocks instead", conf.FileType)
+		}
 	default:
 		return errors.Errorf("unknown config.FileType '%s'", conf.FileType)
 	}
 	return nil
 }
 
+func adjustKeyProvider(conf *Config) error {
+	if conf.EncryptKeyFile != "" {
+		if conf.KeyProvider != nil {
+			return errors.New("can't specify both config.EncryptKeyFile and config.KeyProvider")
+		}
+		conf.KeyProvider = &FileKeyProvider{Path: conf.EncryptKeyFile}
+	}
+	return nil
+}
+
+func adjustCompressOption(conf *Config) error {
+	if conf.CompressConcurrency < 0 {
+		return errors.Errorf("invalid compress concurrency %d, should not be negative", conf.CompressConcurrency)
+	}
+	return validateCompressLevel(conf.compressType(), conf.CompressLevel)
+}
+
 func matchMysqlBugversion(info ServerInfo) bool {
 	// if 8.0.3 <= mysql8 version < 8.0.23
 	// FLUSH TABLES WITH READ LOCK could block other sessions from executing SHOW TABLE STATUS.
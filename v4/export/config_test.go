@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	tcontext "github.com/pingcap/dumpling/v4/context"
+	"github.com/pingcap/tidb/br/pkg/storage"
 	"github.com/stretchr/testify/require"
 )
 
@@ -33,3 +34,17 @@ func TestMatchMysqlBugVersion(t *testing.T) {
 		require.Equalf(t, x.expected, matchMysqlBugversion(x.serverInfo), "server info: %s", x.serverInfo)
 	}
 }
+
+func TestAdjustFileFormat(t *testing.T) {
+	t.Parallel()
+	conf := defaultConfigForTest(t)
+	conf.FileType = "AVRO"
+	require.NoError(t, adjustFileFormat(conf))
+	require.Equal(t, FileFormatAvroString, conf.FileType)
+
+	conf.CompressType = storage.Gzip
+	require.Error(t, adjustFileFormat(conf))
+
+	conf.FileType = "unknown"
+	require.Error(t, adjustFileFormat(conf))
+}
@@ -133,7 +133,7 @@ func (c *ConsistencyLockDumpingTables) Setup(tctx *tcontext.Context) error {
 			}
 		}
 		return errors.Trace(err)
-	}, newLockTablesBackoffer(tctx, blockList))
+	}, newLockTablesBackoffer(tctx, &c.conf.RetryPolicy, blockList))
 }
 
 // TearDown implements ConsistencyController.TearDown
@@ -9,17 +9,18 @@ import (
 	"encoding/hex"
 	"fmt"
 	"math/big"
+	"net/http"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/pingcap/dumpling/v4/cli"
 	tcontext "github.com/pingcap/dumpling/v4/context"
 	"github.com/pingcap/dumpling/v4/log"
 
-	// import mysql driver
-	_ "github.com/go-sql-driver/mysql"
+	"github.com/go-sql-driver/mysql"
 	"github.com/pingcap/errors"
 	"github.com/pingcap/failpoint"
 	pclog "github.com/pingcap/log"
@@ -41,26 +42,45 @@ type Dumper struct {
 	conf      *Config
 	cancelCtx context.CancelFunc
 
-	extStore storage.ExternalStorage
-	dbHandle *sql.DB
+	extStore   storage.ExternalStorage
+	dbHandle   *sql.DB
+	checkpoint *checkpoint
+	manifest   *manifestCollector
+	checksums  *rowChecksumCollector
+	stealer    *workStealer
+	progress   *progressTracker
+
+	// writers is set while the writers are running
+	writersMu sync.Mutex
+	writers   *writerPool
 
 	tidbPDClientForGC         pd.Client
 	selectTiDBTableRegionFunc func(tctx *tcontext.Context, conn *sql.Conn, meta TableMeta) (pkFields []string, pkVals [][]string, err error)
 }
 
-// NewDumper returns a new Dumper
-func NewDumper(ctx context.Context, conf *Config) (*Dumper, error) {
+// NewDumper returns a new Dumper, opts are applied to conf before it's checked
+func NewDumper(ctx context.Context, conf *Config, opts ...DumperOption) (*Dumper, error) {
+	for _, opt := range opts {
+		opt(conf)
+	}
 	tctx, cancelFn := tcontext.Background().WithContext(ctx).WithCancel()
 	d := &Dumper{
 		tctx:                      tctx,
 		conf:                      conf,
 		cancelCtx:                 cancelFn,
+		progress:                  newProgressTracker(),
 		selectTiDBTableRegionFunc: selectTiDBTableRegion,
 	}
 	err := adjustConfig(conf,
 		registerTLSConfig,
 		validateSpecifiedSQL,
-		adjustFileFormat)
+		adjustFileFormat,
+		adjustCompressOption,
+		adjustKeyProvider,
+		adjustRetryPolicy,
+		adjustThrottle,
+		adjustEventListener,
+		adjustUsersFilter)
 	if err != nil {
 		return nil, err
 	}
@@ -71,6 +91,7 @@ func NewDumper(ctx context.Context, conf *Config) (*Dumper, error) {
 		openSQLDB,
 		detectServerInfo,
 		resolveAutoConsistency,
+		initCheckpoint,
 
 		tidbSetPDClientForGC,
 		tidbGetSnapshot,
@@ -90,14 +111,43 @@ func (d *Dumper) Dump() (dumpErr error) {
 		conCtrl ConsistencyController
 	)
 	tctx, conf, pool := d.tctx, d.conf, d.dbHandle
+	// the dump is completed after the metadata, manifest and checkpoint are written by the deferred functions below
+	defer func() {
+		conf.EventListener.OnDumpCompleted(dumpErr)
+	}()
 	tctx.L().Info("begin to run Dump", zap.Stringer("conf", conf))
+	d.progress.start()
+	defer func() {
+		d.progress.finish(dumpErr)
+	}()
 	m := newGlobalMetadata(tctx, d.extStore, conf.Snapshot)
+	m.listener = conf.EventListener
 	repeatableRead := needRepeatableRead(conf.ServerInfo.ServerType, conf.Consistency)
 	defer func() {
 		if dumpErr == nil {
-			_ = m.writeGlobalMetaData()
+			files := d.manifest.manifestFiles()
+			m.recordTables(conf.Tables, files)
+			m.recordRestoreOrder(files)
+			// metadata.json is read by the tools restoring the dump, so the dump fails if it's not written
+			if err := m.writeGlobalMetaData(); err != nil {
+				tctx.L().Warn("fail to write metadata", zap.Error(err))
+				dumpErr = err
+				return
+			}
+			d.manifest.addFile(&ManifestFile{Name: metadataPath, Type: ManifestFileTypeMetadata})
+			d.manifest.addFile(&ManifestFile{Name: metadataJSONPath, Type: ManifestFileTypeMetadata})
+			dumpErr = d.manifest.write(tctx)
 		}
 	}()
+	defer func() {
+		if dumpErr != nil {
+			if err := d.checkpoint.flush(tctx); err != nil {
+				tctx.L().Warn("fail to flush checkpoint", zap.Error(err))
+			}
+			return
+		}
+		dumpErr = d.checkpoint.finish(tctx)
+	}()
 
 	// for consistency lock, we should get table list at first to generate the lock tables SQL
 	if conf.Consistency == consistencyTypeLock {
@@ -133,6 +183,7 @@ func (d *Dumper) Dump() (dumpErr error) {
 	}
 	defer metaConn.Close()
 	m.recordStartTime(time.Now())
+	m.recordServerInfo(conf.ServerInfo)
 	// for consistency lock, we can write snapshot info after all tables are locked.
 	// the binlog pos may changed because there is still possible write between we lock tables and write master status.
 	// but for the locked tables doing replication that starts from metadata is safe.
@@ -150,42 +201,61 @@ func (d *Dumper) Dump() (dumpErr error) {
 			return err
 		}
 	}
+	conf.EventListener.OnTableListResolved(conf.Tables)
 	if err = d.renewSelectTableRegionFuncForLowerTiDB(tctx); err != nil {
 		tctx.L().Error("fail to update select table region info for TiDB", zap.Error(err))
 	}
 
-	rebuildConn := func(conn *sql.Conn) (*sql.Conn, error) {
+	// connectWriter creates a consistent connection for a writer after the consistency is set up
+	connectWriter := func() (*sql.Conn, error) {
 		// make sure that the lock connection is still alive
 		err1 := conCtrl.PingContext(tctx)
 		if err1 != nil {
-			return conn, errors.Trace(err1)
+			return nil, errors.Trace(err1)
 		}
-		// give up the last broken connection
-		conn.Close()
-		newConn, err1 := createConnWithConsistency(tctx, pool, repeatableRead)
+		conn, err1 := createConnWithConsistency(tctx, pool, repeatableRead)
 		if err1 != nil {
-			return conn, errors.Trace(err1)
+			return nil, errors.Trace(err1)
 		}
-		conn = newConn
 		// renew the master status after connection. dm can't close safe-mode until dm reaches current pos
 		if conf.PosAfterConnect {
 			err1 = m.recordGlobalMetaData(conn, conf.ServerInfo.ServerType, true)
 			if err1 != nil {
-				return conn, errors.Trace(err1)
+				conn.Close()
+				return nil, errors.Trace(err1)
 			}
 		}
 		return conn, nil
 	}
+	rebuildConn := func(conn *sql.Conn) (*sql.Conn, error) {
+		newConn, err1 := connectWriter()
+		if err1 != nil {
+			return conn, err1
+		}
+		// give up the last broken connection
+		conn.Close()
+		return newConn, nil
+	}
+
+	needChecksum := conf.Checksum && !conf.NoData
+	if needChecksum && !useTiDBChecksum(conf) {
+		d.checksums = newRowChecksumCollector()
+	}
 
 	taskChan := make(chan Task, defaultDumpThreads)
 	AddGauge(taskChannelCapacity, conf.Labels, defaultDumpThreads)
+	if conf.WorkStealing && conf.SQL == "" {
+		d.stealer = newWorkStealer(taskChan, conf.Labels, conf.EventListener)
+	}
 	wg, writingCtx := errgroup.WithContext(tctx)
 	writerCtx := tctx.WithContext(writingCtx)
-	writers, tearDownWriters, err := d.startWriters(writerCtx, wg, taskChan, rebuildConn)
+	writers, err := d.startWriters(writerCtx, wg, taskChan, rebuildConn, connectWriter)
 	if err != nil {
 		return err
 	}
-	defer tearDownWriters()
+	defer writers.tearDown()
+	d.setWriterPool(writers)
+	defer d.setWriterPool(nil)
 
 	if conf.TransactionalConsistency {
 		if conf.Consistency == consistencyTypeFlush || conf.Consistency == consistencyTypeLock {
@@ -206,14 +276,21 @@ func (d *Dumper) Dump() (dumpErr error) {
 		}
 	}
 
-	summary.SetLogCollector(summary.NewLogCollector(tctx.L().Info))
-	summary.SetUnit(summary.BackupUnit)
-	defer summary.Summary(summary.BackupUnit)
+	// every dump has its own summary, the global collector of br would be shared by the dumps of a Server
+	conf.summary = summary.NewLogCollector(tctx.L().Info)
+	conf.summary.SetUnit(summary.BackupUnit)
+	defer conf.summary.Summary(summary.BackupUnit)
 
 	logProgressCtx, logProgressCancel := tctx.WithCancel()
 	go d.runLogProgress(logProgressCtx)
 	defer logProgressCancel()
 
+	if needGovernor(conf) {
+		governorCtx, governorCancel := tctx.WithCancel()
+		go d.runGovernor(governorCtx)
+		defer governorCancel()
+	}
+
 	tableDataStartTime := time.Now()
 
 	failpoint.Inject("PrintTiDBMemQuotaQuery", func(_ failpoint.Value) {
@@ -227,6 +304,11 @@ func (d *Dumper) Dump() (dumpErr error) {
 		}
 	})
 
+	if conf.DumpUsers {
+		if err = d.dumpUsers(tctx, metaConn); err != nil {
+			return err
+		}
+	}
 	if conf.SQL == "" {
 		if err = d.dumpDatabases(writerCtx, metaConn, taskChan); err != nil && !errors.ErrorEqual(err, context.Canceled) {
 			return err
@@ -234,30 +316,50 @@ func (d *Dumper) Dump() (dumpErr error) {
 	} else {
 		d.dumpSQL(writerCtx, taskChan)
 	}
-	close(taskChan)
+	if d.stealer != nil {
+		// writers may send the split tails of running chunks, taskChan is closed after all of them finish
+		d.stealer.finishDispatching()
+	} else {
+		close(taskChan)
+	}
 	_ = metaConn.Close()
 	if err := wg.Wait(); err != nil {
-		summary.CollectFailureUnit("dump table data", err)
+		conf.summary.CollectFailureUnit("dump table data", err)
 		return errors.Trace(err)
 	}
-	summary.CollectSuccessUnit("dump cost", countTotalTask(writers), time.Since(tableDataStartTime))
+	// the writers exit without error when the dump is canceled
+	if err := tctx.Err(); err != nil {
+		conf.summary.CollectFailureUnit("dump table data", err)
+		return errors.Trace(err)
+	}
+	conf.summary.CollectSuccessUnit("dump cost", writers.countTotalTask(), time.Since(tableDataStartTime))
+
+	if needChecksum {
+		checksums := d.checksums.tableChecksums()
+		if useTiDBChecksum(conf) {
+			if checksums, err = tidbChecksumTables(tctx, conf, d.dbHandle); err != nil {
+				return err
+			}
+		}
+		m.recordTableChecksums(checksums)
+	}
 
-	summary.SetSuccessStatus(true)
+	conf.summary.SetSuccessStatus(true)
 	m.recordFinishTime(time.Now())
 	return nil
 }
 
 func (d *Dumper) startWriters(tctx *tcontext.Context, wg *errgroup.Group, taskChan <-chan Task,
-	rebuildConnFn func(*sql.Conn) (*sql.Conn, error)) ([]*Writer, func(), error) {
+	rebuildConnFn func(*sql.Conn) (*sql.Conn, error), connectWriter func() (*sql.Conn, error)) (*writerPool, error) {
 	conf, pool := d.conf, d.dbHandle
-	writers := make([]*Writer, conf.Threads)
-	for i := 0; i < conf.Threads; i++ {
-		conn, err := createConnWithConsistency(tctx, pool, needRepeatableRead(conf.ServerInfo.ServerType, conf.Consistency))
-		if err != nil {
-			return nil, func() {}, err
-		}
-		writer := NewWriter(tctx, int64(i), conf, conn, d.extStore)
+	newWriter := func(id int64, conn *sql.Conn) *Writer {
+		writer := NewWriter(tctx, id, conf, conn, d.extStore)
 		writer.rebuildConnFn = rebuildConnFn
+		writer.checkpoint = d.checkpoint
+		writer.manifest = d.manifest
+		writer.checksums = d.checksums
+		writer.stealer = d.stealer
+		writer.progress = d.progress
 		writer.setFinishTableCallBack(func(task Task) {
 			if _, ok := task.(*TaskTableData); ok {
 				IncCounter(finishedTablesCounter, conf.Labels)
@@ -278,22 +380,51 @@ func (d *Dumper) startWriters(tctx *tcontext.Context, wg *errgroup.Group, taskCh
 					zap.Int("chunkIdx", td.ChunkIndex))
 			}
 		})
-		wg.Go(func() error {
-			return writer.run(taskChan)
-		})
-		writers[i] = writer
+		return writer
+	}
+	writers := &writerPool{
+		tctx:      tctx,
+		wg:        wg,
+		taskChan:  taskChan,
+		newWriter: newWriter,
+		connect: func() (*sql.Conn, error) {
+			// the connections created after the tables are unlocked are not consistent with the others
+			if !canRebuildConn(conf.Consistency, conf.TransactionalConsistency) {
+				return nil, errors.Errorf("can't add writers with consistency %s after the tables are unlocked, "+
+					"please set --transactional-consistency=false", conf.Consistency)
+			}
+			return connectWriter()
+		},
 	}
-	tearDown := func() {
-		for _, w := range writers {
-			w.conn.Close()
+	writers.mu.Lock()
+	defer writers.mu.Unlock()
+	for i := 0; i < conf.Threads; i++ {
+		conn, err := createConnWithConsistency(tctx, pool, needRepeatableRead(conf.ServerInfo.ServerType, conf.Consistency))
+		if err != nil {
+			return nil, err
 		}
+		writers.startLocked(conn)
 	}
-	return writers, tearDown, nil
+	return writers, nil
 }
 
 func (d *Dumper) dumpDatabases(tctx *tcontext.Context, metaConn *sql.Conn, taskChan chan<- Task) error {
 	conf := d.conf
 	allTables := conf.Tables
+	dumpObjects := !conf.NoSchemas && (conf.Routines || conf.Triggers || conf.Events)
+	if dumpObjects && conf.ServerInfo.ServerType == ServerTypeTiDB {
+		tctx.L().Warn("TiDB doesn't support stored procedures, functions, triggers and events, skip dumping them")
+		dumpObjects = false
+	}
+	// the schema files are written concurrently, the order to restore them is recorded in metadata.json
+	if !conf.NoSchemas && conf.ServerInfo.ServerType == ServerTypeTiDB {
+		if err := d.dumpResourceGroups(tctx, metaConn, taskChan); err != nil {
+			return err
+		}
+		if err := d.dumpPlacementPolicies(tctx, metaConn, taskChan); err != nil {
+			return err
+		}
+	}
 	for dbName, tables := range allTables {
 		if !conf.NoSchemas {
 			createDatabaseSQL, err := ShowCreateDatabase(metaConn, dbName)
@@ -307,7 +438,24 @@ func (d *Dumper) dumpDatabases(tctx *tcontext.Context, metaConn *sql.Conn, taskC
 			}
 		}
 
+		// the sequences must be restored before the tables whose default values may use them
 		for _, table := range tables {
+			if table.Type != TableTypeSequence || conf.NoSchemas {
+				continue
+			}
+			createSequenceSQL, err := ShowCreateSequence(metaConn, dbName, table.Name)
+			if err != nil {
+				return err
+			}
+			if d.sendTaskToChan(tctx, NewTaskSequenceMeta(dbName, table.Name, createSequenceSQL), taskChan) {
+				return tctx.Err()
+			}
+		}
+
+		for _, table := range tables {
+			if table.Type == TableTypeSequence {
+				continue
+			}
 			tctx.L().Debug("start dumping table...", zap.String("database", dbName),
 				zap.String("table", table.Name))
 			meta, err := dumpTableMeta(conf, metaConn, dbName, table)
@@ -337,8 +485,108 @@ func (d *Dumper) dumpDatabases(tctx *tcontext.Context, metaConn *sql.Conn, taskC
 				}
 			}
 		}
+		if dumpObjects {
+			if err := d.dumpDatabaseObjects(tctx, metaConn, dbName, tables, taskChan); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// dumpPlacementPolicies dumps the placement policies referenced by the dumped databases and tables
+func (d *Dumper) dumpPlacementPolicies(tctx *tcontext.Context, metaConn *sql.Conn, taskChan chan<- Task) error {
+	policies, err := ListPlacementPolicies(metaConn, d.conf.Tables)
+	if err != nil {
+		return err
+	}
+	for _, policy := range policies {
+		createSQL, err := ShowCreatePlacementPolicy(metaConn, policy)
+		if err != nil {
+			return err
+		}
+		if d.sendTaskToChan(tctx, NewTaskPolicyMeta(policy, createSQL), taskChan) {
+			return tctx.Err()
+		}
 	}
+	return nil
+}
 
+// dumpResourceGroups dumps the resource groups of TiDB, which may be referenced by the dumped users
+func (d *Dumper) dumpResourceGroups(tctx *tcontext.Context, metaConn *sql.Conn, taskChan chan<- Task) error {
+	resourceGroups, err := ListResourceGroups(metaConn)
+	if err != nil {
+		return err
+	}
+	for _, resourceGroup := range resourceGroups {
+		createSQL, err := ShowCreateResourceGroup(metaConn, resourceGroup)
+		if err != nil {
+			return err
+		}
+		if d.sendTaskToChan(tctx, NewTaskResourceGroupMeta(resourceGroup, createSQL), taskChan) {
+			return tctx.Err()
+		}
+	}
+	return nil
+}
+
+// dumpDatabaseObjects dumps the stored procedures, functions and events of a database, and the triggers of
+// its dumped tables
+func (d *Dumper) dumpDatabaseObjects(tctx *tcontext.Context, metaConn *sql.Conn, dbName string, tables []*TableInfo, taskChan chan<- Task) error {
+	conf := d.conf
+	if conf.Routines {
+		routines, err := ListRoutines(metaConn, dbName)
+		if err != nil {
+			return err
+		}
+		for _, routine := range routines {
+			createSQL, err := ShowCreateRoutine(metaConn, dbName, routine[0], routine[1])
+			if err != nil {
+				return err
+			}
+			if d.sendTaskToChan(tctx, NewTaskRoutineMeta(dbName, routine[0], routine[1], createSQL), taskChan) {
+				return tctx.Err()
+			}
+		}
+	}
+	if conf.Triggers {
+		triggers, err := ListTriggers(metaConn, dbName)
+		if err != nil {
+			return err
+		}
+		for _, table := range tables {
+			if len(triggers[table.Name]) == 0 {
+				continue
+			}
+			var createSQL strings.Builder
+			for _, trigger := range triggers[table.Name] {
+				createTriggerSQL, err := ShowCreateTrigger(metaConn, dbName, trigger)
+				if err != nil {
+					return err
+				}
+				createSQL.WriteString(createTriggerSQL)
+			}
+			if d.sendTaskToChan(tctx, NewTaskTriggerMeta(dbName, table.Name, createSQL.String()), taskChan) {
+				return tctx.Err()
+			}
+		}
+	}
+	if conf.Events {
+		events, err := ListEvents(metaConn, dbName)
+		if err != nil {
+			return err
+		}
+		for _, event := range events {
+			createSQL, err := ShowCreateEvent(metaConn, dbName, event)
+			if err != nil {
+				return err
+			}
+			if d.sendTaskToChan(tctx, NewTaskEventMeta(dbName, event, createSQL), taskChan) {
+				return tctx.Err()
+			}
+		}
+	}
 	return nil
 }
 
@@ -421,9 +669,8 @@ func (d *Dumper) buildConcatTask(tctx *tcontext.Context, conn *sql.Conn, meta Ta
 	}
 }
 
-func (d *Dumper) dumpWholeTableDirectly(tctx *tcontext.Context, meta TableMeta, taskChan chan<- Task, partition, orderByClause string, currentChunk, totalChunks int) error {
-	conf := d.conf
-	tableIR := SelectAllFromTable(conf, meta, partition, orderByClause)
+func (d *Dumper) dumpWholeTableDirectly(tctx *tcontext.Context, meta TableMeta, taskChan chan<- Task, partition string, orderByKeys []string, currentChunk, totalChunks int) error {
+	tableIR := d.newTableChunkData(meta, partition, "", buildOrderByClauseString(orderByKeys), orderByKeys, totalChunks)
 	task := NewTaskTableData(meta, tableIR, currentChunk, totalChunks)
 	ctxDone := d.sendTaskToChan(tctx, task, taskChan)
 	if ctxDone {
@@ -450,11 +697,11 @@ func (d *Dumper) sequentialDumpTable(tctx *tcontext.Context, conn *sql.Conn, met
 			zap.String("database", meta.DatabaseName()),
 			zap.String("table", meta.TableName()))
 	}
-	orderByClause, err := buildOrderByClause(conf, conn, meta.DatabaseName(), meta.TableName(), meta.HasImplicitRowID())
+	orderByKeys, err := buildOrderByKeys(conf, conn, meta.DatabaseName(), meta.TableName(), meta.HasImplicitRowID())
 	if err != nil {
 		return err
 	}
-	return d.dumpWholeTableDirectly(tctx, meta, taskChan, "", orderByClause, 0, 1)
+	return d.dumpWholeTableDirectly(tctx, meta, taskChan, "", orderByKeys, 0, 1)
 }
 
 // concurrentDumpTable tries to split table into several chunks to dump
@@ -474,17 +721,22 @@ func (d *Dumper) concurrentDumpTable(tctx *tcontext.Context, conn *sql.Conn, met
 			zap.String("database", db), zap.String("table", tbl), log.ShortError(err))
 	}
 
-	orderByClause, err := buildOrderByClause(conf, conn, db, tbl, meta.HasImplicitRowID())
+	orderByKeys, err := buildOrderByKeys(conf, conn, db, tbl, meta.HasImplicitRowID())
 	if err != nil {
 		return err
 	}
+	orderByClause := buildOrderByClauseString(orderByKeys)
 
 	field, err := pickupPossibleField(meta, conn)
-	if err != nil || field == "" {
+	if err == nil && field == "" {
+		// no proper integer field, try to split chunks on the primary key, which may be composite or non-integer
+		return d.concurrentDumpTableByPrimaryKey(tctx, conn, meta, taskChan, orderByKeys)
+	}
+	if err != nil {
 		// skip split chunk logic if not found proper field
 		tctx.L().Warn("fallback to sequential dump due to no proper field",
 			zap.String("database", db), zap.String("table", tbl), log.ShortError(err))
-		return d.dumpWholeTableDirectly(tctx, meta, taskChan, "", orderByClause, 0, 1)
+		return d.dumpWholeTableDirectly(tctx, meta, taskChan, "", orderByKeys, 0, 1)
 	}
 
 	count := estimateCount(d.tctx, db, tbl, conn, field, conf)
@@ -499,7 +751,11 @@ func (d *Dumper) concurrentDumpTable(tctx *tcontext.Context, conn *sql.Conn, met
 			zap.Uint64("conf.rows", conf.Rows),
 			zap.String("database", db),
 			zap.String("table", tbl))
-		return d.dumpWholeTableDirectly(tctx, meta, taskChan, "", orderByClause, 0, 1)
+		return d.dumpWholeTableDirectly(tctx, meta, taskChan, "", orderByKeys, 0, 1)
+	}
+
+	if conf.ChunkSplitMode == ChunkSplitModeSample {
+		return d.concurrentDumpTableBySampling(tctx, conn, meta, taskChan, field, orderByKeys)
 	}
 
 	min, max, err := d.selectMinAndMaxIntValue(conn, db, tbl, field)
@@ -520,8 +776,6 @@ func (d *Dumper) concurrentDumpTable(tctx *tcontext.Context, conn *sql.Conn, met
 		totalChunks = new(big.Int).Sub(max, min).Uint64() + 1
 	}
 
-	selectField, selectLen := meta.SelectedField(), meta.SelectedLen()
-
 	chunkIndex := 0
 	nullValueCondition := ""
 	if conf.Where == "" {
@@ -530,11 +784,10 @@ func (d *Dumper) concurrentDumpTable(tctx *tcontext.Context, conn *sql.Conn, met
 	for max.Cmp(cutoff) >= 0 {
 		nextCutOff := new(big.Int).Add(cutoff, bigEstimatedStep)
 		where := fmt.Sprintf("%s(`%s` >= %d AND `%s` < %d)", nullValueCondition, escapeString(field), cutoff, escapeString(field), nextCutOff)
-		query := buildSelectQuery(db, tbl, selectField, "", buildWhereCondition(conf, where), orderByClause)
 		if len(nullValueCondition) > 0 {
 			nullValueCondition = ""
 		}
-		task := NewTaskTableData(meta, newTableData(query, selectLen, false), chunkIndex, int(totalChunks))
+		task := NewTaskTableData(meta, d.newTableChunkData(meta, "", where, orderByClause, orderByKeys, int(totalChunks)), chunkIndex, int(totalChunks))
 		ctxDone := d.sendTaskToChan(tctx, task, taskChan)
 		if ctxDone {
 			return tctx.Err()
@@ -545,8 +798,89 @@ func (d *Dumper) concurrentDumpTable(tctx *tcontext.Context, conn *sql.Conn, met
 	return nil
 }
 
+// concurrentDumpTableByPrimaryKey splits table into chunks of about conf.Rows rows by sampling the boundary keys of its primary key
+func (d *Dumper) concurrentDumpTableByPrimaryKey(tctx *tcontext.Context, conn *sql.Conn, meta TableMeta, taskChan chan<- Task, orderByKeys []string) error {
+	conf := d.conf
+	db, tbl := meta.DatabaseName(), meta.TableName()
+	pkFields, pkColTypes, err := GetPrimaryKeyAndColumnTypes(conn, meta)
+	if err != nil || len(pkFields) == 0 {
+		// skip split chunk logic if not found proper field
+		tctx.L().Warn("fallback to sequential dump due to no proper field",
+			zap.String("database", db), zap.String("table", tbl), log.ShortError(err))
+		return d.dumpWholeTableDirectly(tctx, meta, taskChan, "", orderByKeys, 0, 1)
+	}
+
+	count := estimateCount(d.tctx, db, tbl, conn, "", conf)
+	tctx.L().Info("get estimated rows count",
+		zap.String("database", db),
+		zap.String("table", tbl),
+		zap.Uint64("estimateCount", count))
+	if count < conf.Rows {
+		// skip chunk logic if estimates are low
+		tctx.L().Warn("skip concurrent dump due to estimate count < rows",
+			zap.Uint64("estimate count", count),
+			zap.Uint64("conf.rows", conf.Rows),
+			zap.String("database", db),
+			zap.String("table", tbl))
+		return d.dumpWholeTableDirectly(tctx, meta, taskChan, "", orderByKeys, 0, 1)
+	}
+
+	tctx.L().Debug("dumping tables by sampling primary key",
+		zap.String("database", db), zap.String("table", tbl), zap.Strings("primary key", pkFields))
+	pkVals, err := selectKeyBoundaries(tctx, conn, conf, meta, pkFields, pkColTypes, "")
+	if err != nil {
+		if err2 := errors.Cause(err); err2 == context.DeadlineExceeded || err2 == context.Canceled {
+			return err
+		}
+		tctx.L().Warn("fallback to sequential dump due to fail to sample primary key",
+			zap.String("database", db), zap.String("table", tbl), log.ShortError(err))
+		return d.dumpWholeTableDirectly(tctx, meta, taskChan, "", orderByKeys, 0, 1)
+	}
+	if len(pkVals) == 0 {
+		return d.dumpWholeTableDirectly(tctx, meta, taskChan, "", orderByKeys, 0, 1)
+	}
+	return d.sendConcurrentDumpTasks(tctx, meta, taskChan, pkFields, pkVals, "", 0, len(pkVals)+1)
+}
+
+// concurrentDumpTableBySampling splits table into chunks of about conf.Rows rows by sampling the values of the integer field
+func (d *Dumper) concurrentDumpTableBySampling(tctx *tcontext.Context, conn *sql.Conn, meta TableMeta, taskChan chan<- Task, field string, orderByKeys []string) error {
+	conf := d.conf
+	db, tbl := meta.DatabaseName(), meta.TableName()
+	quotaField := fmt.Sprintf("`%s`", escapeString(field))
+	tctx.L().Debug("dumping tables by sampling field",
+		zap.String("database", db), zap.String("table", tbl), zap.String("field", field))
+	// the field may be a nullable unique key, NULL values are sorted first so they must be skipped
+	vals, err := selectKeyBoundaries(tctx, conn, conf, meta, []string{field}, []string{"BIGINT"}, quotaField+" IS NOT NULL")
+	if err != nil {
+		return err
+	}
+	if len(vals) == 0 {
+		return d.dumpWholeTableDirectly(tctx, meta, taskChan, "", orderByKeys, 0, 1)
+	}
+
+	where := buildWhereClauses([]string{field}, vals)
+	if conf.Where == "" {
+		where[0] = fmt.Sprintf("%s IS NULL OR (%s)", quotaField, where[0])
+	}
+	for i, w := range where {
+		task := NewTaskTableData(meta, d.newTableChunkData(meta, "", w, buildOrderByClauseString(orderByKeys), orderByKeys, len(where)), i, len(where))
+		ctxDone := d.sendTaskToChan(tctx, task, taskChan)
+		if ctxDone {
+			return tctx.Err()
+		}
+	}
+	return nil
+}
+
 func (d *Dumper) sendTaskToChan(tctx *tcontext.Context, task Task, taskChan chan<- Task) (ctxDone bool) {
 	conf := d.conf
+	// stop dispatching while the dump is paused
+	if err := conf.throttle.waitResumed(tctx); err != nil {
+		return true
+	}
+	if t, ok := task.(*TaskTableData); ok {
+		d.progress.addChunk(t)
+	}
 	select {
 	case <-tctx.Done():
 		return true
@@ -554,6 +888,7 @@ func (d *Dumper) sendTaskToChan(tctx *tcontext.Context, task Task, taskChan chan
 		tctx.L().Debug("send task to writer",
 			zap.String("task", task.Brief()))
 		DecGauge(taskChannelCapacity, conf.Labels)
+		conf.EventListener.OnTaskDispatched(task)
 		return false
 	}
 }
@@ -625,7 +960,7 @@ func (d *Dumper) concurrentDumpTiDBTables(tctx *tcontext.Context, conn *sql.Conn
 	if err != nil {
 		return err
 	}
-	return d.sendConcurrentDumpTiDBTasks(tctx, meta, taskChan, handleColNames, handleVals, "", 0, len(handleVals)+1)
+	return d.sendConcurrentDumpTasks(tctx, meta, taskChan, handleColNames, handleVals, "", 0, len(handleVals)+1)
 }
 
 func (d *Dumper) concurrentDumpTiDBPartitionTables(tctx *tcontext.Context, conn *sql.Conn, meta TableMeta, taskChan chan<- Task, partitions []string) error {
@@ -651,7 +986,7 @@ func (d *Dumper) concurrentDumpTiDBPartitionTables(tctx *tcontext.Context, conn
 		cachedHandleVals[i] = handleVals
 	}
 	for i, partition := range partitions {
-		err := d.sendConcurrentDumpTiDBTasks(tctx, meta, taskChan, handleColNames, cachedHandleVals[i], partition, startChunkIdx, totalChunk)
+		err := d.sendConcurrentDumpTasks(tctx, meta, taskChan, handleColNames, cachedHandleVals[i], partition, startChunkIdx, totalChunk)
 		if err != nil {
 			return err
 		}
@@ -660,7 +995,7 @@ func (d *Dumper) concurrentDumpTiDBPartitionTables(tctx *tcontext.Context, conn
 	return nil
 }
 
-func (d *Dumper) sendConcurrentDumpTiDBTasks(tctx *tcontext.Context,
+func (d *Dumper) sendConcurrentDumpTasks(tctx *tcontext.Context,
 	meta TableMeta, taskChan chan<- Task,
 	handleColNames []string, handleVals [][]string, partition string, startChunkIdx, totalChunk int) error {
 	db, tbl := meta.DatabaseName(), meta.TableName()
@@ -669,16 +1004,13 @@ func (d *Dumper) sendConcurrentDumpTiDBTasks(tctx *tcontext.Context,
 			// return error to make outside function try using rows method to dump data
 			return errors.Errorf("empty handleVals for TiDB table `%s`.`%s`", escapeString(db), escapeString(tbl))
 		}
-		return d.dumpWholeTableDirectly(tctx, meta, taskChan, partition, buildOrderByClauseString(handleColNames), startChunkIdx, totalChunk)
+		return d.dumpWholeTableDirectly(tctx, meta, taskChan, partition, handleColNames, startChunkIdx, totalChunk)
 	}
-	conf := d.conf
-	selectField, selectLen := meta.SelectedField(), meta.SelectedLen()
 	where := buildWhereClauses(handleColNames, handleVals)
 	orderByClause := buildOrderByClauseString(handleColNames)
 
 	for i, w := range where {
-		query := buildSelectQuery(db, tbl, selectField, partition, buildWhereCondition(conf, w), orderByClause)
-		task := NewTaskTableData(meta, newTableData(query, selectLen, false), i+startChunkIdx, totalChunk)
+		task := NewTaskTableData(meta, d.newTableChunkData(meta, partition, w, orderByClause, handleColNames, totalChunk), i+startChunkIdx, totalChunk)
 		ctxDone := d.sendTaskToChan(tctx, task, taskChan)
 		if ctxDone {
 			return tctx.Err()
@@ -699,32 +1031,81 @@ func selectTiDBTableSample(tctx *tcontext.Context, conn *sql.Conn, meta TableMet
 	}
 
 	query := buildTiDBTableSampleQuery(pkFields, meta.DatabaseName(), meta.TableName())
+	pkVals, err = selectHandleVals(tctx, conn, query, pkColTypes)
+	return pkFields, pkVals, err
+}
+
+// selectHandleVals runs query which selects handle columns and returns the values of every row as SQL literals.
+// The literals are sent back to the server, so they are always escaped no matter what conf.EscapeBackslash is.
+func selectHandleVals(tctx *tcontext.Context, conn *sql.Conn, query string, handleColTypes []string) ([][]string, error) {
 	rows, err := conn.QueryContext(tctx, query)
 	if err != nil {
-		return nil, nil, errors.Trace(err)
+		return nil, errors.Annotatef(err, "sql: %s", query)
 	}
-	pkValNum := len(pkFields)
-	iter := newRowIter(rows, pkValNum)
+	handleValNum := len(handleColTypes)
+	iter := newRowIter(rows, handleValNum)
 	defer iter.Close()
-	rowRec := MakeRowReceiver(pkColTypes)
+	rowRec := MakeRowReceiver(handleColTypes)
 	buf := new(bytes.Buffer)
 
+	var handleVals [][]string
 	for iter.HasNext() {
 		err = iter.Decode(rowRec)
 		if err != nil {
-			return nil, nil, errors.Trace(err)
+			return nil, errors.Annotatef(err, "sql: %s", query)
 		}
-		pkValRow := make([]string, 0, pkValNum)
+		handleValRow := make([]string, 0, handleValNum)
 		for _, rec := range rowRec.receivers {
 			rec.WriteToBuffer(buf, true)
-			pkValRow = append(pkValRow, buf.String())
+			handleValRow = append(handleValRow, buf.String())
 			buf.Reset()
 		}
-		pkVals = append(pkVals, pkValRow)
+		handleVals = append(handleVals, handleValRow)
 		iter.Next()
 	}
 	iter.Close()
-	return pkFields, pkVals, iter.Error()
+	return handleVals, iter.Error()
+}
+
+// selectKeyBoundaries walks through the key in order and picks the key of every conf.Rows rows as the boundaries
+// of chunks. Each query starts from the previous boundary, so it only scans conf.Rows keys of the index.
+// firstWhere is the condition of the first query, which can be used to skip NULL values of a nullable key.
+func selectKeyBoundaries(tctx *tcontext.Context, conn *sql.Conn, conf *Config, meta TableMeta, keyFields, keyColTypes []string, firstWhere string) ([][]string, error) {
+	quotaCols := make([]string, len(keyFields))
+	for i, s := range keyFields {
+		quotaCols[i] = fmt.Sprintf("`%s`", escapeString(s))
+	}
+	fields, orderByClause := strings.Join(quotaCols, ","), buildOrderByClauseString(keyFields)
+	buf := new(bytes.Buffer)
+
+	var keyVals [][]string
+	for {
+		where, offset := firstWhere, conf.Rows
+		// the next boundary must be greater than the last one, otherwise a sampled field with more than conf.Rows
+		// duplicated values returns the same boundary forever. The last boundary itself is the first row of the
+		// next chunk, so one less row is skipped.
+		if len(keyVals) > 0 {
+			buildCompareClause(buf, quotaCols, keyVals[len(keyVals)-1], greater, false)
+			where = buf.String()
+			buf.Reset()
+			if offset > 0 {
+				offset--
+			}
+		}
+		query := buildSelectQuery(meta.DatabaseName(), meta.TableName(), fields, "", buildWhereCondition(conf, where), orderByClause)
+		query = fmt.Sprintf("%s LIMIT 1 OFFSET %d", query, offset)
+		boundary, err := selectHandleVals(tctx, conn, query, keyColTypes)
+		if err != nil {
+			return nil, err
+		}
+		if len(boundary) == 0 {
+			return keyVals, nil
+		}
+		keyVals = append(keyVals, boundary[0])
+		if err = tctx.Err(); err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
 }
 
 func buildTiDBTableSampleQuery(pkFields []string, dbName, tblName string) string {
@@ -875,6 +1256,9 @@ func prepareTableListToDump(tctx *tcontext.Context, conf *Config, db *sql.Conn)
 	if !conf.NoViews {
 		tableTypes = append(tableTypes, TableTypeView)
 	}
+	if conf.ServerInfo.ServerType == ServerTypeTiDB {
+		tableTypes = append(tableTypes, TableTypeSequence)
+	}
 	conf.Tables, err = ListAllDatabasesTables(tctx, db, databases, getListTableTypeByConf(conf), tableTypes...)
 	if err != nil {
 		return err
@@ -886,7 +1270,7 @@ func prepareTableListToDump(tctx *tcontext.Context, conf *Config, db *sql.Conn)
 
 func dumpTableMeta(conf *Config, conn *sql.Conn, db string, table *TableInfo) (TableMeta, error) {
 	tbl := table.Name
-	selectField, selectLen, err := buildSelectField(conn, db, tbl, conf.CompleteInsert)
+	selectField, selectLen, unsignedBigints, err := buildSelectField(conn, db, tbl, conf.CompleteInsert)
 	if err != nil {
 		return nil, err
 	}
@@ -919,6 +1303,7 @@ func dumpTableMeta(conf *Config, conn *sql.Conn, db string, table *TableInfo) (T
 		selectedField:    selectField,
 		selectedLen:      selectLen,
 		hasImplicitRowID: hasImplicitRowID,
+		unsignedBigints:  unsignedBigints,
 		specCmts: []string{
 			"/*!40101 SET NAMES binary*/;",
 		},
@@ -967,12 +1352,82 @@ func canRebuildConn(consistency string, trxConsistencyOnly bool) bool {
 // Close closes a Dumper and stop dumping immediately
 func (d *Dumper) Close() error {
 	d.cancelCtx()
+	if d.conf.tlsConfigName != "" {
+		mysql.DeregisterTLSConfig(d.conf.tlsConfigName)
+	}
 	if d.dbHandle != nil {
 		return d.dbHandle.Close()
 	}
 	return nil
 }
 
+// Pause pauses dispatching tasks and dumping data until Resume is called
+func (d *Dumper) Pause() error {
+	if !d.progress.setState(DumpStatePaused) {
+		return errors.New("dump is not running")
+	}
+	d.conf.throttle.pause(pauserUser)
+	d.L().Info("dump is paused")
+	return nil
+}
+
+// Resume resumes the dump paused by Pause
+func (d *Dumper) Resume() error {
+	if !d.progress.setState(DumpStateRunning) {
+		return errors.New("dump is not running")
+	}
+	if d.conf.throttle.resume(pauserUser) {
+		d.L().Info("dump is resumed")
+	}
+	return nil
+}
+
+// Cancel stops dumping, Dump returns with a canceled error. Unlike Close, it doesn't release the resources of the Dumper
+func (d *Dumper) Cancel() error {
+	if !d.progress.setState(DumpStateCanceled) {
+		return errors.New("dump is not running")
+	}
+	d.L().Info("dump is canceled")
+	d.cancelCtx()
+	return nil
+}
+
+func (d *Dumper) setWriterPool(writers *writerPool) {
+	d.writersMu.Lock()
+	d.writers = writers
+	d.writersMu.Unlock()
+}
+
+// SetThreads changes the number of writers while dumping. The added writers use consistent connections, and
+// the retired writers exit after they finish their current tasks.
+func (d *Dumper) SetThreads(n int) error {
+	d.writersMu.Lock()
+	writers := d.writers
+	d.writersMu.Unlock()
+	if writers == nil {
+		return errors.New("dump is not dumping data")
+	}
+	return writers.resize(n)
+}
+
+// Threads returns the number of writers, it's 0 if the dump is not dumping data
+func (d *Dumper) Threads() int {
+	d.writersMu.Lock()
+	writers := d.writers
+	d.writersMu.Unlock()
+	if writers == nil {
+		return 0
+	}
+	return writers.size()
+}
+
+// Status returns the progress of the dump
+func (d *Dumper) Status() *DumpStatus {
+	status := d.progress.status(d.conf)
+	status.Threads = d.Threads()
+	return status
+}
+
 func runSteps(d *Dumper, steps ...func(*Dumper) error) error {
 	for _, st := range steps {
 		err := st(d)
@@ -1016,6 +1471,20 @@ func createExternalStore(d *Dumper) error {
 	if err != nil {
 		return errors.Trace(err)
 	}
+	// checksumStorage is below the encrypting storage, so the checksums are calculated on the stored ciphertext
+	// and the dump can be verified without the key
+	d.manifest = newManifestCollector(extStore, conf.EventListener)
+	extStore = &checksumStorage{ExternalStorage: extStore, manifest: d.manifest}
+	if conf.KeyProvider != nil {
+		key, err := conf.KeyProvider.Key(tctx)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if extStore, err = newEncryptedStorage(extStore, key); err != nil {
+			return err
+		}
+		tctx.L().Info("all the output files will be encrypted")
+	}
 	d.extStore = extStore
 	return nil
 }
@@ -1025,7 +1494,9 @@ func startHTTPService(d *Dumper) error {
 	conf := d.conf
 	if conf.StatusAddr != "" {
 		go func() {
-			err := startDumplingService(d.tctx, conf.StatusAddr)
+			err := startDumplingService(d.tctx, conf.StatusAddr, func(router *http.ServeMux) {
+				registerControlAPI(router, d)
+			})
 			if err != nil {
 				d.L().Warn("meet error when stopping dumpling http service", log.ShortError(err))
 			}
@@ -1074,6 +1545,30 @@ func resolveAutoConsistency(d *Dumper) error {
 	return nil
 }
 
+// initCheckpoint is an initialization step of Dumper.
+func initCheckpoint(d *Dumper) error {
+	conf := d.conf
+	if conf.SQL != "" {
+		return nil
+	}
+	if !conf.Resume {
+		if conf.Checkpoint {
+			d.checkpoint = newCheckpoint(d.extStore, conf)
+		}
+		return nil
+	}
+	if conf.Consistency != consistencyTypeSnapshot {
+		d.tctx.L().Warn("resume dump without snapshot consistency, the dumped data may be inconsistent",
+			zap.String("consistency", conf.Consistency))
+	}
+	cp, err := loadCheckpoint(d.tctx, d.extStore, conf)
+	if err != nil {
+		return err
+	}
+	d.checkpoint = cp
+	return nil
+}
+
 // tidbSetPDClientForGC is an initialization step of Dumper.
 func tidbSetPDClientForGC(d *Dumper) error {
 	tctx, si, pool := d.tctx, d.conf.ServerInfo, d.dbHandle
@@ -5,14 +5,20 @@ package export
 import (
 	"context"
 	"fmt"
+	"io/ioutil"
+	"path"
+	"regexp"
 	"testing"
 	"time"
 
 	tcontext "github.com/pingcap/dumpling/v4/context"
+	"github.com/pingcap/dumpling/v4/log"
 	"github.com/stretchr/testify/require"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/pingcap/errors"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
 	"golang.org/x/sync/errgroup"
 )
 
@@ -97,6 +103,21 @@ func TestDumpTableMeta(t *testing.T) {
 		require.Equal(t, "", meta.ShowCreateTable())
 		require.Equal(t, hasImplicitRowID, meta.HasImplicitRowID())
 	}
+
+	// go-sql-driver reports BIGINT UNSIGNED as BIGINT
+	conf.ServerInfo.ServerType = ServerTypeMySQL
+	mock.ExpectQuery("SHOW COLUMNS FROM").
+		WillReturnRows(sqlmock.NewRows([]string{"Field", "Type", "Null", "Key", "Default", "Extra"}).
+			AddRow("id", "bigint(20) unsigned", "NO", "PRI", nil, "").
+			AddRow("n", "bigint", "YES", "", nil, ""))
+	mock.ExpectQuery(fmt.Sprintf("SELECT \\* FROM `%s`.`%s`", database, table)).
+		WillReturnRows(sqlmock.NewRowsWithColumnDefinition(
+			sqlmock.NewColumn("id").OfType("BIGINT", int64(0)),
+			sqlmock.NewColumn("n").OfType("BIGINT", int64(0))).AddRow(1, 1))
+	meta, err := dumpTableMeta(conf, conn, database, &TableInfo{Type: TableTypeBase, Name: table})
+	require.NoError(t, err)
+	require.Equal(t, []string{"UNSIGNED BIGINT", "BIGINT"}, meta.ColumnTypes())
+	require.NoError(t, mock.ExpectationsWereMet())
 }
 
 func TestGetListTableTypeByConf(t *testing.T) {
@@ -127,3 +148,75 @@ func TestGetListTableTypeByConf(t *testing.T) {
 		require.Equalf(t, x.expected, getListTableTypeByConf(conf), "server info: %s, consistency: %s", x.serverInfo, x.consistency)
 	}
 }
+
+func TestConcurrentDumps(t *testing.T) {
+	t.Parallel()
+
+	// the dumps of a Server run in the same process, they must not share the summary or any other state
+	newDumper := func() (*Dumper, sqlmock.Sqlmock, *observer.ObservedLogs) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, db.Close())
+		})
+		conf := defaultConfigForTest(t)
+		conf.OutputDirPath = t.TempDir()
+		conf.Consistency = consistencyTypeNone
+		conf.ServerInfo = ServerInfo{ServerType: ServerTypeMySQL}
+		conf.SQL = "SELECT * FROM `foo`.`bar`"
+		conf.Threads = 1
+		conf.OutputFileTemplate, err = ParseOutputFileTemplate(DefaultAnonymousOutputFileTemplateText)
+		require.NoError(t, err)
+		require.NoError(t, adjustConfig(conf, adjustThrottle, adjustEventListener))
+		core, logs := observer.New(zap.InfoLevel)
+		tctx, cancel := tcontext.Background().WithLogger(log.NewAppLogger(zap.New(core))).WithCancel()
+		d := &Dumper{
+			tctx:                      tctx,
+			conf:                      conf,
+			cancelCtx:                 cancel,
+			dbHandle:                  db,
+			progress:                  newProgressTracker(),
+			selectTiDBTableRegionFunc: selectTiDBTableRegion,
+		}
+		require.NoError(t, createExternalStore(d))
+
+		expectConn := func() {
+			mock.ExpectExec("SET SESSION TRANSACTION ISOLATION LEVEL REPEATABLE READ").WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectExec("START TRANSACTION").WillReturnResult(sqlmock.NewResult(0, 0))
+		}
+		// the meta connection
+		expectConn()
+		mock.ExpectQuery("SHOW DATABASES").WillReturnRows(sqlmock.NewRows([]string{"Database"}))
+		// the connection of the writer
+		expectConn()
+		mock.ExpectQuery(regexp.QuoteMeta(conf.SQL)).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2))
+		return d, mock, logs
+	}
+
+	d1, mock1, logs1 := newDumper()
+	d2, mock2, logs2 := newDumper()
+	var wg errgroup.Group
+	wg.Go(d1.Dump)
+	wg.Go(d2.Dump)
+	require.NoError(t, wg.Wait())
+	require.NoError(t, mock1.ExpectationsWereMet())
+	require.NoError(t, mock2.ExpectationsWereMet())
+	// the meta connection and the connection of the writer are closed with the pool
+	for _, mock := range []sqlmock.Sqlmock{mock1, mock2} {
+		mock.ExpectClose()
+		mock.ExpectClose()
+	}
+
+	for _, d := range []*Dumper{d1, d2} {
+		content, err := ioutil.ReadFile(path.Join(d.conf.OutputDirPath, "result.000000000.sql"))
+		require.NoError(t, err)
+		require.Equal(t, "/*!40101 SET NAMES binary*/;\nINSERT INTO `` (`id`) VALUES\n('1'),\n('2');\n", string(content))
+	}
+	// every dump logs its own summary, which only counts its own rows
+	for _, logs := range []*observer.ObservedLogs{logs1, logs2} {
+		summaries := logs.FilterMessage("backup success summary").All()
+		require.Len(t, summaries, 1)
+		require.Equal(t, uint64(2), summaries[0].ContextMap()["total-rows"])
+	}
+}
@@ -0,0 +1,349 @@
+// Copyright 2021 PingCAP, Inc. Licensed under Apache-2.0.
+
+package export
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"io"
+	"math"
+	"os"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tidb/br/pkg/storage"
+)
+
+// Every encrypted file starts with encryptMagic, a random salt and a random nonce prefix, followed by chunks.
+// Each chunk is at most encryptChunkSize bytes of plaintext sealed by AES-256-GCM with the file key, which is
+// derived from the key and the salt by HKDF-SHA256, so the nonces are never reused across the files encrypted
+// by the same key. The nonce of a chunk is the nonce prefix, the big endian chunk counter and a byte marking
+// the last chunk, so chunks can't be reordered and truncated files can't be decrypted.
+const (
+	// EncryptKeySize is the size of the AES-256 key used to encrypt output files
+	EncryptKeySize = 32
+
+	encryptChunkSize       = 64 * 1024
+	encryptSaltSize        = 32
+	encryptNoncePrefixSize = 7
+	encryptTagSize         = 16
+	// encryptHeaderSize is the size of encryptMagic, the salt and the nonce prefix
+	encryptHeaderSize = 8 + encryptSaltSize + encryptNoncePrefixSize
+)
+
+var (
+	encryptMagic = []byte{'D', 'U', 'M', 'P', 'E', 'N', 'C', 2}
+	// encryptKeyInfo is the info of HKDF to derive the file keys
+	encryptKeyInfo = []byte("dumpling file key")
+)
+
+// KeyProvider provides the key which encrypts all the output files. It can be implemented by a KMS client.
+type KeyProvider interface {
+	// Key returns the AES-256 key, which must be EncryptKeySize bytes
+	Key(ctx context.Context) ([]byte, error)
+}
+
+// FileKeyProvider reads the key from a local file, which contains either EncryptKeySize raw bytes
+// or the hex encoding of them.
+type FileKeyProvider struct {
+	Path string
+}
+
+// Key implements KeyProvider.Key
+func (p *FileKeyProvider) Key(_ context.Context) ([]byte, error) {
+	content, err := os.ReadFile(p.Path)
+	if err != nil {
+		return nil, errors.Annotatef(err, "fail to read encrypt key file %s", p.Path)
+	}
+	if len(content) == EncryptKeySize {
+		return content, nil
+	}
+	key, err := hex.DecodeString(string(bytes.TrimSpace(content)))
+	if err != nil || len(key) != EncryptKeySize {
+		return nil, errors.Errorf("encrypt key file %s should contain %d raw bytes or %d hex characters", p.Path, EncryptKeySize, 2*EncryptKeySize)
+	}
+	return key, nil
+}
+
+func newEncryptAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != EncryptKeySize {
+		return nil, errors.Errorf("invalid encrypt key size %d, should be %d", len(key), EncryptKeySize)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	aead, err := cipher.NewGCM(block)
+	return aead, errors.Trace(err)
+}
+
+// deriveFileKey derives the key of a file from the key and the salt of the file by HKDF-SHA256,
+// a single block of HKDF-Expand is enough for an AES-256 key
+func deriveFileKey(key, salt []byte) []byte {
+	extract := hmac.New(sha256.New, salt)
+	extract.Write(key)
+	expand := hmac.New(sha256.New, extract.Sum(nil))
+	expand.Write(encryptKeyInfo)
+	expand.Write([]byte{1})
+	return expand.Sum(nil)
+}
+
+// newFileAEAD returns the AEAD of a file from the key and the header of the file
+func newFileAEAD(key, header []byte) (cipher.AEAD, error) {
+	if len(key) != EncryptKeySize {
+		return nil, errors.Errorf("invalid encrypt key size %d, should be %d", len(key), EncryptKeySize)
+	}
+	salt := header[len(encryptMagic) : len(encryptMagic)+encryptSaltSize]
+	return newEncryptAEAD(deriveFileKey(key, salt))
+}
+
+func encryptNonce(nonce, prefix []byte, counter uint32, last bool) []byte {
+	nonce = append(nonce[:0], prefix...)
+	nonce = append(nonce, 0, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(nonce[encryptNoncePrefixSize:], counter)
+	if last {
+		nonce[len(nonce)-1] = 1
+	}
+	return nonce
+}
+
+// encryptFileWriter is a storage.ExternalFileWriter which encrypts the written bytes
+type encryptFileWriter struct {
+	w       storage.ExternalFileWriter
+	aead    cipher.AEAD
+	prefix  []byte
+	counter uint32
+	nonce   []byte
+	buf     []byte
+	out     []byte
+}
+
+func newEncryptFileWriter(ctx context.Context, w storage.ExternalFileWriter, key []byte) (*encryptFileWriter, error) {
+	header := make([]byte, encryptHeaderSize)
+	copy(header, encryptMagic)
+	if _, err := rand.Read(header[len(encryptMagic):]); err != nil {
+		return nil, errors.Trace(err)
+	}
+	aead, err := newFileAEAD(key, header)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = w.Write(ctx, header); err != nil {
+		return nil, errors.Trace(err)
+	}
+	return &encryptFileWriter{
+		w:      w,
+		aead:   aead,
+		prefix: header[len(encryptMagic)+encryptSaltSize:],
+		buf:    make([]byte, 0, encryptChunkSize),
+	}, nil
+}
+
+func (e *encryptFileWriter) seal(ctx context.Context, last bool) error {
+	if e.counter == math.MaxUint32 {
+		return errors.New("too many chunks in an encrypted file")
+	}
+	e.nonce = encryptNonce(e.nonce, e.prefix, e.counter, last)
+	e.out = e.aead.Seal(e.out[:0], e.nonce, e.buf, nil)
+	e.counter++
+	e.buf = e.buf[:0]
+	_, err := e.w.Write(ctx, e.out)
+	return errors.Trace(err)
+}
+
+// Write implements storage.ExternalFileWriter.Write
+func (e *encryptFileWriter) Write(ctx context.Context, p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		// only seal a full chunk when there is more data, the last chunk is sealed in Close
+		if len(e.buf) == encryptChunkSize {
+			if err := e.seal(ctx, false); err != nil {
+				return written, err
+			}
+		}
+		n := copy(e.buf[len(e.buf):encryptChunkSize], p)
+		e.buf = e.buf[:len(e.buf)+n]
+		p = p[n:]
+		written += n
+	}
+	return written, nil
+}
+
+// Close implements storage.ExternalFileWriter.Close. It seals the last chunk and closes the file
+func (e *encryptFileWriter) Close(ctx context.Context) error {
+	if err := e.seal(ctx, true); err != nil {
+		_ = e.w.Close(ctx)
+		return err
+	}
+	return e.w.Close(ctx)
+}
+
+// decryptReader decrypts the content written by encryptFileWriter
+type decryptReader struct {
+	r       *bufio.Reader
+	aead    cipher.AEAD
+	prefix  []byte
+	counter uint32
+	nonce   []byte
+	chunk   []byte
+	plain   []byte
+	done    bool
+}
+
+// NewDecryptReader returns a reader which decrypts a file written by dumpling with the encrypt key.
+// It returns an error if the file is modified or truncated.
+func NewDecryptReader(r io.Reader, key []byte) (io.Reader, error) {
+	if len(key) != EncryptKeySize {
+		return nil, errors.Errorf("invalid encrypt key size %d, should be %d", len(key), EncryptKeySize)
+	}
+	header := make([]byte, encryptHeaderSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, errors.Annotate(err, "fail to read the header of encrypted file")
+	}
+	if !bytes.Equal(header[:len(encryptMagic)], encryptMagic) {
+		return nil, errors.New("the file is not encrypted by dumpling")
+	}
+	aead, err := newFileAEAD(key, header)
+	if err != nil {
+		return nil, err
+	}
+	return &decryptReader{
+		r:      bufio.NewReaderSize(r, encryptChunkSize+encryptTagSize),
+		aead:   aead,
+		prefix: header[len(encryptMagic)+encryptSaltSize:],
+		chunk:  make([]byte, encryptChunkSize+encryptTagSize),
+	}, nil
+}
+
+func (d *decryptReader) openNextChunk() error {
+	n, err := io.ReadFull(d.r, d.chunk)
+	last := false
+	switch err {
+	case nil:
+		if _, err = d.r.Peek(1); err == io.EOF {
+			last = true
+		} else if err != nil {
+			return errors.Trace(err)
+		}
+	case io.EOF, io.ErrUnexpectedEOF:
+		last = true
+	default:
+		return errors.Trace(err)
+	}
+	if n < encryptTagSize {
+		return errors.New("encrypted file is truncated")
+	}
+	d.nonce = encryptNonce(d.nonce, d.prefix, d.counter, last)
+	d.plain, err = d.aead.Open(d.plain[:0], d.nonce, d.chunk[:n], nil)
+	if err != nil {
+		return errors.Annotatef(err, "fail to decrypt chunk %d, the file may be modified or truncated, or the key is wrong", d.counter)
+	}
+	d.counter++
+	d.done = last
+	return nil
+}
+
+// Read implements io.Reader
+func (d *decryptReader) Read(p []byte) (int, error) {
+	for len(d.plain) == 0 {
+		if d.done {
+			return 0, io.EOF
+		}
+		if err := d.openNextChunk(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, d.plain)
+	d.plain = d.plain[n:]
+	return n, nil
+}
+
+// decryptFileReader implements storage.ExternalFileReader on decryptReader
+type decryptFileReader struct {
+	io.Reader
+	io.Closer
+}
+
+// Seek implements io.Seeker
+func (r *decryptFileReader) Seek(_ int64, _ int) (int64, error) {
+	return 0, errors.New("encrypted file reader doesn't support Seek")
+}
+
+// encryptedStorage is a storage.ExternalStorage which encrypts all the files it writes
+// and decrypts all the files it reads.
+type encryptedStorage struct {
+	storage.ExternalStorage
+	key []byte
+}
+
+func newEncryptedStorage(s storage.ExternalStorage, key []byte) (storage.ExternalStorage, error) {
+	if _, err := newEncryptAEAD(key); err != nil {
+		return nil, err
+	}
+	return &encryptedStorage{ExternalStorage: s, key: key}, nil
+}
+
+// Create implements storage.ExternalStorage.Create
+func (s *encryptedStorage) Create(ctx context.Context, path string) (storage.ExternalFileWriter, error) {
+	w, err := s.ExternalStorage.Create(ctx, path)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	ew, err := newEncryptFileWriter(ctx, w, s.key)
+	if err != nil {
+		_ = w.Close(ctx)
+		return nil, err
+	}
+	return ew, nil
+}
+
+// WriteFile implements storage.ExternalStorage.WriteFile
+func (s *encryptedStorage) WriteFile(ctx context.Context, name string, data []byte) error {
+	bf := storage.NewBufferWriter()
+	ew, err := newEncryptFileWriter(ctx, bf, s.key)
+	if err != nil {
+		return err
+	}
+	if _, err = ew.Write(ctx, data); err != nil {
+		return err
+	}
+	if err = ew.Close(ctx); err != nil {
+		return err
+	}
+	return s.ExternalStorage.WriteFile(ctx, name, bf.Bytes())
+}
+
+// ReadFile implements storage.ExternalStorage.ReadFile
+func (s *encryptedStorage) ReadFile(ctx context.Context, name string) ([]byte, error) {
+	data, err := s.ExternalStorage.ReadFile(ctx, name)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	r, err := NewDecryptReader(bytes.NewReader(data), s.key)
+	if err != nil {
+		return nil, err
+	}
+	data, err = io.ReadAll(r)
+	return data, errors.Trace(err)
+}
+
+// Open implements storage.ExternalStorage.Open
+func (s *encryptedStorage) Open(ctx context.Context, path string) (storage.ExternalFileReader, error) {
+	fr, err := s.ExternalStorage.Open(ctx, path)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	r, err := NewDecryptReader(fr, s.key)
+	if err != nil {
+		_ = fr.Close()
+		return nil, err
+	}
+	return &decryptFileReader{Reader: r, Closer: fr}, nil
+}
@@ -0,0 +1,165 @@
+// Copyright 2021 PingCAP, Inc. Licensed under Apache-2.0.
+
+package export
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	tcontext "github.com/pingcap/dumpling/v4/context"
+
+	"github.com/pingcap/tidb/br/pkg/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func encryptForTest(t *testing.T, key, data []byte) []byte {
+	ctx := context.Background()
+	bf := storage.NewBufferWriter()
+	w, err := newEncryptFileWriter(ctx, bf, key)
+	require.NoError(t, err)
+	// write in small pieces to cover the buffering across chunks
+	for len(data) > 0 {
+		n := 1000
+		if n > len(data) {
+			n = len(data)
+		}
+		_, err = w.Write(ctx, data[:n])
+		require.NoError(t, err)
+		data = data[n:]
+	}
+	require.NoError(t, w.Close(ctx))
+	return bf.Bytes()
+}
+
+func TestEncryptFileWriter(t *testing.T) {
+	t.Parallel()
+
+	key := bytes.Repeat([]byte{7}, EncryptKeySize)
+	for _, size := range []int{0, 1, encryptChunkSize, encryptChunkSize + 1, 3 * encryptChunkSize} {
+		data := make([]byte, size)
+		for i := range data {
+			data[i] = byte(i % 251)
+		}
+		encrypted := encryptForTest(t, key, data)
+		require.True(t, bytes.HasPrefix(encrypted, encryptMagic))
+
+		r, err := NewDecryptReader(bytes.NewReader(encrypted), key)
+		require.NoError(t, err)
+		decrypted, err := io.ReadAll(r)
+		require.NoError(t, err)
+		require.Equal(t, data, decrypted)
+	}
+
+	data := bytes.Repeat([]byte("dumpling"), encryptChunkSize/4)
+	encrypted := encryptForTest(t, key, data)
+
+	// wrong key
+	r, err := NewDecryptReader(bytes.NewReader(encrypted), bytes.Repeat([]byte{8}, EncryptKeySize))
+	require.NoError(t, err)
+	_, err = io.ReadAll(r)
+	require.Error(t, err)
+
+	// modified content
+	modified := append([]byte{}, encrypted...)
+	modified[len(modified)/2] ^= 1
+	r, err = NewDecryptReader(bytes.NewReader(modified), key)
+	require.NoError(t, err)
+	_, err = io.ReadAll(r)
+	require.Error(t, err)
+
+	// truncated at the boundary of chunks
+	truncated := encrypted[:encryptHeaderSize+encryptChunkSize+encryptTagSize]
+	r, err = NewDecryptReader(bytes.NewReader(truncated), key)
+	require.NoError(t, err)
+	_, err = io.ReadAll(r)
+	require.Error(t, err)
+
+	// every file is encrypted by its own key derived from the salt in the header
+	another := encryptForTest(t, key, data)
+	require.NotEqual(t, encrypted[len(encryptMagic):encryptHeaderSize], another[len(encryptMagic):encryptHeaderSize])
+	require.NotEqual(t, encrypted[encryptHeaderSize:], another[encryptHeaderSize:])
+	swapped := append(append([]byte{}, another[:encryptHeaderSize]...), encrypted[encryptHeaderSize:]...)
+	r, err = NewDecryptReader(bytes.NewReader(swapped), key)
+	require.NoError(t, err)
+	_, err = io.ReadAll(r)
+	require.Error(t, err)
+	salt := another[len(encryptMagic) : len(encryptMagic)+encryptSaltSize]
+	require.Equal(t, deriveFileKey(key, salt), deriveFileKey(key, salt))
+	require.NotEqual(t, key, deriveFileKey(key, salt))
+
+	_, err = NewDecryptReader(bytes.NewReader([]byte("INSERT INTO `t` VALUES (1);")), key)
+	require.Error(t, err)
+	_, err = NewDecryptReader(bytes.NewReader(encrypted), key[:16])
+	require.Error(t, err)
+}
+
+func TestFileKeyProvider(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	key := bytes.Repeat([]byte{0xab}, EncryptKeySize)
+	rawPath := filepath.Join(dir, "raw.key")
+	require.NoError(t, os.WriteFile(rawPath, key, 0o600))
+	hexPath := filepath.Join(dir, "hex.key")
+	require.NoError(t, os.WriteFile(hexPath, []byte(hex.EncodeToString(key)+"\n"), 0o600))
+	badPath := filepath.Join(dir, "bad.key")
+	require.NoError(t, os.WriteFile(badPath, []byte("abcd"), 0o600))
+
+	for _, path := range []string{rawPath, hexPath} {
+		k, err := (&FileKeyProvider{Path: path}).Key(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, key, k)
+	}
+	_, err := (&FileKeyProvider{Path: badPath}).Key(context.Background())
+	require.Error(t, err)
+	_, err = (&FileKeyProvider{Path: filepath.Join(dir, "not-exist")}).Key(context.Background())
+	require.Error(t, err)
+}
+
+func TestEncryptedStorage(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	dir := t.TempDir()
+	local, err := storage.NewLocalStorage(dir)
+	require.NoError(t, err)
+	key := bytes.Repeat([]byte{1}, EncryptKeySize)
+	s, err := newEncryptedStorage(local, key)
+	require.NoError(t, err)
+
+	w, tearDown, err := buildFileWriter(tcontext.Background(), s, "test.t.000000000.sql", compressOption{compressType: CompressGzip})
+	require.NoError(t, err)
+	_, err = w.Write(ctx, []byte("INSERT INTO `t` VALUES (1);\n"))
+	require.NoError(t, err)
+	tearDown(ctx)
+
+	raw, err := os.ReadFile(filepath.Join(dir, "test.t.000000000.sql.gz"))
+	require.NoError(t, err)
+	require.True(t, bytes.HasPrefix(raw, encryptMagic))
+
+	r, err := s.Open(ctx, "test.t.000000000.sql.gz")
+	require.NoError(t, err)
+	gr, err := gzip.NewReader(r)
+	require.NoError(t, err)
+	content, err := io.ReadAll(gr)
+	require.NoError(t, err)
+	require.Equal(t, "INSERT INTO `t` VALUES (1);\n", string(content))
+	require.NoError(t, r.Close())
+
+	require.NoError(t, s.WriteFile(ctx, "metadata", []byte("Started dump at")))
+	raw, err = local.ReadFile(ctx, "metadata")
+	require.NoError(t, err)
+	require.True(t, bytes.HasPrefix(raw, encryptMagic))
+	content, err = s.ReadFile(ctx, "metadata")
+	require.NoError(t, err)
+	require.Equal(t, "Started dump at", string(content))
+
+	_, err = newEncryptedStorage(local, key[:1])
+	require.Error(t, err)
+}
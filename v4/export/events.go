@@ -0,0 +1,98 @@
+// Copyright 2021 PingCAP, Inc. Licensed under Apache-2.0.
+
+package export
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+// EventListener receives the events of a dump. The methods are called synchronously by the dumping goroutines,
+// some of them concurrently, so they should be safe for concurrent use and return quickly.
+type EventListener interface {
+	// OnTableListResolved is called after the databases and tables to dump are listed and filtered
+	OnTableListResolved(tables DatabaseTables)
+	// OnTaskDispatched is called after a task is sent to the writers, including the tails split by work stealing
+	OnTaskDispatched(task Task)
+	// OnChunkStarted is called before a writer dumps a table chunk
+	OnChunkStarted(task *TaskTableData)
+	// OnChunkFinished is called after a table chunk is dumped, the chunks finished in the checkpoint of
+	// a resumed dump are finished without starting
+	OnChunkFinished(task *TaskTableData)
+	// OnChunkRetried is called before a table chunk is dumped again after err, attempt starts from 2
+	OnChunkRetried(meta TableMeta, chunkIndex int, attempt int, err error)
+	// OnFileWritten is called after a file is closed and added to the manifest
+	OnFileWritten(file *ManifestFile)
+	// OnMetadataRecorded is called after the binlog position or the snapshot of the source server is recorded
+	OnMetadataRecorded(meta *MetadataEvent)
+	// OnDumpCompleted is called when Dumper.Dump returns, err is nil if the dump succeeds
+	OnDumpCompleted(err error)
+}
+
+// NopEventListener ignores all the events. It can be embedded to implement a part of EventListener.
+type NopEventListener struct{}
+
+// OnTableListResolved implements EventListener.OnTableListResolved
+func (NopEventListener) OnTableListResolved(DatabaseTables) {}
+
+// OnTaskDispatched implements EventListener.OnTaskDispatched
+func (NopEventListener) OnTaskDispatched(Task) {}
+
+// OnChunkStarted implements EventListener.OnChunkStarted
+func (NopEventListener) OnChunkStarted(*TaskTableData) {}
+
+// OnChunkFinished implements EventListener.OnChunkFinished
+func (NopEventListener) OnChunkFinished(*TaskTableData) {}
+
+// OnChunkRetried implements EventListener.OnChunkRetried
+func (NopEventListener) OnChunkRetried(TableMeta, int, int, error) {}
+
+// OnFileWritten implements EventListener.OnFileWritten
+func (NopEventListener) OnFileWritten(*ManifestFile) {}
+
+// OnMetadataRecorded implements EventListener.OnMetadataRecorded
+func (NopEventListener) OnMetadataRecorded(*MetadataEvent) {}
+
+// OnDumpCompleted implements EventListener.OnDumpCompleted
+func (NopEventListener) OnDumpCompleted(error) {}
+
+// MetadataEvent is the metadata of the source server recorded before dumping data. It's recorded again after
+// the connections of the writers are established if Config.PosAfterConnect is set.
+type MetadataEvent struct {
+	AfterConnection bool
+	// Snapshot is the TSO of the snapshot of TiDB
+	Snapshot     string
+	MasterStatus *BinlogStatus
+	SlaveStatus  []*BinlogStatus
+}
+
+// DumperOption configures the Dumper created by NewDumper
+type DumperOption func(conf *Config)
+
+// WithEventListener makes the Dumper send its events to listener
+func WithEventListener(listener EventListener) DumperOption {
+	return func(conf *Config) {
+		conf.EventListener = listener
+	}
+}
+
+// WithLogger makes the Dumper write logs to logger instead of the logger built from Config.LogFile
+func WithLogger(logger *zap.Logger) DumperOption {
+	return func(conf *Config) {
+		conf.Logger = logger
+	}
+}
+
+// WithLabels sets the labels of the metrics of the Dumper, the labels should match the labels of InitMetricsVector
+func WithLabels(labels prometheus.Labels) DumperOption {
+	return func(conf *Config) {
+		conf.Labels = labels
+	}
+}
+
+func adjustEventListener(conf *Config) error {
+	if conf.EventListener == nil {
+		conf.EventListener = NopEventListener{}
+	}
+	return nil
+}
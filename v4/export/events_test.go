@@ -0,0 +1,82 @@
+// Copyright 2021 PingCAP, Inc. Licensed under Apache-2.0.
+
+package export
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type recordEventListener struct {
+	NopEventListener
+	mu     sync.Mutex
+	events []string
+}
+
+func (l *recordEventListener) record(format string, args ...interface{}) {
+	l.mu.Lock()
+	l.events = append(l.events, fmt.Sprintf(format, args...))
+	l.mu.Unlock()
+}
+
+func (l *recordEventListener) OnChunkStarted(task *TaskTableData) {
+	l.record("chunk started %s.%s %d", task.Meta.DatabaseName(), task.Meta.TableName(), task.ChunkIndex)
+}
+
+func (l *recordEventListener) OnChunkFinished(task *TaskTableData) {
+	l.record("chunk finished %s.%s %d", task.Meta.DatabaseName(), task.Meta.TableName(), task.ChunkIndex)
+}
+
+func (l *recordEventListener) OnFileWritten(file *ManifestFile) {
+	l.record("file written %s %d", file.Name, file.Rows)
+}
+
+func TestEventListener(t *testing.T) {
+	t.Parallel()
+
+	listener := &recordEventListener{}
+	config := defaultConfigForTest(t)
+	config.OutputDirPath = t.TempDir()
+	WithEventListener(listener)(config)
+	writer, clean := createTestWriter(config, t)
+	defer clean()
+	writer.manifest = newManifestCollector(writer.extStorage, config.EventListener)
+
+	data := [][]driver.Value{
+		{"1", "male", "bob@mail.com", "020-1234", nil},
+		{"2", "female", "sarah@mail.com", "020-1253", "healthy"},
+	}
+	colTypes := []string{"INT", "SET", "VARCHAR", "VARCHAR", "TEXT"}
+	tableIR := newMockTableIR("test", "employee", data, nil, colTypes)
+	require.NoError(t, writer.handleTask(NewTaskTableData(tableIR, tableIR, 0, 1)))
+	require.Equal(t, []string{
+		"chunk started test.employee 0",
+		"file written test.employee.000000000.sql 2",
+		"chunk finished test.employee 0",
+	}, listener.events)
+}
+
+func TestDumperOptions(t *testing.T) {
+	t.Parallel()
+
+	conf := &Config{}
+	require.NoError(t, adjustEventListener(conf))
+	require.Equal(t, NopEventListener{}, conf.EventListener)
+
+	listener := &recordEventListener{}
+	logger := zap.NewNop()
+	labels := prometheus.Labels{"task": "test"}
+	for _, opt := range []DumperOption{WithEventListener(listener), WithLogger(logger), WithLabels(labels)} {
+		opt(conf)
+	}
+	require.NoError(t, adjustEventListener(conf))
+	require.Same(t, listener, conf.EventListener)
+	require.Same(t, logger, conf.Logger)
+	require.Equal(t, labels, conf.Labels)
+}
@@ -0,0 +1,235 @@
+// Copyright 2021 PingCAP, Inc. Licensed under Apache-2.0.
+
+package export
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"time"
+
+	tcontext "github.com/pingcap/dumpling/v4/context"
+	"github.com/pingcap/dumpling/v4/log"
+
+	"github.com/pingcap/errors"
+	"go.uber.org/zap"
+)
+
+// pauserGovernor is the pauser of the throttle when the source server is overloaded
+const pauserGovernor = "governor"
+
+// governor polls the load of the source server every conf.ThrottleCheckInterval, and pauses the writers while
+// any signal exceeds its threshold, just like the throttling of gh-ost. Every signal is polled independently.
+// A signal which fails to be polled keeps its last verdict, so an existing pause isn't released just because
+// the source server is too busy to answer, and the signals polled successfully still decide the state.
+type governor struct {
+	conf     *Config
+	pool     *sql.DB
+	throttle *throttle
+	conn     *sql.Conn
+	signals  []governorSignal
+	// reasons are the last verdicts of the signals, an empty reason means the signal doesn't exceed its threshold
+	reasons []string
+}
+
+// governorSignal polls one kind of load of the source server. poll returns the reason why the load exceeds
+// its threshold, or an empty string if it doesn't.
+type governorSignal struct {
+	name string
+	poll func(tctx *tcontext.Context, conn *sql.Conn) (string, error)
+}
+
+func needGovernor(conf *Config) bool {
+	return conf.MaxThreadsRunning > 0 || conf.MaxReplicationLag > 0 || conf.MaxTiDBMemoryUsage > 0
+}
+
+func newGovernor(conf *Config, pool *sql.DB) *governor {
+	g := &governor{conf: conf, pool: pool, throttle: conf.throttle}
+	if conf.MaxThreadsRunning > 0 {
+		g.signals = append(g.signals, governorSignal{name: "Threads_running", poll: g.pollThreadsRunning})
+	}
+	if conf.MaxReplicationLag > 0 && conf.ServerInfo.ServerType != ServerTypeTiDB {
+		g.signals = append(g.signals, governorSignal{name: "Seconds_Behind_Master", poll: g.pollReplicationLag})
+	}
+	if conf.MaxTiDBMemoryUsage > 0 && conf.ServerInfo.ServerType == ServerTypeTiDB {
+		g.signals = append(g.signals, governorSignal{name: "TiDB memory usage", poll: g.pollTiDBMemoryUsage})
+	}
+	g.reasons = make([]string, len(g.signals))
+	return g
+}
+
+func (d *Dumper) runGovernor(tctx *tcontext.Context) {
+	g := newGovernor(d.conf, d.dbHandle)
+	defer g.close()
+	ticker := time.NewTicker(g.conf.ThrottleCheckInterval)
+	defer ticker.Stop()
+	for {
+		g.check(tctx)
+		select {
+		case <-tctx.Done():
+			g.throttle.resume(pauserGovernor)
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (g *governor) check(tctx *tcontext.Context) {
+	g.poll(tctx)
+	reason := ""
+	for _, r := range g.reasons {
+		if r != "" {
+			reason = r
+			break
+		}
+	}
+	if reason != "" {
+		if g.throttle.pause(pauserGovernor) {
+			tctx.L().Info("pause dumping because source server is overloaded", zap.String("reason", reason))
+		}
+		return
+	}
+	if g.throttle.resume(pauserGovernor) {
+		tctx.L().Info("resume dumping because the load of source server is back to normal")
+	}
+}
+
+// poll updates the verdicts of the signals. The verdicts of the signals which fail to be polled are unchanged.
+func (g *governor) poll(tctx *tcontext.Context) {
+	if g.conn == nil {
+		conn, err := g.pool.Conn(tctx)
+		if err != nil {
+			tctx.L().Warn("fail to connect to source server to check its load", log.ShortError(err))
+			return
+		}
+		g.conn = conn
+	}
+	failed := false
+	for i, signal := range g.signals {
+		reason, err := signal.poll(tctx, g.conn)
+		if err != nil {
+			tctx.L().Warn("fail to check the load of source server", zap.String("signal", signal.name), log.ShortError(err))
+			failed = true
+			continue
+		}
+		g.reasons[i] = reason
+	}
+	if failed {
+		// the connection may be broken, it's created again in the next check
+		g.close()
+	}
+}
+
+func (g *governor) pollThreadsRunning(tctx *tcontext.Context, conn *sql.Conn) (string, error) {
+	threadsRunning, err := showThreadsRunning(tctx, conn)
+	if err != nil || threadsRunning <= g.conf.MaxThreadsRunning {
+		return "", err
+	}
+	return fmt.Sprintf("Threads_running %d > %d", threadsRunning, g.conf.MaxThreadsRunning), nil
+}
+
+// pollReplicationLag treats NULL Seconds_Behind_Master as exceeding the threshold like gh-ost, because the lag of
+// a stopped replication is unknown and keeps growing.
+func (g *governor) pollReplicationLag(_ *tcontext.Context, conn *sql.Conn) (string, error) {
+	lag, stopped, err := replicationLag(conn)
+	if err != nil {
+		return "", err
+	}
+	if stopped {
+		return "Seconds_Behind_Master is NULL", nil
+	}
+	if lag > g.conf.MaxReplicationLag {
+		return fmt.Sprintf("Seconds_Behind_Master %s > %s", lag, g.conf.MaxReplicationLag), nil
+	}
+	return "", nil
+}
+
+func (g *governor) pollTiDBMemoryUsage(tctx *tcontext.Context, conn *sql.Conn) (string, error) {
+	instance, usage, err := maxTiDBMemoryUsage(tctx, conn)
+	if err != nil || usage <= g.conf.MaxTiDBMemoryUsage {
+		return "", err
+	}
+	return fmt.Sprintf("memory usage of TiDB %s %.2f > %.2f", instance, usage, g.conf.MaxTiDBMemoryUsage), nil
+}
+
+func (g *governor) close() {
+	if g.conn != nil {
+		g.conn.Close()
+		g.conn = nil
+	}
+}
+
+// showThreadsRunning returns the number of threads which are not sleeping, it returns 0 if the server doesn't support
+func showThreadsRunning(tctx *tcontext.Context, conn *sql.Conn) (int, error) {
+	// mysql> SHOW GLOBAL STATUS LIKE 'Threads_running';
+	// +-----------------+-------+
+	// | Variable_name   | Value |
+	// +-----------------+-------+
+	// | Threads_running | 2     |
+	// +-----------------+-------+
+	const query = "SHOW GLOBAL STATUS LIKE 'Threads_running'"
+	var name, value string
+	err := conn.QueryRowContext(tctx, query).Scan(&name, &value)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, errors.Annotatef(err, "sql: %s", query)
+	}
+	n, err := strconv.Atoi(value)
+	return n, errors.Annotatef(err, "sql: %s", query)
+}
+
+// replicationLag returns the max Seconds_Behind_Master of all the replication channels. stopped is true if
+// Seconds_Behind_Master of any channel is NULL, which means its SQL thread or IO thread isn't running.
+func replicationLag(conn *sql.Conn) (lag time.Duration, stopped bool, err error) {
+	var errLag error
+	err = showSlaveStatus(conn, func(status map[string]sql.NullString) {
+		seconds := status["seconds_behind_master"]
+		if !seconds.Valid {
+			stopped = true
+			return
+		}
+		n, err := strconv.ParseInt(seconds.String, 10, 64)
+		if err != nil {
+			errLag = errors.Annotatef(err, "invalid Seconds_Behind_Master %s", seconds.String)
+			return
+		}
+		if d := time.Duration(n) * time.Second; d > lag {
+			lag = d
+		}
+	})
+	if err != nil {
+		return 0, false, err
+	}
+	return lag, stopped, errLag
+}
+
+// maxTiDBMemoryUsage returns the max fraction of used memory on the hosts of TiDB servers
+func maxTiDBMemoryUsage(tctx *tcontext.Context, conn *sql.Conn) (instance string, usage float64, err error) {
+	// mysql> SELECT INSTANCE, VALUE FROM INFORMATION_SCHEMA.CLUSTER_LOAD WHERE TYPE = 'tidb' AND DEVICE_TYPE = 'memory' AND DEVICE_NAME = 'virtual' AND NAME = 'used-percent';
+	// +-----------------+-------+
+	// | INSTANCE        | VALUE |
+	// +-----------------+-------+
+	// | 127.0.0.1:4000  | 0.52  |
+	// +-----------------+-------+
+	const query = "SELECT INSTANCE, VALUE FROM INFORMATION_SCHEMA.CLUSTER_LOAD WHERE TYPE = 'tidb' AND DEVICE_TYPE = 'memory' AND DEVICE_NAME = 'virtual' AND NAME = 'used-percent'"
+	rows, err := conn.QueryContext(tctx, query)
+	if err != nil {
+		return "", 0, errors.Annotatef(err, "sql: %s", query)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			inst  string
+			value float64
+		)
+		if err = rows.Scan(&inst, &value); err != nil {
+			return "", 0, errors.Annotatef(err, "sql: %s", query)
+		}
+		if value > usage {
+			instance, usage = inst, value
+		}
+	}
+	return instance, usage, errors.Annotatef(rows.Err(), "sql: %s", query)
+}
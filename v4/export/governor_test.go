@@ -0,0 +1,118 @@
+// Copyright 2021 PingCAP, Inc. Licensed under Apache-2.0.
+
+package export
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	tcontext "github.com/pingcap/dumpling/v4/context"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGovernor(t *testing.T) {
+	t.Parallel()
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, db.Close())
+	}()
+
+	conf := defaultConfigForTest(t)
+	conf.ServerInfo = ServerInfo{ServerType: ServerTypeMySQL}
+	conf.MaxThreadsRunning = 20
+	conf.MaxReplicationLag = time.Minute
+	require.True(t, needGovernor(conf))
+	require.NoError(t, adjustThrottle(conf))
+	tctx := tcontext.Background().WithLogger(appLogger)
+	g := newGovernor(conf, db)
+	defer g.close()
+
+	threadsRunning := func(n string) {
+		mock.ExpectQuery("SHOW GLOBAL STATUS LIKE 'Threads_running'").
+			WillReturnRows(sqlmock.NewRows([]string{"Variable_name", "Value"}).AddRow("Threads_running", n))
+	}
+	slaveStatus := func(lags ...interface{}) {
+		mock.ExpectQuery("SELECT @@default_master_connection").WillReturnError(errors.New("mock error"))
+		rows := sqlmock.NewRows([]string{"Seconds_Behind_Master"})
+		for _, lag := range lags {
+			rows.AddRow(lag)
+		}
+		mock.ExpectQuery("SHOW SLAVE STATUS").WillReturnRows(rows)
+	}
+
+	// too many running threads
+	threadsRunning("25")
+	slaveStatus("10")
+	g.check(tctx)
+	require.Equal(t, int32(1), conf.throttle.paused)
+
+	// a signal which fails to be polled keeps its last verdict, the connection is created again
+	mock.ExpectQuery("SHOW GLOBAL STATUS LIKE 'Threads_running'").WillReturnError(errors.New("mock error"))
+	slaveStatus("10")
+	g.check(tctx)
+	require.Equal(t, int32(1), conf.throttle.paused)
+	require.Nil(t, g.conn)
+
+	// the other signals are still polled when one fails
+	threadsRunning("5")
+	slaveStatus("10")
+	g.check(tctx)
+	require.Equal(t, int32(0), conf.throttle.paused)
+	mock.ExpectQuery("SHOW GLOBAL STATUS LIKE 'Threads_running'").WillReturnError(errors.New("mock error"))
+	slaveStatus("10", "90")
+	g.check(tctx)
+	require.Equal(t, int32(1), conf.throttle.paused)
+
+	// NULL Seconds_Behind_Master means the replication is stopped, which exceeds the threshold
+	threadsRunning("5")
+	slaveStatus(nil, "10")
+	g.check(tctx)
+	require.Equal(t, int32(1), conf.throttle.paused)
+
+	threadsRunning("5")
+	slaveStatus("10")
+	g.check(tctx)
+	require.Equal(t, int32(0), conf.throttle.paused)
+	require.NoError(t, conf.throttle.waitRows(context.Background(), 1))
+	require.NoError(t, mock.ExpectationsWereMet())
+	mock.ExpectClose()
+}
+
+func TestGovernorTiDBMemoryUsage(t *testing.T) {
+	t.Parallel()
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, db.Close())
+	}()
+
+	conf := defaultConfigForTest(t)
+	conf.ServerInfo = ServerInfo{ServerType: ServerTypeTiDB}
+	conf.MaxReplicationLag = time.Minute
+	conf.MaxTiDBMemoryUsage = 0.8
+	require.NoError(t, adjustThrottle(conf))
+	tctx := tcontext.Background().WithLogger(appLogger)
+	g := newGovernor(conf, db)
+	defer g.close()
+
+	// replication lag isn't checked on TiDB
+	mock.ExpectQuery("SELECT INSTANCE, VALUE FROM INFORMATION_SCHEMA.CLUSTER_LOAD").
+		WillReturnRows(sqlmock.NewRows([]string{"INSTANCE", "VALUE"}).
+			AddRow("127.0.0.1:4000", 0.5).
+			AddRow("127.0.0.1:4001", 0.9))
+	g.check(tctx)
+	require.Equal(t, int32(1), conf.throttle.paused)
+
+	mock.ExpectQuery("SELECT INSTANCE, VALUE FROM INFORMATION_SCHEMA.CLUSTER_LOAD").
+		WillReturnRows(sqlmock.NewRows([]string{"INSTANCE", "VALUE"}).AddRow("127.0.0.1:4001", 0.6))
+	g.check(tctx)
+	require.Equal(t, int32(0), conf.throttle.paused)
+	require.NoError(t, mock.ExpectationsWereMet())
+	mock.ExpectClose()
+}
@@ -3,6 +3,8 @@
 package export
 
 import (
+	"crypto/subtle"
+	"encoding/json"
 	"net"
 	"net/http"
 	"net/http/pprof"
@@ -20,9 +22,10 @@ import (
 
 var cmuxReadTimeout = 10 * time.Second
 
-func startHTTPServer(tctx *tcontext.Context, lis net.Listener) {
+func startHTTPServer(tctx *tcontext.Context, lis net.Listener, registerAPI func(router *http.ServeMux)) {
 	router := http.NewServeMux()
 	router.Handle("/metrics", promhttp.Handler())
+	registerAPI(router)
 
 	router.HandleFunc("/debug/pprof/", pprof.Index)
 	router.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
@@ -40,7 +43,7 @@ func startHTTPServer(tctx *tcontext.Context, lis net.Listener) {
 	}
 }
 
-func startDumplingService(tctx *tcontext.Context, addr string) error {
+func startDumplingService(tctx *tcontext.Context, addr string, registerAPI func(router *http.ServeMux)) error {
 	rootLis, err := net.Listen("tcp", addr)
 	if err != nil {
 		return errors.Annotate(err, "start listening")
@@ -51,7 +54,7 @@ func startDumplingService(tctx *tcontext.Context, addr string) error {
 	m.SetReadTimeout(cmuxReadTimeout) // set a timeout, ref: https://github.com/pingcap/tidb-binlog/pull/352
 
 	httpL := m.Match(cmux.HTTP1Fast())
-	go startHTTPServer(tctx, httpL)
+	go startHTTPServer(tctx, httpL, registerAPI)
 
 	err = m.Serve() // start serving, block
 	if err != nil && isErrNetClosing(err) {
@@ -60,6 +63,105 @@ func startDumplingService(tctx *tcontext.Context, addr string) error {
 	return err
 }
 
+// registerControlAPI registers the API to watch and control the dump of d. The API changing the dump
+// is only served to the requests with the status token, and is disabled if the token isn't set.
+func registerControlAPI(router *http.ServeMux, d *Dumper) {
+	for action, handler := range controlAPIHandlers(d) {
+		if action != "status" {
+			handler = requireToken(d.conf.StatusToken, handler)
+		}
+		router.HandleFunc("/api/v1/"+action, handler)
+	}
+}
+
+// requireToken wraps handler to reject the requests without the header "Authorization: Bearer <token>",
+// all the requests are rejected if token is empty
+func requireToken(token string, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if token == "" {
+			writeAPIError(w, http.StatusForbidden, errors.New("the API is disabled, set --status-token to enable it"))
+			return
+		}
+		got := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			writeAPIError(w, http.StatusUnauthorized, errors.New("invalid or missing token"))
+			return
+		}
+		handler(w, r)
+	}
+}
+
+// controlAPIHandlers returns the handlers of the API to watch and control the dump of d, indexed by the actions
+func controlAPIHandlers(d *Dumper) map[string]http.HandlerFunc {
+	handlers := map[string]http.HandlerFunc{
+		"status": func(w http.ResponseWriter, r *http.Request) {
+			if !checkMethod(w, r, http.MethodGet) {
+				return
+			}
+			writeJSON(w, http.StatusOK, d.Status())
+		},
+		"threads": func(w http.ResponseWriter, r *http.Request) {
+			if !checkMethod(w, r, http.MethodPost) {
+				return
+			}
+			var req struct {
+				Threads int `json:"threads"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				writeAPIError(w, http.StatusBadRequest, errors.Annotate(err, "invalid request body"))
+				return
+			}
+			if req.Threads <= 0 {
+				writeAPIError(w, http.StatusBadRequest, errors.Errorf("invalid threads %d, it should be positive", req.Threads))
+				return
+			}
+			if err := d.SetThreads(req.Threads); err != nil {
+				writeAPIError(w, http.StatusConflict, err)
+				return
+			}
+			writeJSON(w, http.StatusOK, d.Status())
+		},
+	}
+	for action, fn := range map[string]func() error{
+		"pause":  d.Pause,
+		"resume": d.Resume,
+		"cancel": d.Cancel,
+	} {
+		fn := fn
+		handlers[action] = func(w http.ResponseWriter, r *http.Request) {
+			if !checkMethod(w, r, http.MethodPost) {
+				return
+			}
+			if err := fn(); err != nil {
+				writeAPIError(w, http.StatusConflict, err)
+				return
+			}
+			writeJSON(w, http.StatusOK, d.Status())
+		}
+	}
+	return handlers
+}
+
+// checkMethod writes an error and returns false if the method of r isn't method
+func checkMethod(w http.ResponseWriter, r *http.Request, method string) bool {
+	if r.Method != method {
+		writeAPIError(w, http.StatusMethodNotAllowed, errors.Errorf("method %s is not allowed", r.Method))
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	// the error can't be sent to the client after the header is written
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeAPIError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, map[string]string{"error": err.Error()})
+}
+
 var useOfClosedErrMsg = "use of closed network connection"
 
 // isErrNetClosing checks whether is an ErrNetClosing error
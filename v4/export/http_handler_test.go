@@ -0,0 +1,117 @@
+// Copyright 2021 PingCAP, Inc. Licensed under Apache-2.0.
+
+package export
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	tcontext "github.com/pingcap/dumpling/v4/context"
+	"github.com/stretchr/testify/require"
+)
+
+func TestControlAPI(t *testing.T) {
+	t.Parallel()
+
+	conf := defaultConfigForTest(t)
+	conf.StatusToken = "secret"
+	require.NoError(t, adjustThrottle(conf))
+	tctx, cancel := tcontext.Background().WithLogger(appLogger).WithCancel()
+	defer cancel()
+	d := &Dumper{tctx: tctx, conf: conf, cancelCtx: cancel, progress: newProgressTracker()}
+	router := http.NewServeMux()
+	registerControlAPI(router, d)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	token := conf.StatusToken
+	request := func(method, path string) (int, *DumpStatus) {
+		req, err := http.NewRequest(method, server.URL+path, nil)
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		status := &DumpStatus{}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(status))
+		return resp.StatusCode, status
+	}
+
+	d.progress.start()
+	meta := newMockTableIR(database, table, nil, nil, nil)
+	task := NewTaskTableData(meta, nil, 0, 2)
+	d.progress.addChunk(task)
+	d.progress.startTask(1, task)
+	code, status := request(http.MethodGet, "/api/v1/status")
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, DumpStateRunning, status.State)
+	require.Equal(t, []TableProgress{{Database: database, Table: table, ChunksTotal: 2}}, status.Tables)
+	require.Len(t, status.Writers, 1)
+	require.Equal(t, task.Brief(), status.Writers[0].Task)
+
+	task.Files = []*ManifestFile{{Rows: 10, Size: 100}}
+	d.progress.finishTask(1, task)
+	_, status = request(http.MethodGet, "/api/v1/status")
+	require.Equal(t, []TableProgress{{Database: database, Table: table, ChunksDone: 1, ChunksTotal: 2, Rows: 10, Bytes: 100}}, status.Tables)
+	require.Equal(t, []WriterProgress{{ID: 1}}, status.Writers)
+
+	token = "wrong"
+	code, _ = request(http.MethodPost, "/api/v1/pause")
+	require.Equal(t, http.StatusUnauthorized, code)
+	code, _ = request(http.MethodGet, "/api/v1/status")
+	require.Equal(t, http.StatusOK, code)
+	token = conf.StatusToken
+
+	code, _ = request(http.MethodGet, "/api/v1/pause")
+	require.Equal(t, http.StatusMethodNotAllowed, code)
+	code, status = request(http.MethodPost, "/api/v1/pause")
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, DumpStatePaused, status.State)
+	require.Equal(t, int32(1), conf.throttle.paused)
+
+	code, status = request(http.MethodPost, "/api/v1/resume")
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, DumpStateRunning, status.State)
+	require.NoError(t, conf.throttle.waitRows(context.Background(), 1))
+
+	code, status = request(http.MethodPost, "/api/v1/cancel")
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, DumpStateCanceled, status.State)
+	require.Error(t, tctx.Err())
+	d.progress.finish(tctx.Err())
+	code, _ = request(http.MethodPost, "/api/v1/resume")
+	require.Equal(t, http.StatusConflict, code)
+	_, status = request(http.MethodGet, "/api/v1/status")
+	require.Equal(t, DumpStateCanceled, status.State)
+}
+
+func TestControlAPIWithoutToken(t *testing.T) {
+	t.Parallel()
+
+	conf := defaultConfigForTest(t)
+	require.NoError(t, adjustThrottle(conf))
+	tctx, cancel := tcontext.Background().WithLogger(appLogger).WithCancel()
+	defer cancel()
+	d := &Dumper{tctx: tctx, conf: conf, cancelCtx: cancel, progress: newProgressTracker()}
+	router := http.NewServeMux()
+	registerControlAPI(router, d)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	for _, action := range []string{"pause", "resume", "cancel", "threads"} {
+		resp, err := http.Post(server.URL+"/api/v1/"+action, "application/json", nil)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+		require.Equal(t, http.StatusForbidden, resp.StatusCode, action)
+	}
+	require.Equal(t, int32(0), conf.throttle.paused)
+	require.NoError(t, tctx.Err())
+
+	resp, err := http.Get(server.URL + "/api/v1/status")
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+}
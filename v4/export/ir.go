@@ -59,6 +59,12 @@ type Stringer interface {
 	WriteToBufferInCsv(*bytes.Buffer, bool, *csvOption)
 }
 
+// JSONStringer is an optional interface of Stringer which writes the value as a typed JSON value in jsonl type.
+// The values of the Stringers not implementing it are written as JSON strings of their csv values.
+type JSONStringer interface {
+	WriteToBufferInJSON(*bytes.Buffer)
+}
+
 // RowReceiver is an interface which represents sql types that support bind address for *sql.Rows
 type RowReceiver interface {
 	BindAddress([]interface{})
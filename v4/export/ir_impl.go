@@ -4,6 +4,7 @@ package export
 
 import (
 	"database/sql"
+	"reflect"
 	"strings"
 
 	"github.com/pingcap/errors"
@@ -259,16 +260,30 @@ type tableMeta struct {
 	showCreateView   string
 	avgRowLength     uint64
 	hasImplicitRowID bool
+	unsignedBigints  map[string]struct{}
 }
 
+// ColumnTypes returns the database type names of the columns. go-sql-driver reports BIGINT UNSIGNED as BIGINT,
+// so they are renamed to UNSIGNED BIGINT like the other drivers if they are known to be unsigned.
 func (tm *tableMeta) ColumnTypes() []string {
 	colTypes := make([]string, len(tm.colTypes))
 	for i, ct := range tm.colTypes {
 		colTypes[i] = ct.DatabaseTypeName()
+		if colTypes[i] == "BIGINT" && tm.isUnsignedBigint(ct) {
+			colTypes[i] = "UNSIGNED BIGINT"
+		}
 	}
 	return colTypes
 }
 
+func (tm *tableMeta) isUnsignedBigint(ct *sql.ColumnType) bool {
+	if _, ok := tm.unsignedBigints[ct.Name()]; ok {
+		return true
+	}
+	// go-sql-driver only tells the unsigned columns apart by the scan type if they are NOT NULL
+	return ct.ScanType() == reflect.TypeOf(uint64(0))
+}
+
 func (tm *tableMeta) ColumnNames() []string {
 	colNames := make([]string, len(tm.colTypes))
 	for i, ct := range tm.colTypes {
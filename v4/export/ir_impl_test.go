@@ -102,7 +102,7 @@ func TestChunkRowIter(t *testing.T) {
 	sqlRowIter := newRowIter(rows, 2)
 
 	res := newSimpleRowReceiver(2)
-	wp := newWriterPipe(nil, testFileSize, testStatementSize, nil)
+	wp := newWriterPipe(nil, testFileSize, testStatementSize, nil, nil)
 
 	var resSize [][]uint64
 	for sqlRowIter.HasNext() {
@@ -49,6 +49,8 @@ func TestMain(m *testing.M) {
 
 	opts := []goleak.Option{
 		goleak.IgnoreTopFunction("go.opencensus.io/stats/view.(*worker).start"),
+		// parquet-go initializes a global zstd decoder which starts background goroutines
+		goleak.IgnoreTopFunction("github.com/klauspost/compress/zstd.(*blockDec).startDecoder"),
 	}
 
 	goleak.VerifyTestMain(m, opts...)
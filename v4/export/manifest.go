@@ -0,0 +1,256 @@
+// Copyright 2021 PingCAP, Inc. Licensed under Apache-2.0.
+
+package export
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	tcontext "github.com/pingcap/dumpling/v4/context"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tidb/br/pkg/storage"
+	"go.uber.org/zap"
+)
+
+const manifestFileName = "manifest.json"
+
+// The types of files listed in the manifest
+const (
+	ManifestFileTypeData     = "data"
+	ManifestFileTypeSchema   = "schema"
+	ManifestFileTypeMetadata = "metadata"
+	ManifestFileTypeUsers    = "users"
+)
+
+// ManifestFile describes a file written by dumpling. Size and SHA256 are calculated on the bytes
+// stored in the external storage, which are compressed and encrypted if configured.
+type ManifestFile struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Size     int64  `json:"size"`
+	SHA256   string `json:"sha256"`
+	Database string `json:"database,omitempty"`
+	Table    string `json:"table,omitempty"`
+	// Object is the kind of the object created by a schema file, like "table" or "placement-policy",
+	// it's the same as the name of the template of the file
+	Object string `json:"object,omitempty"`
+	// ChunkIndex and Rows are only meaningful for data files
+	ChunkIndex int    `json:"chunk-index"`
+	Rows       uint64 `json:"rows"`
+}
+
+// Manifest is the content of manifest.json, which lists all the files of a dump
+type Manifest struct {
+	Files []*ManifestFile `json:"files"`
+}
+
+type fileChecksum struct {
+	size   int64
+	sha256 string
+}
+
+// manifestCollector collects the checksums of all the files written to the external storage
+// and the files written by writers, and writes manifest.json after the dump succeeds.
+// All the methods are safe to be called on a nil manifestCollector.
+type manifestCollector struct {
+	mu sync.Mutex
+	// extStore is the storage manifest.json is written to, it never encrypts manifest.json
+	// so that the dump can be verified without the encrypt key
+	extStore  storage.ExternalStorage
+	checksums map[string]fileChecksum
+	files     map[string]*ManifestFile
+	listener  EventListener
+}
+
+func newManifestCollector(extStore storage.ExternalStorage, listener EventListener) *manifestCollector {
+	return &manifestCollector{
+		extStore:  extStore,
+		listener:  listener,
+		checksums: make(map[string]fileChecksum),
+		files:     make(map[string]*ManifestFile),
+	}
+}
+
+func (m *manifestCollector) recordChecksum(name string, size int64, sum []byte) {
+	m.mu.Lock()
+	m.checksums[name] = fileChecksum{size: size, sha256: hex.EncodeToString(sum)}
+	m.mu.Unlock()
+}
+
+// addFile fills the size and checksum of a closed file and adds it to the manifest. The recorded
+// size and checksum are kept if the file isn't written in this run, e.g. it's skipped by checkpoint.
+func (m *manifestCollector) addFile(f *ManifestFile) *ManifestFile {
+	if m == nil {
+		return f
+	}
+	m.mu.Lock()
+	if c, ok := m.checksums[f.Name]; ok {
+		f.Size, f.SHA256 = c.size, c.sha256
+	}
+	m.files[f.Name] = f
+	m.mu.Unlock()
+	m.listener.OnFileWritten(f)
+	return f
+}
+
+// removeFile removes a file which is deleted from the external storage
+func (m *manifestCollector) removeFile(name string) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	delete(m.files, name)
+	delete(m.checksums, name)
+	m.mu.Unlock()
+}
+
+// manifestFiles returns all the added files sorted by name
+func (m *manifestCollector) manifestFiles() []*ManifestFile {
+	if m == nil {
+		return nil
+	}
+	m.mu.Lock()
+	files := make([]*ManifestFile, 0, len(m.files))
+	for _, f := range m.files {
+		files = append(files, f)
+	}
+	m.mu.Unlock()
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Name < files[j].Name
+	})
+	return files
+}
+
+// write writes manifest.json
+func (m *manifestCollector) write(tctx *tcontext.Context) error {
+	if m == nil {
+		return nil
+	}
+	manifest := Manifest{Files: m.manifestFiles()}
+	content, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if err = m.extStore.WriteFile(tctx, manifestFileName, content); err != nil {
+		return errors.Annotatef(err, "fail to write %s", manifestFileName)
+	}
+	tctx.L().Info("manifest is written", zap.String("file", manifestFileName), zap.Int("files", len(manifest.Files)))
+	return nil
+}
+
+// checksumStorage is a storage.ExternalStorage which calculates the size and checksum of all the files it writes
+type checksumStorage struct {
+	storage.ExternalStorage
+	manifest *manifestCollector
+}
+
+// Create implements storage.ExternalStorage.Create
+func (s *checksumStorage) Create(ctx context.Context, path string) (storage.ExternalFileWriter, error) {
+	w, err := s.ExternalStorage.Create(ctx, path)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return &checksumFileWriter{ExternalFileWriter: w, name: path, hash: sha256.New(), manifest: s.manifest}, nil
+}
+
+// WriteFile implements storage.ExternalStorage.WriteFile
+func (s *checksumStorage) WriteFile(ctx context.Context, name string, data []byte) error {
+	if err := s.ExternalStorage.WriteFile(ctx, name, data); err != nil {
+		return errors.Trace(err)
+	}
+	sum := sha256.Sum256(data)
+	s.manifest.recordChecksum(name, int64(len(data)), sum[:])
+	return nil
+}
+
+type checksumFileWriter struct {
+	storage.ExternalFileWriter
+	name     string
+	hash     hash.Hash
+	size     int64
+	manifest *manifestCollector
+}
+
+// Write implements storage.ExternalFileWriter.Write
+func (w *checksumFileWriter) Write(ctx context.Context, p []byte) (int, error) {
+	n, err := w.ExternalFileWriter.Write(ctx, p)
+	_, _ = w.hash.Write(p[:n])
+	w.size += int64(n)
+	return n, err
+}
+
+// Close implements storage.ExternalFileWriter.Close
+func (w *checksumFileWriter) Close(ctx context.Context) error {
+	if err := w.ExternalFileWriter.Close(ctx); err != nil {
+		return err
+	}
+	w.manifest.recordChecksum(w.name, w.size, w.hash.Sum(nil))
+	return nil
+}
+
+// VerifyManifest re-reads all the files listed in the manifest of the dump in conf.OutputDirPath,
+// and checks their sizes and checksums.
+func VerifyManifest(ctx context.Context, conf *Config) error {
+	extStore, err := conf.createExternalStorage(ctx)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return verifyManifest(tcontext.Background().WithContext(ctx), extStore)
+}
+
+func verifyManifest(tctx *tcontext.Context, extStore storage.ExternalStorage) error {
+	content, err := extStore.ReadFile(tctx, manifestFileName)
+	if err != nil {
+		return errors.Annotatef(err, "fail to read %s", manifestFileName)
+	}
+	var manifest Manifest
+	if err = json.Unmarshal(content, &manifest); err != nil {
+		return errors.Annotatef(err, "fail to parse %s", manifestFileName)
+	}
+
+	var problems []string
+	for _, f := range manifest.Files {
+		size, sum, err := checksumFile(tctx, extStore, f.Name)
+		var problem string
+		switch {
+		case err != nil:
+			problem = fmt.Sprintf("%s: fail to read: %s", f.Name, err)
+		case size != f.Size:
+			problem = fmt.Sprintf("%s: size is %d, expected %d", f.Name, size, f.Size)
+		case sum != f.SHA256:
+			problem = fmt.Sprintf("%s: sha256 is %s, expected %s", f.Name, sum, f.SHA256)
+		default:
+			continue
+		}
+		tctx.L().Warn("file doesn't match the manifest", zap.String("problem", problem))
+		problems = append(problems, problem)
+	}
+	if len(problems) > 0 {
+		return errors.Errorf("%d of %d files don't match the manifest:\n%s", len(problems), len(manifest.Files), strings.Join(problems, "\n"))
+	}
+	tctx.L().Info("all the files match the manifest", zap.Int("files", len(manifest.Files)))
+	return nil
+}
+
+func checksumFile(ctx context.Context, extStore storage.ExternalStorage, name string) (int64, string, error) {
+	r, err := extStore.Open(ctx, name)
+	if err != nil {
+		return 0, "", errors.Trace(err)
+	}
+	defer r.Close()
+	h := sha256.New()
+	size, err := io.Copy(h, r)
+	if err != nil {
+		return 0, "", errors.Trace(err)
+	}
+	return size, hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,87 @@
+// Copyright 2021 PingCAP, Inc. Licensed under Apache-2.0.
+
+package export
+
+import (
+	"crypto/sha256"
+	"database/sql/driver"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path"
+	"testing"
+
+	tcontext "github.com/pingcap/dumpling/v4/context"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestManifest(t *testing.T) {
+	t.Parallel()
+
+	tctx := tcontext.Background()
+	dir := t.TempDir()
+	config := defaultConfigForTest(t)
+	config.OutputDirPath = dir
+
+	writer, clean := createTestWriter(config, t)
+	defer clean()
+	m := newManifestCollector(writer.extStorage, NopEventListener{})
+	rawStore := writer.extStorage
+	writer.extStorage = &checksumStorage{ExternalStorage: rawStore, manifest: m}
+	writer.manifest = m
+
+	require.NoError(t, writer.WriteTableMeta("test", "t", "CREATE TABLE t (a INT)"))
+	data := [][]driver.Value{{"1"}, {"2"}, {"3"}}
+	tableIR := newMockTableIR("test", "t", data, nil, []string{"INT"})
+	files, _, err := writer.writeTableData(tableIR, tableIR, 0)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	require.NoError(t, m.write(tctx))
+
+	content, err := os.ReadFile(path.Join(dir, manifestFileName))
+	require.NoError(t, err)
+	var manifest Manifest
+	require.NoError(t, json.Unmarshal(content, &manifest))
+	require.Len(t, manifest.Files, 2)
+	for _, f := range manifest.Files {
+		fileContent, err := os.ReadFile(path.Join(dir, f.Name))
+		require.NoError(t, err)
+		sum := sha256.Sum256(fileContent)
+		require.Equal(t, int64(len(fileContent)), f.Size)
+		require.Equal(t, hex.EncodeToString(sum[:]), f.SHA256)
+		require.Equal(t, "test", f.Database)
+		require.Equal(t, "t", f.Table)
+	}
+	require.Equal(t, "test.t-schema.sql", manifest.Files[0].Name)
+	require.Equal(t, ManifestFileTypeSchema, manifest.Files[0].Type)
+	require.Equal(t, outputFileTemplateTable, manifest.Files[0].Object)
+	require.Equal(t, "test.t.000000000.sql", manifest.Files[1].Name)
+	require.Equal(t, ManifestFileTypeData, manifest.Files[1].Type)
+	require.Equal(t, uint64(3), manifest.Files[1].Rows)
+
+	require.NoError(t, verifyManifest(tctx, rawStore))
+
+	// corrupted file
+	dataFile := path.Join(dir, "test.t.000000000.sql")
+	dataContent, err := os.ReadFile(dataFile)
+	require.NoError(t, err)
+	corrupted := append([]byte{}, dataContent...)
+	corrupted[len(corrupted)-2] ^= 1
+	require.NoError(t, os.WriteFile(dataFile, corrupted, 0o644))
+	err = verifyManifest(tctx, rawStore)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "test.t.000000000.sql: sha256 is")
+
+	// truncated file
+	require.NoError(t, os.WriteFile(dataFile, dataContent[:len(dataContent)-1], 0o644))
+	err = verifyManifest(tctx, rawStore)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "test.t.000000000.sql: size is")
+
+	// missing file
+	require.NoError(t, os.Remove(dataFile))
+	err = verifyManifest(tctx, rawStore)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "test.t.000000000.sql: fail to read")
+}
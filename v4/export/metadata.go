@@ -6,7 +6,9 @@ import (
 	"bytes"
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"sort"
 	"strings"
 	"time"
 
@@ -22,12 +24,71 @@ type globalMetadata struct {
 	buffer          bytes.Buffer
 	afterConnBuffer bytes.Buffer
 	snapshot        string
+	// json is the structured metadata written to metadata.json
+	json      metadataJSON
+	checksums []*tableChecksum
 
-	storage storage.ExternalStorage
+	storage  storage.ExternalStorage
+	listener EventListener
+}
+
+// metadataJSON is the content of metadata.json, which contains the same information as the text metadata file
+type metadataJSON struct {
+	StartTime     string `json:"start-time,omitempty"`
+	FinishTime    string `json:"finish-time,omitempty"`
+	ServerType    string `json:"server-type"`
+	ServerVersion string `json:"server-version,omitempty"`
+	Snapshot      string `json:"snapshot,omitempty"`
+
+	MasterStatus *BinlogStatus   `json:"master-status,omitempty"`
+	SlaveStatus  []*BinlogStatus `json:"slave-status,omitempty"`
+	// MasterStatusAfterConnection is the master status after the connection pool is established, see Config.PosAfterConnect
+	MasterStatusAfterConnection *BinlogStatus `json:"master-status-after-connection,omitempty"`
+
+	Tables []*tableMetadata `json:"tables"`
+	// RestoreOrder lists the schema files and users.sql in the order to restore them, see restoreRanks
+	RestoreOrder []string `json:"restore-order"`
+}
+
+// BinlogStatus is the binlog position in `SHOW MASTER STATUS` or `SHOW SLAVE STATUS`
+type BinlogStatus struct {
+	ConnectionName string `json:"connection-name,omitempty"`
+	Host           string `json:"host,omitempty"`
+	Log            string `json:"log"`
+	Pos            string `json:"pos"`
+	GTID           string `json:"gtid"`
+}
+
+// tableMetadata is the row count and the data files of a dumped table
+type tableMetadata struct {
+	Database string         `json:"database"`
+	Table    string         `json:"table"`
+	Rows     uint64         `json:"rows"`
+	Files    []string       `json:"files"`
+	Checksum *tableChecksum `json:"checksum,omitempty"`
+}
+
+// restoreRanks are the ranks of the objects created by the schema files in the restore order. The cluster-wide
+// objects are created before the databases, the sequences before the tables using them, the routines before the
+// views calling them, and the triggers and events after the tables and routines, the data files should be loaded
+// after the tables and before the triggers. The users are created at last to grant the privileges on the objects.
+var restoreRanks = map[string]int{
+	outputFileTemplateResGroup:  0,
+	outputFileTemplatePolicy:    1,
+	outputFileTemplateSchema:    2,
+	outputFileTemplateSequence:  3,
+	outputFileTemplateTable:     4,
+	outputFileTemplateFunction:  5,
+	outputFileTemplateProcedure: 5,
+	outputFileTemplateView:      6,
+	outputFileTemplateTrigger:   7,
+	outputFileTemplateEvent:     8,
+	ManifestFileTypeUsers:       9,
 }
 
 const (
 	metadataPath       = "metadata"
+	metadataJSONPath   = "metadata.json"
 	metadataTimeLayout = "2006-01-02 15:04:05"
 
 	fileFieldIndex    = 0
@@ -50,28 +111,140 @@ func (m globalMetadata) String() string {
 
 func (m *globalMetadata) recordStartTime(t time.Time) {
 	m.buffer.WriteString("Started dump at: " + t.Format(metadataTimeLayout) + "\n")
+	m.json.StartTime = t.Format(time.RFC3339)
 }
 
 func (m *globalMetadata) recordFinishTime(t time.Time) {
 	m.buffer.Write(m.afterConnBuffer.Bytes())
 	m.buffer.WriteString("Finished dump at: " + t.Format(metadataTimeLayout) + "\n")
+	m.json.FinishTime = t.Format(time.RFC3339)
+}
+
+func (m *globalMetadata) recordServerInfo(si ServerInfo) {
+	m.json.ServerType = si.ServerType.String()
+	if si.ServerVersion != nil {
+		m.json.ServerVersion = si.ServerVersion.String()
+	}
+	if si.ServerType == ServerTypeTiDB {
+		m.json.Snapshot = m.snapshot
+	}
+}
+
+// recordTables records the row counts, data files and checksums of the dumped tables.
+// tables are the tables to dump, and files are the files written by the dump.
+func (m *globalMetadata) recordTables(tables DatabaseTables, files []*ManifestFile) {
+	tableMap := make(map[tableKey]*tableMetadata)
+	getTable := func(db, tbl string) *tableMetadata {
+		key := tableKey{database: db, table: tbl}
+		t, ok := tableMap[key]
+		if !ok {
+			t = &tableMetadata{Database: db, Table: tbl, Files: []string{}}
+			tableMap[key] = t
+		}
+		return t
+	}
+	for db, tbls := range tables {
+		for _, tbl := range tbls {
+			if tbl.Type == TableTypeBase {
+				getTable(db, tbl.Name)
+			}
+		}
+	}
+	for _, f := range files {
+		if f.Type != ManifestFileTypeData {
+			continue
+		}
+		t := getTable(f.Database, f.Table)
+		t.Rows += f.Rows
+		t.Files = append(t.Files, f.Name)
+	}
+	for _, checksum := range m.checksums {
+		getTable(checksum.Database, checksum.Table).Checksum = checksum
+	}
+
+	m.json.Tables = make([]*tableMetadata, 0, len(tableMap))
+	for _, t := range tableMap {
+		sort.Strings(t.Files)
+		m.json.Tables = append(m.json.Tables, t)
+	}
+	sort.Slice(m.json.Tables, func(i, j int) bool {
+		a, b := m.json.Tables[i], m.json.Tables[j]
+		if a.Database != b.Database {
+			return a.Database < b.Database
+		}
+		return a.Table < b.Table
+	})
+}
+
+// recordRestoreOrder records the schema files and users.sql of files in the order to restore them,
+// the files of the same rank are sorted by their names
+func (m *globalMetadata) recordRestoreOrder(files []*ManifestFile) {
+	type rankedFile struct {
+		rank int
+		name string
+	}
+	ranked := make([]rankedFile, 0, len(files))
+	for _, f := range files {
+		object := f.Object
+		if f.Type == ManifestFileTypeUsers {
+			object = ManifestFileTypeUsers
+		} else if f.Type != ManifestFileTypeSchema {
+			continue
+		}
+		rank, ok := restoreRanks[object]
+		if !ok {
+			continue
+		}
+		ranked = append(ranked, rankedFile{rank: rank, name: f.Name})
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].rank != ranked[j].rank {
+			return ranked[i].rank < ranked[j].rank
+		}
+		return ranked[i].name < ranked[j].name
+	})
+	m.json.RestoreOrder = make([]string, 0, len(ranked))
+	for _, f := range ranked {
+		m.json.RestoreOrder = append(m.json.RestoreOrder, f.name)
+	}
 }
 
 func (m *globalMetadata) recordGlobalMetaData(db *sql.Conn, serverType ServerType, afterConn bool) error { // revive:disable-line:flag-parameter
+	var err error
 	if afterConn {
 		m.afterConnBuffer.Reset()
-		return recordGlobalMetaData(m.tctx, db, &m.afterConnBuffer, serverType, afterConn, m.snapshot)
+		m.json.MasterStatusAfterConnection = nil
+		err = recordGlobalMetaData(m.tctx, db, &m.afterConnBuffer, &m.json, serverType, afterConn, m.snapshot)
+	} else {
+		err = recordGlobalMetaData(m.tctx, db, &m.buffer, &m.json, serverType, afterConn, m.snapshot)
 	}
-	return recordGlobalMetaData(m.tctx, db, &m.buffer, serverType, afterConn, m.snapshot)
+	if err == nil && m.listener != nil {
+		event := &MetadataEvent{AfterConnection: afterConn, Snapshot: m.snapshot, MasterStatus: m.json.MasterStatus}
+		if afterConn {
+			event.MasterStatus = m.json.MasterStatusAfterConnection
+		} else {
+			event.SlaveStatus = m.json.SlaveStatus
+		}
+		m.listener.OnMetadataRecorded(event)
+	}
+	return err
 }
 
-func recordGlobalMetaData(tctx *tcontext.Context, db *sql.Conn, buffer *bytes.Buffer, serverType ServerType, afterConn bool, snapshot string) error { // revive:disable-line:flag-parameter
-	writeMasterStatusHeader := func() {
+func recordGlobalMetaData(tctx *tcontext.Context, db *sql.Conn, buffer *bytes.Buffer, meta *metadataJSON, serverType ServerType, afterConn bool, snapshot string) error { // revive:disable-line:flag-parameter
+	writeMasterStatus := func(logFile, pos, gtidSet string) {
 		buffer.WriteString("SHOW MASTER STATUS:")
 		if afterConn {
 			buffer.WriteString(" /* AFTER CONNECTION POOL ESTABLISHED */")
 		}
 		buffer.WriteString("\n")
+		fmt.Fprintf(buffer, "\tLog: %s\n\tPos: %s\n\tGTID:%s\n", logFile, pos, gtidSet)
+
+		status := &BinlogStatus{Log: logFile, Pos: pos, GTID: gtidSet}
+		if afterConn {
+			meta.MasterStatusAfterConnection = status
+		} else {
+			meta.MasterStatus = status
+		}
 	}
 
 	switch serverType {
@@ -109,8 +282,7 @@ func recordGlobalMetaData(tctx *tcontext.Context, db *sql.Conn, buffer *bytes.Bu
 		gtidSet := getValidStr(str, gtidSetFieldIndex)
 
 		if logFile != "" {
-			writeMasterStatusHeader()
-			fmt.Fprintf(buffer, "\tLog: %s\n\tPos: %s\n\tGTID:%s\n", logFile, pos, gtidSet)
+			writeMasterStatus(logFile, pos, gtidSet)
 		}
 	// For MariaDB:
 	// SHOW MASTER STATUS;
@@ -140,8 +312,7 @@ func recordGlobalMetaData(tctx *tcontext.Context, db *sql.Conn, buffer *bytes.Bu
 		}
 
 		if logFile != "" {
-			writeMasterStatusHeader()
-			fmt.Fprintf(buffer, "\tLog: %s\n\tPos: %s\n\tGTID:%s\n", logFile, pos, gtidSet)
+			writeMasterStatus(logFile, pos, gtidSet)
 		}
 	default:
 		return errors.Errorf("unsupported serverType %s for recordGlobalMetaData", serverType.String())
@@ -155,21 +326,41 @@ func recordGlobalMetaData(tctx *tcontext.Context, db *sql.Conn, buffer *bytes.Bu
 	if afterConn {
 		return nil
 	}
-	// get follower status info
-	var (
-		isms  bool
-		query string
-	)
+	return showSlaveStatus(db, func(status map[string]sql.NullString) {
+		// only the status of multi-source replication has connection name
+		connName, isms := status["connection_name"]
+		pos, logFile := status["exec_master_log_pos"].String, status["relay_master_log_file"].String
+		host, gtidSet := status["master_host"].String, status["executed_gtid_set"].String
+		if len(host) > 0 {
+			buffer.WriteString("SHOW SLAVE STATUS:\n")
+			if isms {
+				buffer.WriteString("\tConnection name: " + connName.String + "\n")
+			}
+			fmt.Fprintf(buffer, "\tHost: %s\n\tLog: %s\n\tPos: %s\n\tGTID:%s\n\n", host, logFile, pos, gtidSet)
+			meta.SlaveStatus = append(meta.SlaveStatus, &BinlogStatus{
+				ConnectionName: connName.String,
+				Host:           host,
+				Log:            logFile,
+				Pos:            pos,
+				GTID:           gtidSet,
+			})
+		}
+	})
+}
+
+// showSlaveStatus runs `SHOW SLAVE STATUS`, or `SHOW ALL SLAVES STATUS` if the server is MariaDB with multi-source
+// replication, and calls handleOneRow with the lower case column names and the values of every replication channel.
+func showSlaveStatus(db *sql.Conn, handleOneRow func(status map[string]sql.NullString)) error {
+	isms := false
 	if err := simpleQuery(db, "SELECT @@default_master_connection", func(rows *sql.Rows) error {
 		isms = true
 		return nil
 	}); err != nil {
 		isms = false
 	}
+	query := "SHOW SLAVE STATUS"
 	if isms {
 		query = "SHOW ALL SLAVES STATUS"
-	} else {
-		query = "SHOW SLAVE STATUS"
 	}
 	return simpleQuery(db, query, func(rows *sql.Rows) error {
 		cols, err := rows.Columns()
@@ -184,44 +375,36 @@ func recordGlobalMetaData(tctx *tcontext.Context, db *sql.Conn, buffer *bytes.Bu
 		if err := rows.Scan(args...); err != nil {
 			return errors.Trace(err)
 		}
-		var connName, pos, logFile, host, gtidSet string
+		status := make(map[string]sql.NullString, len(cols))
 		for i, col := range cols {
-			if data[i].Valid {
-				col = strings.ToLower(col)
-				switch col {
-				case "connection_name":
-					connName = data[i].String
-				case "exec_master_log_pos":
-					pos = data[i].String
-				case "relay_master_log_file":
-					logFile = data[i].String
-				case "master_host":
-					host = data[i].String
-				case "executed_gtid_set":
-					gtidSet = data[i].String
-				}
-			}
-		}
-		if len(host) > 0 {
-			buffer.WriteString("SHOW SLAVE STATUS:\n")
-			if isms {
-				buffer.WriteString("\tConnection name: " + connName + "\n")
-			}
-			fmt.Fprintf(buffer, "\tHost: %s\n\tLog: %s\n\tPos: %s\n\tGTID:%s\n\n", host, logFile, pos, gtidSet)
+			status[strings.ToLower(col)] = data[i]
 		}
+		handleOneRow(status)
 		return nil
 	})
 }
 
 func (m *globalMetadata) writeGlobalMetaData() error {
+	if err := m.writeFile(metadataPath, m.String()); err != nil {
+		return err
+	}
+	content, err := json.MarshalIndent(&m.json, "", "  ")
+	if err != nil {
+		return errors.Trace(err)
+	}
+	// WriteFile returns the error of closing the file, which is only logged by writeFile
+	return errors.Annotatef(m.storage.WriteFile(m.tctx, metadataJSONPath, content), "fail to write %s", metadataJSONPath)
+}
+
+func (m *globalMetadata) writeFile(path, content string) error {
 	// keep consistent with mydumper. Never compress metadata
-	fileWriter, tearDown, err := buildFileWriter(m.tctx, m.storage, metadataPath, storage.NoCompression)
+	fileWriter, tearDown, err := buildFileWriter(m.tctx, m.storage, path, noCompressOption)
 	if err != nil {
 		return err
 	}
 	defer tearDown(m.tctx)
 
-	return write(m.tctx, fileWriter, m.String())
+	return write(m.tctx, fileWriter, content)
 }
 
 func getValidStr(str []string, idx int) string {
@@ -4,15 +4,19 @@ package export
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	tcontext "github.com/pingcap/dumpling/v4/context"
 	"github.com/stretchr/testify/require"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/coreos/go-semver/semver"
 	"github.com/pingcap/tidb/br/pkg/storage"
 )
 
@@ -331,3 +335,134 @@ func createStorage(t *testing.T) storage.ExternalStorage {
 	testLoc, _ := storage.Create(context.Background(), backend, true)
 	return testLoc
 }
+
+func TestMetadataJSON(t *testing.T) {
+	t.Parallel()
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, db.Close())
+	}()
+
+	conn, err := db.Conn(context.Background())
+	require.NoError(t, err)
+
+	rows := sqlmock.NewRows([]string{"File", "Position", "Binlog_Do_DB", "Binlog_Ignore_DB", "Executed_Gtid_Set"}).
+		AddRow(logFile, pos, "", "", gtidSet)
+	followerRows := sqlmock.NewRows([]string{"connection_name", "exec_master_log_pos", "relay_master_log_file", "master_host", "Executed_Gtid_Set"}).
+		AddRow("conn1", "256529431", "mysql-bin.001821", "192.168.1.100", gtidSet).
+		AddRow("conn2", "1024", "mysql-bin.000003", "192.168.1.101", "")
+	mock.ExpectQuery("SHOW MASTER STATUS").WillReturnRows(rows)
+	mock.ExpectQuery("SELECT @@default_master_connection").WillReturnRows(sqlmock.NewRows([]string{"@@default_master_connection"}).AddRow("conn1"))
+	mock.ExpectQuery("SHOW ALL SLAVES STATUS").WillReturnRows(followerRows)
+	mock.ExpectQuery("SHOW MASTER STATUS").WillReturnRows(
+		sqlmock.NewRows([]string{"File", "Position", "Binlog_Do_DB", "Binlog_Ignore_DB", "Executed_Gtid_Set"}).
+			AddRow(logFile, "8000", "", "", gtidSet))
+
+	dir := t.TempDir()
+	extStore, err := storage.NewLocalStorage(dir)
+	require.NoError(t, err)
+	m := newGlobalMetadata(tcontext.Background(), extStore, "")
+	startTime := time.Date(2021, 7, 1, 10, 0, 0, 0, time.UTC)
+	m.recordStartTime(startTime)
+	m.recordServerInfo(ServerInfo{ServerType: ServerTypeMySQL, ServerVersion: semver.New("8.0.25")})
+	require.NoError(t, m.recordGlobalMetaData(conn, ServerTypeMySQL, false))
+	require.NoError(t, m.recordGlobalMetaData(conn, ServerTypeMySQL, true))
+	m.recordTableChecksums([]*tableChecksum{{Database: "test", Table: "t", Algorithm: ChecksumAlgorithmCRC64XOR, Checksum: 1, Count: 3, Bytes: 3}})
+	m.recordTables(DatabaseTables{"test": {{Name: "t", Type: TableTypeBase}, {Name: "empty", Type: TableTypeBase}, {Name: "v", Type: TableTypeView}}},
+		[]*ManifestFile{
+			{Name: "test.t-schema.sql", Type: ManifestFileTypeSchema, Database: "test", Table: "t"},
+			{Name: "test.t.000000001.sql", Type: ManifestFileTypeData, Database: "test", Table: "t", ChunkIndex: 1, Rows: 1},
+			{Name: "test.t.000000000.sql", Type: ManifestFileTypeData, Database: "test", Table: "t", Rows: 2},
+		})
+	m.recordFinishTime(startTime.Add(time.Minute))
+	require.NoError(t, m.writeGlobalMetaData())
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	content, err := os.ReadFile(filepath.Join(dir, metadataJSONPath))
+	require.NoError(t, err)
+	var meta metadataJSON
+	require.NoError(t, json.Unmarshal(content, &meta))
+	require.Equal(t, metadataJSON{
+		StartTime:     "2021-07-01T10:00:00Z",
+		FinishTime:    "2021-07-01T10:01:00Z",
+		ServerType:    "MySQL",
+		ServerVersion: "8.0.25",
+		MasterStatus:  &BinlogStatus{Log: logFile, Pos: pos, GTID: gtidSet},
+		SlaveStatus: []*BinlogStatus{
+			{ConnectionName: "conn1", Host: "192.168.1.100", Log: "mysql-bin.001821", Pos: "256529431", GTID: gtidSet},
+			{ConnectionName: "conn2", Host: "192.168.1.101", Log: "mysql-bin.000003", Pos: "1024"},
+		},
+		MasterStatusAfterConnection: &BinlogStatus{Log: logFile, Pos: "8000", GTID: gtidSet},
+		Tables: []*tableMetadata{
+			{Database: "test", Table: "empty", Files: []string{}},
+			{
+				Database: "test",
+				Table:    "t",
+				Rows:     3,
+				Files:    []string{"test.t.000000000.sql", "test.t.000000001.sql"},
+				Checksum: &tableChecksum{Database: "test", Table: "t", Algorithm: ChecksumAlgorithmCRC64XOR, Checksum: 1, Count: 3, Bytes: 3},
+			},
+		},
+	}, meta)
+
+	// the text metadata file is still written
+	content, err = os.ReadFile(filepath.Join(dir, metadataPath))
+	require.NoError(t, err)
+	require.Contains(t, string(content), "SHOW MASTER STATUS: /* AFTER CONNECTION POOL ESTABLISHED */\n")
+}
+
+func TestRecordRestoreOrder(t *testing.T) {
+	t.Parallel()
+
+	m := newGlobalMetadata(tcontext.Background(), nil, "")
+	m.recordRestoreOrder([]*ManifestFile{
+		{Name: "metadata", Type: ManifestFileTypeMetadata},
+		{Name: "test.e-event-schema-post.sql", Type: ManifestFileTypeSchema, Object: outputFileTemplateEvent},
+		{Name: "test.f-function-schema-post.sql", Type: ManifestFileTypeSchema, Object: outputFileTemplateFunction},
+		{Name: "test.s-schema-sequence.sql", Type: ManifestFileTypeSchema, Object: outputFileTemplateSequence},
+		{Name: "test.t-schema-triggers.sql", Type: ManifestFileTypeSchema, Object: outputFileTemplateTrigger},
+		{Name: "test.t-schema.sql", Type: ManifestFileTypeSchema, Object: outputFileTemplateTable},
+		{Name: "test.t.000000000.sql", Type: ManifestFileTypeData},
+		{Name: "test.v-schema-view.sql", Type: ManifestFileTypeSchema, Object: outputFileTemplateView},
+		{Name: "test.v-schema.sql", Type: ManifestFileTypeSchema, Object: outputFileTemplateTable},
+		{Name: "test-schema-create.sql", Type: ManifestFileTypeSchema, Object: outputFileTemplateSchema},
+		{Name: "p-placement-policy-create.sql", Type: ManifestFileTypeSchema, Object: outputFileTemplatePolicy},
+		{Name: "rg-resource-group-create.sql", Type: ManifestFileTypeSchema, Object: outputFileTemplateResGroup},
+		{Name: "users.sql", Type: ManifestFileTypeUsers},
+	})
+	require.Equal(t, []string{
+		"rg-resource-group-create.sql",
+		"p-placement-policy-create.sql",
+		"test-schema-create.sql",
+		"test.s-schema-sequence.sql",
+		"test.t-schema.sql",
+		"test.v-schema.sql",
+		"test.f-function-schema-post.sql",
+		"test.v-schema-view.sql",
+		"test.t-schema-triggers.sql",
+		"test.e-event-schema-post.sql",
+		"users.sql",
+	}, m.json.RestoreOrder)
+}
+
+// failWriteFileStorage fails to write files by WriteFile
+type failWriteFileStorage struct {
+	storage.ExternalStorage
+}
+
+func (s failWriteFileStorage) WriteFile(context.Context, string, []byte) error {
+	return errors.New("storage is full")
+}
+
+func TestWriteGlobalMetaDataError(t *testing.T) {
+	t.Parallel()
+
+	store, err := storage.NewLocalStorage(t.TempDir())
+	require.NoError(t, err)
+	m := newGlobalMetadata(tcontext.Background(), failWriteFileStorage{ExternalStorage: store}, "")
+	err = m.writeGlobalMetaData()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), metadataJSONPath)
+}
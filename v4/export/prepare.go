@@ -15,10 +15,17 @@ import (
 )
 
 const (
-	outputFileTemplateSchema = "schema"
-	outputFileTemplateTable  = "table"
-	outputFileTemplateView   = "view"
-	outputFileTemplateData   = "data"
+	outputFileTemplateSchema    = "schema"
+	outputFileTemplateTable     = "table"
+	outputFileTemplateView      = "view"
+	outputFileTemplateProcedure = "procedure"
+	outputFileTemplateFunction  = "function"
+	outputFileTemplateTrigger   = "trigger"
+	outputFileTemplateEvent     = "event"
+	outputFileTemplateSequence  = "sequence"
+	outputFileTemplatePolicy    = "placement-policy"
+	outputFileTemplateResGroup  = "resource-group"
+	outputFileTemplateData      = "data"
 
 	defaultOutputFileTemplateBase = `
 		{{- define "objectName" -}}
@@ -27,14 +34,20 @@ const (
 		{{- define "schema" -}}
 			{{fn .DB}}-schema-create
 		{{- end -}}
+		{{- define "placement-policy" -}}
+			{{fn .Policy}}-placement-policy-create
+		{{- end -}}
+		{{- define "resource-group" -}}
+			{{fn .ResourceGroup}}-resource-group-create
+		{{- end -}}
 		{{- define "event" -}}
-			{{template "objectName" .}}-schema-post
+			{{template "objectName" .}}-event-schema-post
 		{{- end -}}
 		{{- define "function" -}}
-			{{template "objectName" .}}-schema-post
+			{{template "objectName" .}}-function-schema-post
 		{{- end -}}
 		{{- define "procedure" -}}
-			{{template "objectName" .}}-schema-post
+			{{template "objectName" .}}-procedure-schema-post
 		{{- end -}}
 		{{- define "sequence" -}}
 			{{template "objectName" .}}-schema-sequence
@@ -112,6 +125,8 @@ const (
 	TableTypeBase TableType = iota
 	// TableTypeView represents the view table
 	TableTypeView
+	// TableTypeSequence represents the sequence of TiDB
+	TableTypeSequence
 )
 
 const (
@@ -119,6 +134,8 @@ const (
 	TableTypeBaseStr = "BASE TABLE"
 	// TableTypeViewStr represents the view table string
 	TableTypeViewStr = "VIEW"
+	// TableTypeSequenceStr represents the sequence string
+	TableTypeSequenceStr = "SEQUENCE"
 )
 
 func (t TableType) String() string {
@@ -127,6 +144,8 @@ func (t TableType) String() string {
 		return TableTypeBaseStr
 	case TableTypeView:
 		return TableTypeViewStr
+	case TableTypeSequence:
+		return TableTypeSequenceStr
 	default:
 		return "UNKNOWN"
 	}
@@ -139,6 +158,8 @@ func ParseTableType(s string) (TableType, error) {
 		return TableTypeBase, nil
 	case TableTypeViewStr:
 		return TableTypeView, nil
+	case TableTypeSequenceStr:
+		return TableTypeSequence, nil
 	default:
 		return TableTypeBase, errors.Errorf("unknown table type %s", s)
 	}
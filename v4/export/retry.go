@@ -3,6 +3,7 @@
 package export
 
 import (
+	"math/rand"
 	"strings"
 	"time"
 
@@ -15,36 +16,117 @@ import (
 )
 
 const (
-	dumpChunkRetryTime       = 3
-	lockTablesRetryTime      = 5
-	dumpChunkWaitInterval    = 50 * time.Millisecond
-	dumpChunkMaxWaitInterval = 200 * time.Millisecond
+	lockTablesRetryTime = 5
 	// ErrNoSuchTable is the error code no such table in MySQL/TiDB
 	ErrNoSuchTable uint16 = 1146
 )
 
-func newDumpChunkBackoffer(shouldRetry bool) *dumpChunkBackoffer { // revive:disable-line:flag-parameter
-	if !shouldRetry {
-		return &dumpChunkBackoffer{
-			attempt: 1,
+// RetryPolicy is the policy to retry the table chunks failed to dump and `LOCK TABLES` failed by transient errors
+type RetryPolicy struct {
+	// MaxAttempts is the max number of attempts including the first one
+	MaxAttempts int
+	// BaseBackoff is the backoff before the first retry, the backoff is doubled after every retry up to MaxBackoff
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+	// Jitter is the fraction of the backoff which is randomly cut off, in [0, 1]
+	Jitter float64
+	// RetryableErrorCodes and FatalErrorCodes are the MySQL error codes which are always or never retried.
+	// The other MySQL errors are retried if they are classified as retryable by dbutil.IsRetryableError.
+	RetryableErrorCodes []uint16
+	FatalErrorCodes     []uint16
+}
+
+// DefaultRetryPolicy returns the default RetryPolicy
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 3,
+		BaseBackoff: 100 * time.Millisecond,
+		MaxBackoff:  200 * time.Millisecond,
+	}
+}
+
+func adjustRetryPolicy(conf *Config) error {
+	p := conf.RetryPolicy
+	if p.MaxAttempts < 1 {
+		return errors.Errorf("invalid retry max attempts %d, should be at least 1", p.MaxAttempts)
+	}
+	if p.BaseBackoff < 0 || p.MaxBackoff < p.BaseBackoff {
+		return errors.Errorf("invalid retry backoff [%s, %s], should be non-negative and base backoff should not exceed max backoff", p.BaseBackoff, p.MaxBackoff)
+	}
+	if p.Jitter < 0 || p.Jitter > 1 {
+		return errors.Errorf("invalid retry jitter %v, should be in [0, 1]", p.Jitter)
+	}
+	for _, code := range p.RetryableErrorCodes {
+		for _, fatal := range p.FatalErrorCodes {
+			if code == fatal {
+				return errors.Errorf("error code %d can't be both retryable and fatal", code)
+			}
 		}
 	}
-	return &dumpChunkBackoffer{
-		attempt:      dumpChunkRetryTime,
-		delayTime:    dumpChunkWaitInterval,
-		maxDelayTime: dumpChunkMaxWaitInterval,
+	return nil
+}
+
+// retryableMySQLError returns whether the MySQL error should be retried
+func (p *RetryPolicy) retryableMySQLError(err *mysql.MySQLError) bool {
+	for _, code := range p.FatalErrorCodes {
+		if code == err.Number {
+			return false
+		}
+	}
+	for _, code := range p.RetryableErrorCodes {
+		if code == err.Number {
+			return true
+		}
+	}
+	return dbutil.IsRetryableError(err)
+}
+
+// retryBackoff counts the attempts and calculates the backoffs of retrying transient errors by a RetryPolicy
+type retryBackoff struct {
+	policy  *RetryPolicy
+	attempt int
+	delay   time.Duration
+}
+
+func newRetryBackoff(policy *RetryPolicy) retryBackoff {
+	return retryBackoff{
+		policy:  policy,
+		attempt: policy.MaxAttempts,
+		delay:   policy.BaseBackoff,
+	}
+}
+
+func (b *retryBackoff) next() time.Duration {
+	b.attempt--
+	if b.attempt <= 0 {
+		return 0
+	}
+	delay := b.delay
+	if delay > b.policy.MaxBackoff {
+		delay = b.policy.MaxBackoff
+	}
+	b.delay = 2 * delay
+	if b.policy.Jitter > 0 {
+		delay -= time.Duration(rand.Float64() * b.policy.Jitter * float64(delay))
 	}
+	return delay
+}
+
+func newDumpChunkBackoffer(policy *RetryPolicy, shouldRetry bool) *dumpChunkBackoffer { // revive:disable-line:flag-parameter
+	b := &dumpChunkBackoffer{retryBackoff: newRetryBackoff(policy)}
+	if !shouldRetry {
+		b.attempt = 1
+	}
+	return b
 }
 
 type dumpChunkBackoffer struct {
-	attempt      int
-	delayTime    time.Duration
-	maxDelayTime time.Duration
+	retryBackoff
 }
 
 func (b *dumpChunkBackoffer) NextBackoff(err error) time.Duration {
 	err = errors.Cause(err)
-	if _, ok := err.(*mysql.MySQLError); ok && !dbutil.IsRetryableError(err) {
+	if mysqlErr, ok := err.(*mysql.MySQLError); ok && !b.policy.retryableMySQLError(mysqlErr) {
 		b.attempt = 0
 		return 0
 	} else if _, ok := err.(*writerError); ok {
@@ -52,35 +134,40 @@ func (b *dumpChunkBackoffer) NextBackoff(err error) time.Duration {
 		b.attempt = 0
 		return 0
 	}
-	b.delayTime = 2 * b.delayTime
-	b.attempt--
-	if b.delayTime > b.maxDelayTime {
-		return b.maxDelayTime
-	}
-	return b.delayTime
+	return b.next()
 }
 
 func (b *dumpChunkBackoffer) Attempt() int {
 	return b.attempt
 }
 
-func newLockTablesBackoffer(tctx *tcontext.Context, blockList map[string]map[string]interface{}) *lockTablesBackoffer {
+// newLockTablesBackoffer returns the backoffer of `LOCK TABLES`, which skips the tables dropped during locking
+// for at most lockTablesRetryTime times, and retries the transient errors by policy.
+func newLockTablesBackoffer(tctx *tcontext.Context, policy *RetryPolicy, blockList map[string]map[string]interface{}) *lockTablesBackoffer {
 	return &lockTablesBackoffer{
-		tctx:      tctx,
-		attempt:   lockTablesRetryTime,
-		blockList: blockList,
+		tctx:         tctx,
+		attempt:      lockTablesRetryTime,
+		retryBackoff: newRetryBackoff(policy),
+		blockList:    blockList,
 	}
 }
 
 type lockTablesBackoffer struct {
-	tctx      *tcontext.Context
-	attempt   int
-	blockList map[string]map[string]interface{}
+	tctx    *tcontext.Context
+	attempt int
+	// retryBackoff counts the retries of transient errors
+	retryBackoff retryBackoff
+	blockList    map[string]map[string]interface{}
 }
 
 func (b *lockTablesBackoffer) NextBackoff(err error) time.Duration {
 	err = errors.Cause(err)
-	if mysqlErr, ok := err.(*mysql.MySQLError); ok && mysqlErr.Number == ErrNoSuchTable {
+	mysqlErr, ok := err.(*mysql.MySQLError)
+	if !ok {
+		b.attempt = 0
+		return 0
+	}
+	if mysqlErr.Number == ErrNoSuchTable {
 		b.attempt--
 		db, table, err := getTableFromMySQLError(mysqlErr.Message)
 		if err != nil {
@@ -94,11 +181,17 @@ func (b *lockTablesBackoffer) NextBackoff(err error) time.Duration {
 		b.blockList[db][table] = struct{}{}
 		return 0
 	}
+	if b.retryBackoff.policy.retryableMySQLError(mysqlErr) {
+		return b.retryBackoff.next()
+	}
 	b.attempt = 0
 	return 0
 }
 
 func (b *lockTablesBackoffer) Attempt() int {
+	if b.retryBackoff.attempt <= 0 {
+		return 0
+	}
 	return b.attempt
 }
 
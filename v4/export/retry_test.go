@@ -0,0 +1,94 @@
+// Copyright 2021 PingCAP, Inc. Licensed under Apache-2.0.
+
+package export
+
+import (
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	tcontext "github.com/pingcap/dumpling/v4/context"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/pingcap/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDumpChunkBackoffer(t *testing.T) {
+	t.Parallel()
+
+	policy := DefaultRetryPolicy()
+	policy.MaxAttempts = 4
+	policy.MaxBackoff = 300 * time.Millisecond
+	b := newDumpChunkBackoffer(&policy, true)
+	require.Equal(t, 4, b.Attempt())
+	require.Equal(t, 100*time.Millisecond, b.NextBackoff(errors.Trace(driver.ErrBadConn)))
+	require.Equal(t, 200*time.Millisecond, b.NextBackoff(driver.ErrBadConn))
+	require.Equal(t, 300*time.Millisecond, b.NextBackoff(driver.ErrBadConn))
+	require.Equal(t, 1, b.Attempt())
+	// no backoff after the last attempt
+	require.Equal(t, time.Duration(0), b.NextBackoff(driver.ErrBadConn))
+	require.Equal(t, 0, b.Attempt())
+
+	// only one attempt if the connection can't be rebuilt
+	b = newDumpChunkBackoffer(&policy, false)
+	require.Equal(t, 1, b.Attempt())
+
+	// 1105 isn't retryable by default
+	unknownErr := &mysql.MySQLError{Number: 1105, Message: "unknown error"}
+	b = newDumpChunkBackoffer(&policy, true)
+	b.NextBackoff(errors.Trace(unknownErr))
+	require.Equal(t, 0, b.Attempt())
+	policy.RetryableErrorCodes = []uint16{1105}
+	b = newDumpChunkBackoffer(&policy, true)
+	b.NextBackoff(errors.Trace(unknownErr))
+	require.Equal(t, 3, b.Attempt())
+
+	// 1213 (deadlock) is retryable by default
+	deadlockErr := &mysql.MySQLError{Number: 1213, Message: "Deadlock found when trying to get lock"}
+	b = newDumpChunkBackoffer(&policy, true)
+	b.NextBackoff(deadlockErr)
+	require.Equal(t, 3, b.Attempt())
+	policy.FatalErrorCodes = []uint16{1213}
+	b = newDumpChunkBackoffer(&policy, true)
+	b.NextBackoff(deadlockErr)
+	require.Equal(t, 0, b.Attempt())
+
+	// the backoff is cut off by jitter
+	policy = DefaultRetryPolicy()
+	policy.Jitter = 0.5
+	for i := 0; i < 10; i++ {
+		b = newDumpChunkBackoffer(&policy, true)
+		backoff := b.NextBackoff(driver.ErrBadConn)
+		require.GreaterOrEqual(t, backoff, 50*time.Millisecond)
+		require.LessOrEqual(t, backoff, 100*time.Millisecond)
+	}
+}
+
+func TestLockTablesBackoffer(t *testing.T) {
+	t.Parallel()
+
+	policy := DefaultRetryPolicy()
+	policy.MaxAttempts = 2
+	policy.RetryableErrorCodes = []uint16{1105}
+	blockList := make(map[string]map[string]interface{})
+	b := newLockTablesBackoffer(tcontext.Background(), &policy, blockList)
+	require.Equal(t, lockTablesRetryTime, b.Attempt())
+
+	// the dropped table is skipped without backoff
+	require.Equal(t, time.Duration(0), b.NextBackoff(&mysql.MySQLError{Number: ErrNoSuchTable, Message: "Table 'db.t1' doesn't exist"}))
+	require.Contains(t, blockList["db"], "t1")
+	require.Equal(t, lockTablesRetryTime-1, b.Attempt())
+
+	// the transient errors are retried by the policy
+	unknownErr := &mysql.MySQLError{Number: 1105, Message: "unknown error"}
+	require.Equal(t, 100*time.Millisecond, b.NextBackoff(errors.Trace(unknownErr)))
+	require.Equal(t, lockTablesRetryTime-1, b.Attempt())
+	require.Equal(t, time.Duration(0), b.NextBackoff(unknownErr))
+	require.Equal(t, 0, b.Attempt())
+
+	// the other errors aren't retried
+	b = newLockTablesBackoffer(tcontext.Background(), &policy, blockList)
+	b.NextBackoff(&mysql.MySQLError{Number: 1044, Message: "Access denied"})
+	require.Equal(t, 0, b.Attempt())
+}
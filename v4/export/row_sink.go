@@ -0,0 +1,146 @@
+// Copyright 2021 PingCAP, Inc. Licensed under Apache-2.0.
+
+package export
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"strings"
+
+	tcontext "github.com/pingcap/dumpling/v4/context"
+	"github.com/pingcap/dumpling/v4/log"
+
+	"github.com/pingcap/errors"
+	"go.uber.org/zap"
+)
+
+// rowSinkRowsPerGaugeUpdate controls how often finishedRowsGauge is updated when sending rows to RowSink
+const rowSinkRowsPerGaugeUpdate = 1024
+
+// RowSink receives the rows of the dumped tables in process instead of the data files written to the external
+// storage, see WithRowSink. The methods are called by all the writers concurrently.
+type RowSink interface {
+	// WriteRow receives a row of a table chunk. The value of a column is nil for NULL, int64 or uint64 for
+	// integers, float64 for FLOAT and DOUBLE, []byte for binary types, and string for the others including
+	// DECIMAL and temporal types.
+	WriteRow(ctx context.Context, meta TableMeta, chunkIndex int, row []interface{}) error
+	// FinishChunk is called after all the rows of a table chunk are written. A table chunk which fails is dumped
+	// again unless it can be resumed after the last written row, so the rows written before FinishChunk may be
+	// received more than once.
+	FinishChunk(ctx context.Context, meta TableMeta, chunkIndex int) error
+}
+
+// WithRowSink makes the Dumper send the rows of the tables to sink instead of writing data files. The schema
+// files and the metadata are still written to the output directory.
+func WithRowSink(sink RowSink) DumperOption {
+	return func(conf *Config) {
+		conf.RowSink = sink
+	}
+}
+
+// buildRowSinkColumns classifies the columns like parquet, except that the unsigned integers are integers too
+func buildRowSinkColumns(meta TableMeta) []parquetColumn {
+	colTypes := meta.ColumnTypes()
+	sinkColTypes := make([]string, len(colTypes))
+	for i, colType := range colTypes {
+		sinkColTypes[i] = strings.TrimPrefix(colType, "UNSIGNED ")
+	}
+	return buildParquetColumns(meta.ColumnNames(), sinkColTypes)
+}
+
+// convertSinkValue converts the raw bytes read from database to the go value passed to RowSink
+func convertSinkValue(col parquetColumn, raw sql.RawBytes) (interface{}, error) {
+	if raw == nil {
+		return nil, nil
+	}
+	switch col.kind {
+	case parquetKindInt64:
+		if v, err := strconv.ParseInt(string(raw), 10, 64); err == nil {
+			return v, nil
+		}
+		// BIGINT UNSIGNED may overflow int64
+		v, err := strconv.ParseUint(string(raw), 10, 64)
+		if err != nil {
+			return nil, errors.Annotatef(err, "column %s is not an integer", col.name)
+		}
+		return v, nil
+	case parquetKindDouble:
+		v, err := strconv.ParseFloat(string(raw), 64)
+		if err != nil {
+			return nil, errors.Annotatef(err, "column %s is not a float", col.name)
+		}
+		return v, nil
+	case parquetKindBinary:
+		// raw is reused by the next row
+		return append([]byte{}, raw...), nil
+	default:
+		return string(raw), nil
+	}
+}
+
+// writeToRowSink sends the rows of a table chunk to conf.RowSink, and returns the number of sent rows
+func writeToRowSink(pCtx *tcontext.Context, cfg *Config, meta TableMeta, tblIR TableDataIR, chunkIndex int) (n uint64, err error) {
+	sink := cfg.RowSink
+	fileRowIter := tblIR.Rows()
+	if !fileRowIter.HasNext() {
+		if err = fileRowIter.Error(); err != nil {
+			return 0, err
+		}
+		return 0, sink.FinishChunk(pCtx, meta, chunkIndex)
+	}
+
+	var (
+		columns     = buildRowSinkColumns(meta)
+		row         = make(rawBytesRow, len(columns))
+		counter     uint64
+		lastCounter uint64
+	)
+	defer func() {
+		if err != nil {
+			pCtx.L().Warn("fail to send table(chunk) to row sink, will revert some metrics and start a retry if possible",
+				zap.String("database", meta.DatabaseName()),
+				zap.String("table", meta.TableName()),
+				zap.Uint64("finished rows", lastCounter),
+				log.ShortError(err))
+			SubGauge(finishedRowsGauge, cfg.Labels, float64(lastCounter))
+		}
+	}()
+
+	for fileRowIter.HasNext() {
+		if err = fileRowIter.Decode(row); err != nil {
+			return counter, errors.Trace(err)
+		}
+		if err = cfg.throttle.waitRows(pCtx, 1); err != nil {
+			return counter, err
+		}
+		values := make([]interface{}, len(columns))
+		for i, col := range columns {
+			if values[i], err = convertSinkValue(col, row[i]); err != nil {
+				return counter, err
+			}
+		}
+		if err = sink.WriteRow(pCtx, meta, chunkIndex, values); err != nil {
+			return counter, errors.Annotate(err, "row sink fails to write row")
+		}
+		counter++
+
+		fileRowIter.Next()
+		if counter-lastCounter >= rowSinkRowsPerGaugeUpdate {
+			if err = pCtx.Err(); err != nil {
+				return counter, err
+			}
+			AddGauge(finishedRowsGauge, cfg.Labels, float64(counter-lastCounter))
+			lastCounter = counter
+		}
+	}
+	if err = fileRowIter.Error(); err != nil {
+		return counter, errors.Trace(err)
+	}
+	if err = sink.FinishChunk(pCtx, meta, chunkIndex); err != nil {
+		return counter, errors.Annotate(err, "row sink fails to finish chunk")
+	}
+	AddGauge(finishedRowsGauge, cfg.Labels, float64(counter-lastCounter))
+	lastCounter = counter
+	return counter, nil
+}
@@ -0,0 +1,79 @@
+// Copyright 2021 PingCAP, Inc. Licensed under Apache-2.0.
+
+package export
+
+import (
+	"context"
+	"database/sql/driver"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/pingcap/errors"
+	"github.com/stretchr/testify/require"
+)
+
+type mockRowSink struct {
+	mu       sync.Mutex
+	rows     [][]interface{}
+	finished []int
+	err      error
+}
+
+func (s *mockRowSink) WriteRow(_ context.Context, meta TableMeta, _ int, row []interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	s.rows = append(s.rows, row)
+	return nil
+}
+
+func (s *mockRowSink) FinishChunk(_ context.Context, _ TableMeta, chunkIndex int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.finished = append(s.finished, chunkIndex)
+	return nil
+}
+
+func TestWriteTableDataToRowSink(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	config := defaultConfigForTest(t)
+	config.OutputDirPath = dir
+	sink := &mockRowSink{}
+	WithRowSink(sink)(config)
+
+	writer, clean := createTestWriter(config, t)
+	defer clean()
+
+	data := [][]driver.Value{
+		{"1", "18446744073709551615", "1.5", "3.14", "bob", []byte{0, 1}},
+		{"-2", "0", "2", "0.00", nil, nil},
+	}
+	colTypes := []string{"INT", "UNSIGNED BIGINT", "DOUBLE", "DECIMAL", "VARCHAR", "BLOB"}
+	tableIR := newMockTableIR("test", "employee", data, nil, colTypes)
+	require.NoError(t, writer.WriteTableData(tableIR, tableIR, 3))
+
+	require.Equal(t, [][]interface{}{
+		{int64(1), uint64(18446744073709551615), 1.5, "3.14", "bob", []byte{0, 1}},
+		{int64(-2), int64(0), float64(2), "0.00", nil, nil},
+	}, sink.rows)
+	require.Equal(t, []int{3}, sink.finished)
+
+	// no data file is written
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Empty(t, entries)
+
+	// the error of the sink fails the chunk
+	sink.rows, sink.finished, sink.err = nil, nil, errors.New("sink is closed")
+	writer.conf.RetryPolicy.MaxAttempts = 1
+	tableIR = newMockTableIR("test", "employee", data, nil, colTypes)
+	err = writer.WriteTableData(tableIR, tableIR, 0)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "sink is closed")
+	require.Empty(t, sink.finished)
+}
@@ -0,0 +1,333 @@
+// Copyright 2021 PingCAP, Inc. Licensed under Apache-2.0.
+
+package export
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	tcontext "github.com/pingcap/dumpling/v4/context"
+	"github.com/pingcap/dumpling/v4/log"
+
+	"github.com/pingcap/errors"
+	filter "github.com/pingcap/tidb-tools/pkg/table-filter"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+// ServerJobLabel is the label of the metrics of every job run by Server
+const ServerJobLabel = "job"
+
+// JobRequest is the body of POST /api/v1/jobs
+type JobRequest struct {
+	// Config is decoded onto DefaultConfig, the fields which can't be decoded from JSON are set by the fields below
+	Config                 *Config  `json:"config"`
+	Password               string   `json:"password"`
+	Filter                 []string `json:"filter"`
+	CaseSensitive          bool     `json:"case-sensitive"`
+	OutputFilenameTemplate string   `json:"output-filename-template"`
+}
+
+// JobStatus is the state and progress of a job run by Server
+type JobStatus struct {
+	ID         string      `json:"id"`
+	State      string      `json:"state"`
+	Error      string      `json:"error,omitempty"`
+	SubmitTime time.Time   `json:"submit-time"`
+	FinishTime *time.Time  `json:"finish-time,omitempty"`
+	Progress   *DumpStatus `json:"progress,omitempty"`
+}
+
+type serverJob struct {
+	id         string
+	conf       *Config
+	cancel     context.CancelFunc
+	submitTime time.Time
+
+	// the fields below are protected by Server.mu
+	dumper     *Dumper
+	handlers   map[string]http.HandlerFunc
+	canceled   bool
+	finished   bool
+	err        error
+	finishTime time.Time
+}
+
+// Server runs the dump jobs submitted over HTTP. Every job runs its own Dumper, whose metrics are labeled by
+// the job ID, and at most maxHistory finished jobs are kept. The job API requires the token if it's set.
+type Server struct {
+	tctx       *tcontext.Context
+	maxHistory int
+	token      string
+
+	mu     sync.Mutex
+	jobs   map[string]*serverJob
+	order  []string
+	nextID int
+	// newDumper is replaced in unit tests
+	newDumper func(ctx context.Context, conf *Config) (*Dumper, error)
+}
+
+// NewServer returns a new Server, the context of all the jobs is derived from ctx. The requests of the job API
+// must have the header "Authorization: Bearer <token>" if token isn't empty.
+func NewServer(ctx context.Context, logger *zap.Logger, maxHistory int, token string) *Server {
+	return &Server{
+		tctx:       tcontext.Background().WithContext(ctx).WithLogger(log.NewAppLogger(logger)),
+		maxHistory: maxHistory,
+		token:      token,
+		jobs:       make(map[string]*serverJob),
+		newDumper: func(ctx context.Context, conf *Config) (*Dumper, error) {
+			return NewDumper(ctx, conf)
+		},
+	}
+}
+
+// Run serves the API and the metrics on addr until it fails. The job API runs dumps with any config submitted,
+// so it's only served without a token on the loopback addresses.
+func (s *Server) Run(addr string) error {
+	if s.token == "" && !isLoopbackAddr(addr) {
+		return errors.Errorf("the job API on %s is accessible from other hosts, set a token or listen on a loopback address like 127.0.0.1", addr)
+	}
+	s.tctx.L().Info("dumpling server is started", zap.String("address", addr))
+	return startDumplingService(s.tctx, addr, s.registerAPI)
+}
+
+func (s *Server) registerAPI(router *http.ServeMux) {
+	handleJobs, handleJob := http.HandlerFunc(s.handleJobs), http.HandlerFunc(s.handleJob)
+	if s.token != "" {
+		handleJobs, handleJob = requireToken(s.token, handleJobs), requireToken(s.token, handleJob)
+	}
+	router.HandleFunc("/api/v1/jobs", handleJobs)
+	router.HandleFunc("/api/v1/jobs/", handleJob)
+}
+
+// isLoopbackAddr returns whether the host of addr is a loopback address, the empty host listens on all the addresses
+func isLoopbackAddr(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// handleJobs lists the jobs on GET and submits a job on POST
+func (s *Server) handleJobs(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, s.listJobs())
+	case http.MethodPost:
+		req := JobRequest{Config: DefaultConfig()}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeAPIError(w, http.StatusBadRequest, errors.Annotate(err, "invalid request body"))
+			return
+		}
+		if err := req.adjustConfig(); err != nil {
+			writeAPIError(w, http.StatusBadRequest, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, s.submit(req.Config))
+	default:
+		writeAPIError(w, http.StatusMethodNotAllowed, errors.Errorf("method %s is not allowed", r.Method))
+	}
+}
+
+// handleJob serves /api/v1/jobs/{id} and the control API of the job like /api/v1/jobs/{id}/pause
+func (s *Server) handleJob(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/api/v1/jobs/")
+	id, action := path, ""
+	if i := strings.IndexByte(path, '/'); i >= 0 {
+		id, action = path[:i], path[i+1:]
+	}
+	s.mu.Lock()
+	job, ok := s.jobs[id]
+	var handler http.HandlerFunc
+	if ok {
+		handler = job.handlers[action]
+	}
+	dumperCreated := ok && job.dumper != nil
+	s.mu.Unlock()
+	switch {
+	case !ok:
+		writeAPIError(w, http.StatusNotFound, errors.Errorf("job %s is not found", id))
+	case action == "":
+		if checkMethod(w, r, http.MethodGet) {
+			writeJSON(w, http.StatusOK, s.jobStatus(job))
+		}
+	case action == "cancel" && !dumperCreated:
+		if !checkMethod(w, r, http.MethodPost) {
+			return
+		}
+		if err := s.cancelPendingJob(job); err != nil {
+			writeAPIError(w, http.StatusConflict, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, s.jobStatus(job))
+	case handler == nil && dumperCreated:
+		writeAPIError(w, http.StatusNotFound, errors.Errorf("unknown action %s", action))
+	case handler == nil:
+		writeAPIError(w, http.StatusConflict, errors.Errorf("job %s is not started", id))
+	default:
+		handler(w, r)
+	}
+}
+
+func (req *JobRequest) adjustConfig() error {
+	conf := req.Config
+	if conf == nil {
+		return errors.New("config is required")
+	}
+	conf.Password = req.Password
+	filters := req.Filter
+	if len(filters) == 0 {
+		filters = []string{"*.*", DefaultTableFilter}
+	}
+	tableFilter, err := ParseTableFilter(nil, filters)
+	if err != nil {
+		return errors.Errorf("failed to parse filter: %s", err)
+	}
+	if !req.CaseSensitive {
+		tableFilter = filter.CaseInsensitive(tableFilter)
+	}
+	conf.TableFilter = tableFilter
+	outputFilenameFormat := req.OutputFilenameTemplate
+	if outputFilenameFormat == "" && conf.SQL != "" {
+		outputFilenameFormat = DefaultAnonymousOutputFileTemplateText
+	}
+	conf.OutputFileTemplate, err = ParseOutputFileTemplate(outputFilenameFormat)
+	if err != nil {
+		return errors.Errorf("failed to parse output filename template '%s'", outputFilenameFormat)
+	}
+	// the jobs are controlled by the API of the server
+	conf.StatusAddr = ""
+	return nil
+}
+
+func (s *Server) submit(conf *Config) *JobStatus {
+	s.mu.Lock()
+	s.nextID++
+	id := strconv.Itoa(s.nextID)
+	ctx, cancel := context.WithCancel(s.tctx)
+	logger := s.tctx.L().With(zap.String("job", id))
+	conf.Logger = logger
+	conf.Labels = prometheus.Labels{ServerJobLabel: id}
+	job := &serverJob{id: id, conf: conf, cancel: cancel, submitTime: time.Now()}
+	s.jobs[id] = job
+	s.order = append(s.order, id)
+	s.evictHistoryLocked()
+	s.mu.Unlock()
+
+	logger.Info("job is submitted", zap.Stringer("conf", conf))
+	go s.run(ctx, job)
+	return s.jobStatus(job)
+}
+
+func (s *Server) run(ctx context.Context, job *serverJob) {
+	defer job.cancel()
+	dumper, err := s.newDumper(ctx, job.conf)
+	if err == nil {
+		s.mu.Lock()
+		job.dumper = dumper
+		job.handlers = controlAPIHandlers(dumper)
+		s.mu.Unlock()
+		err = dumper.Dump()
+	}
+	if dumper != nil {
+		if err1 := dumper.Close(); err1 != nil {
+			job.conf.Logger.Warn("fail to close dumper", zap.Error(err1))
+		}
+	}
+	s.mu.Lock()
+	job.finished, job.err, job.finishTime = true, err, time.Now()
+	s.evictHistoryLocked()
+	s.mu.Unlock()
+	if err != nil {
+		job.conf.Logger.Warn("job failed", zap.Error(err))
+	} else {
+		job.conf.Logger.Info("job is finished")
+	}
+}
+
+// cancelPendingJob cancels a job whose Dumper isn't created yet
+func (s *Server) cancelPendingJob(job *serverJob) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if job.finished {
+		return errors.Errorf("job %s is finished", job.id)
+	}
+	job.canceled = true
+	job.cancel()
+	return nil
+}
+
+// evictHistoryLocked removes the earliest finished jobs beyond maxHistory with their metrics
+func (s *Server) evictHistoryLocked() {
+	finished := 0
+	for _, id := range s.order {
+		if s.jobs[id].finished {
+			finished++
+		}
+	}
+	order := s.order[:0]
+	for _, id := range s.order {
+		job := s.jobs[id]
+		if job.finished && finished > s.maxHistory {
+			finished--
+			delete(s.jobs, id)
+			RemoveLabelValuesWithTaskInMetrics(job.conf.Labels)
+			continue
+		}
+		order = append(order, id)
+	}
+	s.order = order
+}
+
+func (s *Server) jobStatus(job *serverJob) *JobStatus {
+	s.mu.Lock()
+	status := &JobStatus{ID: job.id, State: DumpStatePending, SubmitTime: job.submitTime}
+	dumper, canceled, finished, err, finishTime := job.dumper, job.canceled, job.finished, job.err, job.finishTime
+	s.mu.Unlock()
+	if dumper != nil {
+		status.Progress = dumper.Status()
+		status.State = status.Progress.State
+	}
+	if finished {
+		status.FinishTime = &finishTime
+		switch {
+		case canceled:
+			status.State = DumpStateCanceled
+		case err != nil:
+			// the dump may fail before it runs
+			if status.State != DumpStateCanceled {
+				status.State = DumpStateFailed
+			}
+			status.Error = err.Error()
+		default:
+			status.State = DumpStateFinished
+		}
+	}
+	return status
+}
+
+func (s *Server) listJobs() []*JobStatus {
+	s.mu.Lock()
+	jobs := make([]*serverJob, 0, len(s.order))
+	for _, id := range s.order {
+		jobs = append(jobs, s.jobs[id])
+	}
+	s.mu.Unlock()
+	statuses := make([]*JobStatus, 0, len(jobs))
+	for _, job := range jobs {
+		statuses = append(statuses, s.jobStatus(job))
+	}
+	return statuses
+}
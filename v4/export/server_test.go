@@ -0,0 +1,133 @@
+// Copyright 2021 PingCAP, Inc. Licensed under Apache-2.0.
+
+package export
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pingcap/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServerJobs(t *testing.T) {
+	t.Parallel()
+
+	s := NewServer(context.Background(), appLogger.Logger, 1, "")
+	confs := make(chan *Config, 2)
+	s.newDumper = func(ctx context.Context, conf *Config) (*Dumper, error) {
+		confs <- conf
+		if conf.Host == "unreachable" {
+			return nil, errors.New("connection refused")
+		}
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	router := http.NewServeMux()
+	s.registerAPI(router)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	request := func(method, path, body string, v interface{}) int {
+		req, err := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+		require.NoError(t, err)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		if v != nil {
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(v))
+		}
+		return resp.StatusCode
+	}
+
+	require.Equal(t, http.StatusBadRequest, request(http.MethodPost, "/api/v1/jobs", "{", nil))
+	require.Equal(t, http.StatusBadRequest, request(http.MethodPost, "/api/v1/jobs", `{"filter": ["!"]}`, nil))
+
+	status := &JobStatus{}
+	code := request(http.MethodPost, "/api/v1/jobs",
+		`{"config": {"Host": "10.0.0.1", "Port": 4000, "Threads": 8}, "password": "secret", "filter": ["db.*"]}`, status)
+	require.Equal(t, http.StatusCreated, code)
+	require.Equal(t, "1", status.ID)
+	require.Equal(t, DumpStatePending, status.State)
+	conf := <-confs
+	require.Equal(t, "10.0.0.1", conf.Host)
+	require.Equal(t, 4000, conf.Port)
+	require.Equal(t, 8, conf.Threads)
+	require.Equal(t, "secret", conf.Password)
+	require.Equal(t, "", conf.StatusAddr)
+	require.Equal(t, prometheus.Labels{ServerJobLabel: "1"}, conf.Labels)
+	require.True(t, conf.TableFilter.MatchTable("db", "t"))
+	require.False(t, conf.TableFilter.MatchTable("other", "t"))
+
+	// the job can only be canceled before its dumper is created
+	require.Equal(t, http.StatusConflict, request(http.MethodPost, "/api/v1/jobs/1/pause", "", nil))
+	require.Equal(t, http.StatusNotFound, request(http.MethodGet, "/api/v1/jobs/2", "", nil))
+	require.Equal(t, http.StatusOK, request(http.MethodPost, "/api/v1/jobs/1/cancel", "", nil))
+	require.Eventually(t, func() bool {
+		request(http.MethodGet, "/api/v1/jobs/1", "", status)
+		return status.FinishTime != nil
+	}, time.Second, 10*time.Millisecond)
+	require.Equal(t, DumpStateCanceled, status.State)
+
+	request(http.MethodPost, "/api/v1/jobs", `{"config": {"Host": "unreachable"}}`, status)
+	require.Equal(t, "2", status.ID)
+	<-confs
+	var statuses []*JobStatus
+	require.Eventually(t, func() bool {
+		request(http.MethodGet, "/api/v1/jobs", "", &statuses)
+		return len(statuses) == 1 && statuses[0].FinishTime != nil
+	}, time.Second, 10*time.Millisecond)
+	// only the last finished job is kept
+	require.Equal(t, "2", statuses[0].ID)
+	require.Equal(t, DumpStateFailed, statuses[0].State)
+	require.Equal(t, "connection refused", statuses[0].Error)
+	require.Equal(t, http.StatusNotFound, request(http.MethodGet, "/api/v1/jobs/1", "", nil))
+}
+
+func TestServerToken(t *testing.T) {
+	t.Parallel()
+
+	for addr, loopback := range map[string]bool{
+		"127.0.0.1:8281": true,
+		"localhost:8281": true,
+		"[::1]:8281":     true,
+		":8281":          false,
+		"0.0.0.0:8281":   false,
+		"10.0.0.1:8281":  false,
+		"invalid":        false,
+	} {
+		require.Equal(t, loopback, isLoopbackAddr(addr), addr)
+	}
+	err := NewServer(context.Background(), appLogger.Logger, 1, "").Run(":0")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "set a token")
+
+	s := NewServer(context.Background(), appLogger.Logger, 1, "secret")
+	router := http.NewServeMux()
+	s.registerAPI(router)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	request := func(path, token string) int {
+		req, err := http.NewRequest(http.MethodGet, server.URL+path, nil)
+		require.NoError(t, err)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+		return resp.StatusCode
+	}
+	require.Equal(t, http.StatusUnauthorized, request("/api/v1/jobs", ""))
+	require.Equal(t, http.StatusUnauthorized, request("/api/v1/jobs", "wrong"))
+	require.Equal(t, http.StatusUnauthorized, request("/api/v1/jobs/1", ""))
+	require.Equal(t, http.StatusOK, request("/api/v1/jobs", "secret"))
+	require.Equal(t, http.StatusNotFound, request("/api/v1/jobs/1", "secret"))
+}
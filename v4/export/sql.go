@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"io"
 	"net/url"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -145,6 +146,273 @@ func ShowCreateView(db *sql.Conn, database, view string) (createFakeTableSQL str
 	return createTableSQL.String(), createViewSQL.String(), nil
 }
 
+// ShowCreateSequence constructs the create sequence SQL for a specified sequence of TiDB, and sets the sequence
+// to its current value so that the values allocated before are not allocated again
+// returns (createSequenceSQL, error)
+func ShowCreateSequence(db *sql.Conn, database, sequence string) (string, error) {
+	var oneRow [2]string
+	handleOneRow := func(rows *sql.Rows) error {
+		return rows.Scan(&oneRow[0], &oneRow[1])
+	}
+	var createSequenceSQL strings.Builder
+	fmt.Fprintf(&createSequenceSQL, "DROP SEQUENCE IF EXISTS `%s`;\n", escapeString(sequence))
+	query := fmt.Sprintf("SHOW CREATE SEQUENCE `%s`.`%s`", escapeString(database), escapeString(sequence))
+	if err := simpleQuery(db, query, handleOneRow); err != nil {
+		return "", errors.Annotatef(err, "sql: %s", query)
+	}
+	createSequenceSQL.WriteString(oneRow[1])
+	createSequenceSQL.WriteString(";\n")
+
+	// The result for `show table next_row_id` SQL
+	// mysql> show table seq next_row_id;
+	// +---------+------------+-------------+--------------------+-------------+
+	// | DB_NAME | TABLE_NAME | COLUMN_NAME | NEXT_GLOBAL_ROW_ID | ID_TYPE     |
+	// +---------+------------+-------------+--------------------+-------------+
+	// | test    | seq        | _tidb_rowid |                  1 | _TIDB_ROWID |
+	// | test    | seq        |             |               1001 | SEQUENCE    |
+	// +---------+------------+-------------+--------------------+-------------+
+	// the sequence isn't transactional, so its value read now is never less than the value at the snapshot
+	query = fmt.Sprintf("SHOW TABLE `%s`.`%s` NEXT_ROW_ID", escapeString(database), escapeString(sequence))
+	rows, err := db.QueryContext(context.Background(), query)
+	if err != nil {
+		return "", errors.Annotatef(err, "sql: %s", query)
+	}
+	results, err := GetSpecifiedColumnValuesAndClose(rows, "NEXT_GLOBAL_ROW_ID", "ID_TYPE")
+	if err != nil {
+		return "", errors.Annotatef(err, "sql: %s", query)
+	}
+	for _, oneRow := range results {
+		nextGlobalRowID, idType := oneRow[0], oneRow[1]
+		if idType == TableTypeSequenceStr {
+			fmt.Fprintf(&createSequenceSQL, "SELECT SETVAL(`%s`,%s);\n", escapeString(sequence), nextGlobalRowID)
+		}
+	}
+	return createSequenceSQL.String(), nil
+}
+
+// ListPlacementPolicies lists the placement policies of TiDB referenced by the databases, tables and partitions
+// in tables. It returns nothing if TiDB doesn't support placement policies.
+func ListPlacementPolicies(db *sql.Conn, tables DatabaseTables) ([]string, error) {
+	policies := make(map[string]struct{})
+	handleOneRow := func(rows *sql.Rows) error {
+		var schema, table, policy string
+		if err := rows.Scan(&schema, &table, &policy); err != nil {
+			return errors.Trace(err)
+		}
+		dbTables, ok := tables[schema]
+		if !ok {
+			return nil
+		}
+		if table == "" {
+			policies[policy] = struct{}{}
+			return nil
+		}
+		for _, tbl := range dbTables {
+			if tbl.Name == table {
+				policies[policy] = struct{}{}
+				break
+			}
+		}
+		return nil
+	}
+	queries := []string{
+		"SELECT SCHEMA_NAME, '', TIDB_PLACEMENT_POLICY_NAME FROM INFORMATION_SCHEMA.SCHEMATA WHERE TIDB_PLACEMENT_POLICY_NAME IS NOT NULL",
+		"SELECT TABLE_SCHEMA, TABLE_NAME, TIDB_PLACEMENT_POLICY_NAME FROM INFORMATION_SCHEMA.TABLES WHERE TIDB_PLACEMENT_POLICY_NAME IS NOT NULL",
+		"SELECT TABLE_SCHEMA, TABLE_NAME, TIDB_PLACEMENT_POLICY_NAME FROM INFORMATION_SCHEMA.PARTITIONS WHERE TIDB_PLACEMENT_POLICY_NAME IS NOT NULL",
+	}
+	for _, query := range queries {
+		if err := simpleQuery(db, query, handleOneRow); err != nil {
+			if isUnknownColumnErr(err) {
+				return nil, nil
+			}
+			return nil, err
+		}
+	}
+	names := make([]string, 0, len(policies))
+	for policy := range policies {
+		names = append(names, policy)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// ShowCreatePlacementPolicy constructs the create placement policy SQL for a specified placement policy of TiDB.
+// The placement policies are shared by all the databases, so the SQL doesn't replace an existing one.
+// returns (createPolicySQL, error)
+func ShowCreatePlacementPolicy(db *sql.Conn, policy string) (string, error) {
+	var oneRow [2]string
+	handleOneRow := func(rows *sql.Rows) error {
+		return rows.Scan(&oneRow[0], &oneRow[1])
+	}
+	query := fmt.Sprintf("SHOW CREATE PLACEMENT POLICY `%s`", escapeString(policy))
+	if err := simpleQuery(db, query, handleOneRow); err != nil {
+		return "", errors.Annotatef(err, "sql: %s", query)
+	}
+	const createPolicy = "CREATE PLACEMENT POLICY "
+	if !strings.HasPrefix(oneRow[1], createPolicy) {
+		return "", errors.Errorf("unexpected result of %s: %s", query, oneRow[1])
+	}
+	return "CREATE PLACEMENT POLICY IF NOT EXISTS " + strings.TrimPrefix(oneRow[1], createPolicy) + ";\n", nil
+}
+
+// ListResourceGroups lists the resource groups of TiDB except the default one. The resource groups are referenced
+// by the users and the sessions instead of the tables, so all of them are listed.
+func ListResourceGroups(db *sql.Conn) ([]string, error) {
+	var names []string
+	handleOneRow := func(rows *sql.Rows) error {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return errors.Trace(err)
+		}
+		names = append(names, name)
+		return nil
+	}
+	query := "SELECT NAME FROM INFORMATION_SCHEMA.RESOURCE_GROUPS WHERE NAME != 'default' ORDER BY NAME"
+	if err := simpleQuery(db, query, handleOneRow); err != nil {
+		// TiDB without resource groups
+		if isNoSuchTableErr(err) {
+			return nil, nil
+		}
+		return nil, errors.Annotatef(err, "sql: %s", query)
+	}
+	return names, nil
+}
+
+// ShowCreateResourceGroup constructs the create resource group SQL for a specified resource group of TiDB.
+// The resource groups are shared by all the databases, so the SQL doesn't replace an existing one.
+// returns (createResourceGroupSQL, error)
+func ShowCreateResourceGroup(db *sql.Conn, resourceGroup string) (string, error) {
+	var oneRow [2]string
+	handleOneRow := func(rows *sql.Rows) error {
+		return rows.Scan(&oneRow[0], &oneRow[1])
+	}
+	query := fmt.Sprintf("SHOW CREATE RESOURCE GROUP `%s`", escapeString(resourceGroup))
+	if err := simpleQuery(db, query, handleOneRow); err != nil {
+		return "", errors.Annotatef(err, "sql: %s", query)
+	}
+	const createResourceGroup = "CREATE RESOURCE GROUP "
+	if !strings.HasPrefix(oneRow[1], createResourceGroup) {
+		return "", errors.Errorf("unexpected result of %s: %s", query, oneRow[1])
+	}
+	return "CREATE RESOURCE GROUP IF NOT EXISTS " + strings.TrimPrefix(oneRow[1], createResourceGroup) + ";\n", nil
+}
+
+func isUnknownColumnErr(err error) bool {
+	mysqlErr, ok := errors.Cause(err).(*mysql.MySQLError)
+	return ok && mysqlErr.Number == 1054
+}
+
+// ListRoutines lists the stored procedures and functions of a database
+// returns the [name, type] of every routine, type is PROCEDURE or FUNCTION
+func ListRoutines(db *sql.Conn, database string) ([][2]string, error) {
+	var routines [][2]string
+	handleOneRow := func(rows *sql.Rows) error {
+		var routine [2]string
+		if err := rows.Scan(&routine[0], &routine[1]); err != nil {
+			return errors.Trace(err)
+		}
+		routines = append(routines, routine)
+		return nil
+	}
+	query := "SELECT ROUTINE_NAME, ROUTINE_TYPE FROM INFORMATION_SCHEMA.ROUTINES WHERE ROUTINE_SCHEMA = ? ORDER BY ROUTINE_TYPE, ROUTINE_NAME"
+	err := simpleQueryWithArgs(db, handleOneRow, query, database)
+	return routines, err
+}
+
+// ListTriggers lists the triggers of a database
+// returns the trigger names of every table in the order of creation
+func ListTriggers(db *sql.Conn, database string) (map[string][]string, error) {
+	triggers := make(map[string][]string)
+	handleOneRow := func(rows *sql.Rows) error {
+		var table, trigger string
+		if err := rows.Scan(&table, &trigger); err != nil {
+			return errors.Trace(err)
+		}
+		triggers[table] = append(triggers[table], trigger)
+		return nil
+	}
+	// triggers with the same timing and event are fired in ACTION_ORDER, so they are created in this order
+	query := "SELECT EVENT_OBJECT_TABLE, TRIGGER_NAME FROM INFORMATION_SCHEMA.TRIGGERS WHERE TRIGGER_SCHEMA = ? " +
+		"ORDER BY EVENT_OBJECT_TABLE, ACTION_TIMING, EVENT_MANIPULATION, ACTION_ORDER"
+	err := simpleQueryWithArgs(db, handleOneRow, query, database)
+	return triggers, err
+}
+
+// ListEvents lists the event names of a database
+func ListEvents(db *sql.Conn, database string) ([]string, error) {
+	events := oneStrColumnTable{}
+	query := "SELECT EVENT_NAME FROM INFORMATION_SCHEMA.EVENTS WHERE EVENT_SCHEMA = ? ORDER BY EVENT_NAME"
+	err := simpleQueryWithArgs(db, events.handleOneRow, query, database)
+	return events.data, err
+}
+
+// ShowCreateRoutine constructs the create SQL for a specified stored procedure or function
+// returns (createRoutineSQL, error)
+func ShowCreateRoutine(db *sql.Conn, database, routine, routineType string) (string, error) {
+	return showCreateObject(db, database, routine, routineType, "CREATE "+routineType)
+}
+
+// ShowCreateTrigger constructs the create SQL for a specified trigger
+// returns (createTriggerSQL, error)
+func ShowCreateTrigger(db *sql.Conn, database, trigger string) (string, error) {
+	return showCreateObject(db, database, trigger, "TRIGGER", "SQL ORIGINAL STATEMENT")
+}
+
+// ShowCreateEvent constructs the create SQL for a specified event
+// returns (createEventSQL, error)
+func ShowCreateEvent(db *sql.Conn, database, event string) (string, error) {
+	return showCreateObject(db, database, event, "EVENT", "CREATE EVENT")
+}
+
+// showCreateObject builds the SQLs which recreate a procedure, function, trigger or event from the result of
+// SHOW CREATE, with the sql_mode, charset and time zone (of an event) used to create it. The body may contain
+// semicolons, so the CREATE statement is delimited by ";;" like mysqldump, and the SQLs are meant to be run by
+// the mysql client.
+func showCreateObject(db *sql.Conn, database, name, objectType, createColumn string) (string, error) {
+	query := fmt.Sprintf("SHOW CREATE %s `%s`.`%s`", objectType, escapeString(database), escapeString(name))
+	rows, err := db.QueryContext(context.Background(), query)
+	if err != nil {
+		return "", errors.Annotatef(err, "sql: %s", query)
+	}
+	// The result for `show create procedure` SQL
+	// mysql> show create procedure p;
+	// +-----------+----------+---------------------------------------------------------+----------------------+----------------------+--------------------+
+	// | Procedure | sql_mode | Create Procedure                                        | character_set_client | collation_connection | Database Collation |
+	// +-----------+----------+---------------------------------------------------------+----------------------+----------------------+--------------------+
+	// | p         |          | CREATE DEFINER=`root`@`%` PROCEDURE `p`() BEGIN ... END | utf8mb4              | utf8mb4_general_ci   | utf8mb4_general_ci |
+	// +-----------+----------+---------------------------------------------------------+----------------------+----------------------+--------------------+
+	// the results of triggers and events are similar, and the result of events has an extra time_zone column
+	results, err := GetSpecifiedColumnValuesAndClose(rows, "SQL_MODE", createColumn, "CHARACTER_SET_CLIENT", "COLLATION_CONNECTION", "TIME_ZONE")
+	if err != nil {
+		return "", errors.Annotatef(err, "sql: %s", query)
+	}
+	if len(results) == 0 || results[0][1] == "" {
+		return "", errors.Errorf("can't get the definition of %s `%s`.`%s`, please check the privileges of the user",
+			strings.ToLower(objectType), database, name)
+	}
+	sqlMode, createSQL, characterSet, collationConnection, timeZone := results[0][0], results[0][1], results[0][2], results[0][3], results[0][4]
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "DROP %s IF EXISTS `%s`;\n", objectType, escapeString(name))
+	SetCharset(&b, characterSet, collationConnection)
+	b.WriteString("SET @PREV_SQL_MODE=@@SQL_MODE;\n")
+	fmt.Fprintf(&b, "SET SQL_MODE='%s';\n", sqlMode)
+	if timeZone != "" {
+		b.WriteString("SET @PREV_TIME_ZONE=@@TIME_ZONE;\n")
+		fmt.Fprintf(&b, "SET TIME_ZONE='%s';\n", timeZone)
+	}
+	b.WriteString("DELIMITER ;;\n")
+	b.WriteString(createSQL)
+	b.WriteString(";;\n")
+	b.WriteString("DELIMITER ;\n")
+	if timeZone != "" {
+		b.WriteString("SET TIME_ZONE=@PREV_TIME_ZONE;\n")
+	}
+	b.WriteString("SET SQL_MODE=@PREV_SQL_MODE;\n")
+	RestoreCharset(&b)
+	return b.String(), nil
+}
+
 // SetCharset builds the set charset SQLs
 func SetCharset(w *strings.Builder, characterSet, collationConnection string) {
 	w.WriteString("SET @PREV_CHARACTER_SET_CLIENT=@@CHARACTER_SET_CLIENT;\n")
@@ -240,6 +508,23 @@ func ListAllDatabasesTables(tctx *tcontext.Context, db *sql.Conn, databaseNames
 		for _, tableType = range tableTypes {
 			selectedTableType[tableType] = struct{}{}
 		}
+		// the sequences of TiDB have no engine like views in SHOW TABLE STATUS
+		sequences := make(map[string]map[string]struct{})
+		if _, ok := selectedTableType[TableTypeSequence]; ok {
+			query := fmt.Sprintf("SELECT TABLE_SCHEMA,TABLE_NAME FROM INFORMATION_SCHEMA.TABLES WHERE TABLE_TYPE='%s'", TableTypeSequence)
+			if err = simpleQuery(db, query, func(rows *sql.Rows) error {
+				if err2 := rows.Scan(&schema, &table); err2 != nil {
+					return errors.Trace(err2)
+				}
+				if sequences[schema] == nil {
+					sequences[schema] = make(map[string]struct{})
+				}
+				sequences[schema][table] = struct{}{}
+				return nil
+			}); err != nil {
+				return nil, errors.Annotatef(err, "sql: %s", query)
+			}
+		}
 		for _, schema = range databaseNames {
 			dbTables[schema] = make([]*TableInfo, 0)
 			query := fmt.Sprintf(queryTemplate, escapeString(schema))
@@ -262,7 +547,9 @@ func ListAllDatabasesTables(tctx *tcontext.Context, db *sql.Conn, databaseNames
 					avgRowLength = 0
 				}
 				tableType = TableTypeBase
-				if engine == "" && (comment == "" || comment == TableTypeViewStr) {
+				if _, ok := sequences[schema][table]; ok {
+					tableType = TableTypeSequence
+				} else if engine == "" && (comment == "" || comment == TableTypeViewStr) {
 					tableType = TableTypeView
 				} else if engine == "" {
 					tctx.L().Warn("Invalid table without engine found", zap.String("database", schema), zap.String("table", table))
@@ -336,17 +623,26 @@ func buildSelectQuery(database, table, fields, partition, where, orderByClause s
 }
 
 func buildOrderByClause(conf *Config, db *sql.Conn, database, table string, hasImplicitRowID bool) (string, error) { // revive:disable-line:flag-parameter
+	keys, err := buildOrderByKeys(conf, db, database, table, hasImplicitRowID)
+	if err != nil {
+		return "", err
+	}
+	return buildOrderByClauseString(keys), nil
+}
+
+// buildOrderByKeys returns the columns the rows of table are ordered by, which are _tidb_rowid or the primary key
+func buildOrderByKeys(conf *Config, db *sql.Conn, database, table string, hasImplicitRowID bool) ([]string, error) { // revive:disable-line:flag-parameter
 	if !conf.SortByPk {
-		return "", nil
+		return nil, nil
 	}
 	if hasImplicitRowID {
-		return orderByTiDBRowID, nil
+		return []string{"_tidb_rowid"}, nil
 	}
 	cols, err := GetPrimaryKeyColumns(db, database, table)
 	if err != nil {
-		return "", errors.Trace(err)
+		return nil, errors.Trace(err)
 	}
-	return buildOrderByClauseString(cols), nil
+	return cols, nil
 }
 
 // SelectTiDBRowID checks whether this table has _tidb_rowid column
@@ -709,34 +1005,39 @@ func createConnWithConsistency(ctx context.Context, db *sql.DB, repeatableRead b
 }
 
 // buildSelectField returns the selecting fields' string(joined by comma(`,`)),
-// and the number of writable fields.
-func buildSelectField(db *sql.Conn, dbName, tableName string, completeInsert bool) (string, int, error) { // revive:disable-line:flag-parameter
+// the number of writable fields, and the names of the BIGINT UNSIGNED columns
+// which go-sql-driver reports as BIGINT.
+func buildSelectField(db *sql.Conn, dbName, tableName string, completeInsert bool) (string, int, map[string]struct{}, error) { // revive:disable-line:flag-parameter
 	query := fmt.Sprintf("SHOW COLUMNS FROM `%s`.`%s`", escapeString(dbName), escapeString(tableName))
 	rows, err := db.QueryContext(context.Background(), query)
 	if err != nil {
-		return "", 0, errors.Annotatef(err, "sql: %s", query)
+		return "", 0, nil, errors.Annotatef(err, "sql: %s", query)
 	}
 	defer rows.Close()
 	availableFields := make([]string, 0)
+	unsignedBigints := make(map[string]struct{})
 
 	hasGenerateColumn := false
-	results, err := GetSpecifiedColumnValuesAndClose(rows, "FIELD", "EXTRA")
+	results, err := GetSpecifiedColumnValuesAndClose(rows, "FIELD", "TYPE", "EXTRA")
 	if err != nil {
-		return "", 0, errors.Annotatef(err, "sql: %s", query)
+		return "", 0, nil, errors.Annotatef(err, "sql: %s", query)
 	}
 	for _, oneRow := range results {
-		fieldName, extra := oneRow[0], oneRow[1]
+		fieldName, tp, extra := oneRow[0], strings.ToLower(oneRow[1]), oneRow[2]
 		switch extra {
 		case "STORED GENERATED", "VIRTUAL GENERATED":
 			hasGenerateColumn = true
 			continue
 		}
 		availableFields = append(availableFields, wrapBackTicks(escapeString(fieldName)))
+		if strings.HasPrefix(tp, "bigint") && strings.Contains(tp, "unsigned") {
+			unsignedBigints[fieldName] = struct{}{}
+		}
 	}
 	if completeInsert || hasGenerateColumn {
-		return strings.Join(availableFields, ","), len(availableFields), nil
+		return strings.Join(availableFields, ","), len(availableFields), unsignedBigints, nil
 	}
-	return "*", len(availableFields), nil
+	return "*", len(availableFields), unsignedBigints, nil
 }
 
 func buildWhereClauses(handleColNames []string, handleVals [][]string) []string {
@@ -11,6 +11,7 @@ import (
 	"io"
 	"os"
 	"path"
+	"regexp"
 	"runtime"
 	"strconv"
 	"strings"
@@ -21,6 +22,7 @@ import (
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/coreos/go-semver/semver"
+	"github.com/go-sql-driver/mysql"
 	"github.com/pingcap/errors"
 )
 
@@ -115,7 +117,7 @@ func TestBuildSelectAllQuery(t *testing.T) {
 		WillReturnRows(sqlmock.NewRows([]string{"Field", "Type", "Null", "Key", "Default", "Extra"}).
 			AddRow("id", "int(11)", "NO", "PRI", nil, ""))
 
-	selectedField, _, err := buildSelectField(conn, database, table, false)
+	selectedField, _, _, err := buildSelectField(conn, database, table, false)
 	require.NoError(t, err)
 
 	q := buildSelectQuery(database, table, selectedField, "", "", orderByClause)
@@ -132,7 +134,7 @@ func TestBuildSelectAllQuery(t *testing.T) {
 		WillReturnRows(sqlmock.NewRows([]string{"Field", "Type", "Null", "Key", "Default", "Extra"}).
 			AddRow("id", "int(11)", "NO", "PRI", nil, ""))
 
-	selectedField, _, err = buildSelectField(conn, database, table, false)
+	selectedField, _, _, err = buildSelectField(conn, database, table, false)
 	require.NoError(t, err)
 
 	q = buildSelectQuery(database, table, selectedField, "", "", orderByClause)
@@ -157,7 +159,7 @@ func TestBuildSelectAllQuery(t *testing.T) {
 			WillReturnRows(sqlmock.NewRows([]string{"Field", "Type", "Null", "Key", "Default", "Extra"}).
 				AddRow("id", "int(11)", "NO", "PRI", nil, ""))
 
-		selectedField, _, err = buildSelectField(conn, database, table, false)
+		selectedField, _, _, err = buildSelectField(conn, database, table, false)
 		require.NoError(t, err, comment)
 
 		q = buildSelectQuery(database, table, selectedField, "", "", orderByClause)
@@ -183,7 +185,7 @@ func TestBuildSelectAllQuery(t *testing.T) {
 			WillReturnRows(sqlmock.NewRows([]string{"Field", "Type", "Null", "Key", "Default", "Extra"}).
 				AddRow("id", "int(11)", "NO", "PRI", nil, ""))
 
-		selectedField, _, err = buildSelectField(conn, "test", "t", false)
+		selectedField, _, _, err = buildSelectField(conn, "test", "t", false)
 		require.NoError(t, err, comment)
 
 		q := buildSelectQuery(database, table, selectedField, "", "", orderByClause)
@@ -204,7 +206,7 @@ func TestBuildSelectAllQuery(t *testing.T) {
 			WillReturnRows(sqlmock.NewRows([]string{"Field", "Type", "Null", "Key", "Default", "Extra"}).
 				AddRow("id", "int(11)", "NO", "PRI", nil, ""))
 
-		selectedField, _, err := buildSelectField(conn, "test", "t", false)
+		selectedField, _, _, err := buildSelectField(conn, "test", "t", false)
 		require.NoError(t, err, comment)
 
 		q := buildSelectQuery(database, table, selectedField, "", "", "")
@@ -294,7 +296,7 @@ func TestBuildSelectField(t *testing.T) {
 		WillReturnRows(sqlmock.NewRows([]string{"Field", "Type", "Null", "Key", "Default", "Extra"}).
 			AddRow("id", "int(11)", "NO", "PRI", nil, ""))
 
-	selectedField, _, err := buildSelectField(conn, "test", "t", false)
+	selectedField, _, _, err := buildSelectField(conn, "test", "t", false)
 	require.Equal(t, "*", selectedField)
 	require.NoError(t, err)
 	require.NoError(t, mock.ExpectationsWereMet())
@@ -306,7 +308,7 @@ func TestBuildSelectField(t *testing.T) {
 			AddRow("name", "varchar(12)", "NO", "", nil, "").
 			AddRow("quo`te", "varchar(12)", "NO", "UNI", nil, ""))
 
-	selectedField, _, err = buildSelectField(conn, "test", "t", true)
+	selectedField, _, _, err = buildSelectField(conn, "test", "t", true)
 	require.Equal(t, "`id`,`name`,`quo``te`", selectedField)
 	require.NoError(t, err)
 	require.NoError(t, mock.ExpectationsWereMet())
@@ -319,7 +321,7 @@ func TestBuildSelectField(t *testing.T) {
 			AddRow("quo`te", "varchar(12)", "NO", "UNI", nil, "").
 			AddRow("generated", "varchar(12)", "NO", "", nil, "VIRTUAL GENERATED"))
 
-	selectedField, _, err = buildSelectField(conn, "test", "t", false)
+	selectedField, _, _, err = buildSelectField(conn, "test", "t", false)
 	require.Equal(t, "`id`,`name`,`quo``te`", selectedField)
 	require.NoError(t, err)
 	require.NoError(t, mock.ExpectationsWereMet())
@@ -382,6 +384,186 @@ func TestShowCreateView(t *testing.T) {
 	require.NoError(t, mock.ExpectationsWereMet())
 }
 
+func TestShowCreateRoutineTriggerEvent(t *testing.T) {
+	t.Parallel()
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, db.Close())
+	}()
+
+	conn, err := db.Conn(context.Background())
+	require.NoError(t, err)
+
+	setCharset := "SET @PREV_CHARACTER_SET_CLIENT=@@CHARACTER_SET_CLIENT;\nSET @PREV_CHARACTER_SET_RESULTS=@@CHARACTER_SET_RESULTS;\nSET @PREV_COLLATION_CONNECTION=@@COLLATION_CONNECTION;\nSET character_set_client = utf8mb4;\nSET character_set_results = utf8mb4;\nSET collation_connection = utf8mb4_general_ci;\n"
+	restoreCharset := "SET character_set_client = @PREV_CHARACTER_SET_CLIENT;\nSET character_set_results = @PREV_CHARACTER_SET_RESULTS;\nSET collation_connection = @PREV_COLLATION_CONNECTION;\n"
+
+	mock.ExpectQuery("SHOW CREATE PROCEDURE `test`.`p`").
+		WillReturnRows(sqlmock.NewRows([]string{"Procedure", "sql_mode", "Create Procedure", "character_set_client", "collation_connection", "Database Collation"}).
+			AddRow("p", "STRICT_TRANS_TABLES", "CREATE DEFINER=`root`@`%` PROCEDURE `p`()\nBEGIN\n  SELECT 1;\nEND", "utf8mb4", "utf8mb4_general_ci", "utf8mb4_general_ci"))
+	createSQL, err := ShowCreateRoutine(conn, "test", "p", "PROCEDURE")
+	require.NoError(t, err)
+	require.Equal(t, "DROP PROCEDURE IF EXISTS `p`;\n"+setCharset+
+		"SET @PREV_SQL_MODE=@@SQL_MODE;\nSET SQL_MODE='STRICT_TRANS_TABLES';\n"+
+		"DELIMITER ;;\nCREATE DEFINER=`root`@`%` PROCEDURE `p`()\nBEGIN\n  SELECT 1;\nEND;;\nDELIMITER ;\n"+
+		"SET SQL_MODE=@PREV_SQL_MODE;\n"+restoreCharset, createSQL)
+
+	mock.ExpectQuery("SHOW CREATE TRIGGER `test`.`tr`").
+		WillReturnRows(sqlmock.NewRows([]string{"Trigger", "sql_mode", "SQL Original Statement", "character_set_client", "collation_connection", "Database Collation", "Created"}).
+			AddRow("tr", "", "CREATE DEFINER=`root`@`%` TRIGGER `tr` BEFORE INSERT ON `t` FOR EACH ROW SET NEW.a = 1", "utf8mb4", "utf8mb4_general_ci", "utf8mb4_general_ci", "2021-06-01 00:00:00.00"))
+	createSQL, err = ShowCreateTrigger(conn, "test", "tr")
+	require.NoError(t, err)
+	require.Equal(t, "DROP TRIGGER IF EXISTS `tr`;\n"+setCharset+
+		"SET @PREV_SQL_MODE=@@SQL_MODE;\nSET SQL_MODE='';\n"+
+		"DELIMITER ;;\nCREATE DEFINER=`root`@`%` TRIGGER `tr` BEFORE INSERT ON `t` FOR EACH ROW SET NEW.a = 1;;\nDELIMITER ;\n"+
+		"SET SQL_MODE=@PREV_SQL_MODE;\n"+restoreCharset, createSQL)
+
+	mock.ExpectQuery("SHOW CREATE EVENT `test`.`e`").
+		WillReturnRows(sqlmock.NewRows([]string{"Event", "sql_mode", "time_zone", "Create Event", "character_set_client", "collation_connection", "Database Collation"}).
+			AddRow("e", "", "SYSTEM", "CREATE DEFINER=`root`@`%` EVENT `e` ON SCHEDULE EVERY 1 DAY DO DELETE FROM `t`", "utf8mb4", "utf8mb4_general_ci", "utf8mb4_general_ci"))
+	createSQL, err = ShowCreateEvent(conn, "test", "e")
+	require.NoError(t, err)
+	require.Equal(t, "DROP EVENT IF EXISTS `e`;\n"+setCharset+
+		"SET @PREV_SQL_MODE=@@SQL_MODE;\nSET SQL_MODE='';\nSET @PREV_TIME_ZONE=@@TIME_ZONE;\nSET TIME_ZONE='SYSTEM';\n"+
+		"DELIMITER ;;\nCREATE DEFINER=`root`@`%` EVENT `e` ON SCHEDULE EVERY 1 DAY DO DELETE FROM `t`;;\nDELIMITER ;\n"+
+		"SET TIME_ZONE=@PREV_TIME_ZONE;\nSET SQL_MODE=@PREV_SQL_MODE;\n"+restoreCharset, createSQL)
+
+	// the definition is NULL if the user has no privilege to see it
+	mock.ExpectQuery("SHOW CREATE FUNCTION `test`.`f`").
+		WillReturnRows(sqlmock.NewRows([]string{"Function", "sql_mode", "Create Function", "character_set_client", "collation_connection", "Database Collation"}).
+			AddRow("f", "", nil, "utf8mb4", "utf8mb4_general_ci", "utf8mb4_general_ci"))
+	_, err = ShowCreateRoutine(conn, "test", "f", "FUNCTION")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "can't get the definition of function `test`.`f`")
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestListTriggers(t *testing.T) {
+	t.Parallel()
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, db.Close())
+	}()
+
+	conn, err := db.Conn(context.Background())
+	require.NoError(t, err)
+
+	mock.ExpectQuery("SELECT EVENT_OBJECT_TABLE, TRIGGER_NAME FROM INFORMATION_SCHEMA.TRIGGERS").
+		WithArgs("test").
+		WillReturnRows(sqlmock.NewRows([]string{"EVENT_OBJECT_TABLE", "TRIGGER_NAME"}).
+			AddRow("t1", "tr2").AddRow("t1", "tr1").AddRow("t2", "tr3"))
+	triggers, err := ListTriggers(conn, "test")
+	require.NoError(t, err)
+	require.Equal(t, map[string][]string{"t1": {"tr2", "tr1"}, "t2": {"tr3"}}, triggers)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestShowCreateSequence(t *testing.T) {
+	t.Parallel()
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, db.Close())
+	}()
+
+	conn, err := db.Conn(context.Background())
+	require.NoError(t, err)
+
+	mock.ExpectQuery("SHOW CREATE SEQUENCE `test`.`s`").
+		WillReturnRows(sqlmock.NewRows([]string{"Sequence", "Create Sequence"}).
+			AddRow("s", "CREATE SEQUENCE `s` start with 1 minvalue 1 maxvalue 9223372036854775806 increment by 1 cache 1000 nocycle ENGINE=InnoDB"))
+	mock.ExpectQuery("SHOW TABLE `test`.`s` NEXT_ROW_ID").
+		WillReturnRows(sqlmock.NewRows([]string{"DB_NAME", "TABLE_NAME", "COLUMN_NAME", "NEXT_GLOBAL_ROW_ID", "ID_TYPE"}).
+			AddRow("test", "s", "_tidb_rowid", "1", "_TIDB_ROWID").
+			AddRow("test", "s", nil, "1001", "SEQUENCE"))
+	createSQL, err := ShowCreateSequence(conn, "test", "s")
+	require.NoError(t, err)
+	require.Equal(t, "DROP SEQUENCE IF EXISTS `s`;\n"+
+		"CREATE SEQUENCE `s` start with 1 minvalue 1 maxvalue 9223372036854775806 increment by 1 cache 1000 nocycle ENGINE=InnoDB;\n"+
+		"SELECT SETVAL(`s`,1001);\n", createSQL)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestListPlacementPolicies(t *testing.T) {
+	t.Parallel()
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, db.Close())
+	}()
+
+	conn, err := db.Conn(context.Background())
+	require.NoError(t, err)
+
+	tables := NewDatabaseTables().
+		AppendTables("db1", []string{"t1", "t2"}, []uint64{0, 0}).
+		AppendTables("db2", []string{"t3"}, []uint64{0})
+	columns := []string{"SCHEMA", "TABLE", "TIDB_PLACEMENT_POLICY_NAME"}
+	mock.ExpectQuery("SELECT SCHEMA_NAME, '', TIDB_PLACEMENT_POLICY_NAME FROM INFORMATION_SCHEMA.SCHEMATA").
+		WillReturnRows(sqlmock.NewRows(columns).AddRow("db2", "", "p_db").AddRow("db3", "", "p_skipped"))
+	mock.ExpectQuery("SELECT TABLE_SCHEMA, TABLE_NAME, TIDB_PLACEMENT_POLICY_NAME FROM INFORMATION_SCHEMA.TABLES").
+		WillReturnRows(sqlmock.NewRows(columns).AddRow("db1", "t2", "p_table").AddRow("db1", "t9", "p_skipped"))
+	mock.ExpectQuery("SELECT TABLE_SCHEMA, TABLE_NAME, TIDB_PLACEMENT_POLICY_NAME FROM INFORMATION_SCHEMA.PARTITIONS").
+		WillReturnRows(sqlmock.NewRows(columns).AddRow("db1", "t1", "p_partition").AddRow("db1", "t2", "p_table"))
+	policies, err := ListPlacementPolicies(conn, tables)
+	require.NoError(t, err)
+	require.Equal(t, []string{"p_db", "p_partition", "p_table"}, policies)
+
+	// TiDB without placement policies
+	mock.ExpectQuery("SELECT SCHEMA_NAME, '', TIDB_PLACEMENT_POLICY_NAME FROM INFORMATION_SCHEMA.SCHEMATA").
+		WillReturnError(&mysql.MySQLError{Number: 1054, Message: "Unknown column 'TIDB_PLACEMENT_POLICY_NAME' in 'where clause'"})
+	policies, err = ListPlacementPolicies(conn, tables)
+	require.NoError(t, err)
+	require.Empty(t, policies)
+
+	mock.ExpectQuery("SHOW CREATE PLACEMENT POLICY `p_db`").
+		WillReturnRows(sqlmock.NewRows([]string{"Policy", "Create Policy"}).
+			AddRow("p_db", "CREATE PLACEMENT POLICY `p_db` PRIMARY_REGION=\"us-east-1\" REGIONS=\"us-east-1,us-west-1\""))
+	createSQL, err := ShowCreatePlacementPolicy(conn, "p_db")
+	require.NoError(t, err)
+	require.Equal(t, "CREATE PLACEMENT POLICY IF NOT EXISTS `p_db` PRIMARY_REGION=\"us-east-1\" REGIONS=\"us-east-1,us-west-1\";\n", createSQL)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestListResourceGroups(t *testing.T) {
+	t.Parallel()
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, db.Close())
+	}()
+
+	conn, err := db.Conn(context.Background())
+	require.NoError(t, err)
+
+	query := "SELECT NAME FROM INFORMATION_SCHEMA.RESOURCE_GROUPS WHERE NAME != 'default' ORDER BY NAME"
+	mock.ExpectQuery(query).WillReturnRows(sqlmock.NewRows([]string{"NAME"}).AddRow("rg1").AddRow("rg2"))
+	resourceGroups, err := ListResourceGroups(conn)
+	require.NoError(t, err)
+	require.Equal(t, []string{"rg1", "rg2"}, resourceGroups)
+
+	// TiDB without resource groups
+	mock.ExpectQuery(query).
+		WillReturnError(&mysql.MySQLError{Number: 1146, Message: "Table 'information_schema.resource_groups' doesn't exist"})
+	resourceGroups, err = ListResourceGroups(conn)
+	require.NoError(t, err)
+	require.Empty(t, resourceGroups)
+
+	mock.ExpectQuery("SHOW CREATE RESOURCE GROUP `rg1`").
+		WillReturnRows(sqlmock.NewRows([]string{"Name", "Create Resource Group"}).
+			AddRow("rg1", "CREATE RESOURCE GROUP `rg1` RU_PER_SEC=1000 PRIORITY=HIGH"))
+	createSQL, err := ShowCreateResourceGroup(conn, "rg1")
+	require.NoError(t, err)
+	require.Equal(t, "CREATE RESOURCE GROUP IF NOT EXISTS `rg1` RU_PER_SEC=1000 PRIORITY=HIGH;\n", createSQL)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
 func TestGetSuitableRows(t *testing.T) {
 	t.Parallel()
 
@@ -730,6 +912,7 @@ func TestBuildTableSampleQueries(t *testing.T) {
 					}
 					mock.ExpectQuery(fmt.Sprintf("SHOW INDEX FROM `%s`.`%s`", database, table)).WillReturnRows(rows)
 					mock.ExpectQuery("SHOW INDEX FROM").WillReturnRows(sqlmock.NewRows(showIndexHeaders))
+					mock.ExpectQuery("SHOW INDEX FROM").WillReturnRows(sqlmock.NewRows(showIndexHeaders))
 				} else {
 					d.conf.Rows = 200000
 					mock.ExpectQuery("EXPLAIN SELECT `_tidb_rowid`").
@@ -748,9 +931,7 @@ func TestBuildTableSampleQueries(t *testing.T) {
 				require.True(t, ok)
 				require.Equal(t, i, taskTableData.ChunkIndex)
 
-				data, ok := taskTableData.Data.(*tableData)
-				require.True(t, ok)
-				require.Equal(t, query, data.query)
+				require.Equal(t, []string{query}, tableDataQueries(taskTableData.Data))
 			}
 
 			// special case, no value found
@@ -828,6 +1009,202 @@ func TestBuildPartitionClauses(t *testing.T) {
 	}
 }
 
+func TestConcurrentDumpTableByPrimaryKey(t *testing.T) {
+	t.Parallel()
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, db.Close())
+	}()
+
+	conn, err := db.Conn(context.Background())
+	require.NoError(t, err)
+
+	tctx, cancel := tcontext.Background().WithLogger(appLogger).WithCancel()
+	d := &Dumper{
+		tctx:      tctx,
+		conf:      DefaultConfig(),
+		cancelCtx: cancel,
+	}
+	d.conf.Rows = 2
+	d.conf.ServerInfo = ServerInfo{ServerType: ServerTypeMySQL}
+
+	meta := &mockTableIR{
+		dbName:        database,
+		tblName:       table,
+		selectedField: "*",
+		colNames:      []string{"id", "name", "v"},
+		colTypes:      []string{"VARCHAR", "VARCHAR", "INT"},
+		specCmt: []string{
+			"/*!40101 SET NAMES binary*/;",
+		},
+	}
+	pkRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows(showIndexHeaders).
+			AddRow(table, 0, "PRIMARY", 1, "id", "A", 0, nil, nil, "", "BTREE", "", "").
+			AddRow(table, 0, "PRIMARY", 2, "name", "A", 0, nil, nil, "", "BTREE", "", "")
+	}
+	// buildOrderByClause, pickupPossibleField and GetPrimaryKeyColumns
+	for i := 0; i < 3; i++ {
+		mock.ExpectQuery("SHOW INDEX FROM").WillReturnRows(pkRows())
+	}
+	mock.ExpectQuery("EXPLAIN SELECT \\* FROM `foo`.`bar`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "select_type", "table", "type", "rows"}).
+			AddRow(1, "SIMPLE", table, "index", 5))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT `id`,`name` FROM `foo`.`bar` ORDER BY `id`,`name` LIMIT 1 OFFSET 2")).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow("b", "x"))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT `id`,`name` FROM `foo`.`bar` WHERE `id`>'b' or(`id`='b' and `name`>'x')  ORDER BY `id`,`name` LIMIT 1 OFFSET 1")).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow("d", "y"))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT `id`,`name` FROM `foo`.`bar` WHERE `id`>'d' or(`id`='d' and `name`>'y')  ORDER BY `id`,`name` LIMIT 1 OFFSET 1")).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}))
+
+	taskChan := make(chan Task, 128)
+	require.NoError(t, d.concurrentDumpTable(tctx, conn, meta, taskChan))
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	expectedWhereClauses := []string{
+		"`id`<'b' or(`id`='b' and `name`<'x')",
+		"(`id`>'b' and `id`<'d')or(`id`='b' and(`name`>='x'))or(`id`='d' and(`name`<'y'))",
+		"`id`>'d' or(`id`='d' and `name`>='y')",
+	}
+	require.Len(t, taskChan, len(expectedWhereClauses))
+	for i, w := range expectedWhereClauses {
+		task := (<-taskChan).(*TaskTableData)
+		require.Equal(t, i, task.ChunkIndex)
+		require.Equal(t, len(expectedWhereClauses), task.TotalChunks)
+		query := buildSelectQuery(database, table, "*", "", buildWhereCondition(d.conf, w), "ORDER BY `id`,`name`")
+		require.Equal(t, []string{query}, tableDataQueries(task.Data))
+	}
+}
+
+func TestConcurrentDumpTableBySampling(t *testing.T) {
+	t.Parallel()
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, db.Close())
+	}()
+
+	conn, err := db.Conn(context.Background())
+	require.NoError(t, err)
+
+	tctx, cancel := tcontext.Background().WithLogger(appLogger).WithCancel()
+	d := &Dumper{
+		tctx:      tctx,
+		conf:      DefaultConfig(),
+		cancelCtx: cancel,
+	}
+	d.conf.Rows = 2
+	d.conf.ChunkSplitMode = ChunkSplitModeSample
+	d.conf.ServerInfo = ServerInfo{ServerType: ServerTypeMySQL}
+
+	meta := &mockTableIR{
+		dbName:        database,
+		tblName:       table,
+		selectedField: "*",
+		colNames:      []string{"id", "v"},
+		colTypes:      []string{"BIGINT", "VARCHAR"},
+		specCmt: []string{
+			"/*!40101 SET NAMES binary*/;",
+		},
+	}
+	// buildOrderByClause and pickupPossibleField
+	for i := 0; i < 2; i++ {
+		mock.ExpectQuery("SHOW INDEX FROM").WillReturnRows(sqlmock.NewRows(showIndexHeaders).
+			AddRow(table, 0, "PRIMARY", 1, "id", "A", 0, nil, nil, "", "BTREE", "", ""))
+	}
+	mock.ExpectQuery("EXPLAIN SELECT `id` FROM `foo`.`bar`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "select_type", "table", "type", "rows"}).
+			AddRow(1, "SIMPLE", table, "index", 5))
+	// the ids are sparse, equal steps would produce lots of empty chunks
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT `id` FROM `foo`.`bar` WHERE `id` IS NOT NULL  ORDER BY `id` LIMIT 1 OFFSET 2")).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(100))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT `id` FROM `foo`.`bar` WHERE `id`>100  ORDER BY `id` LIMIT 1 OFFSET 1")).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1000000000))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT `id` FROM `foo`.`bar` WHERE `id`>1000000000  ORDER BY `id` LIMIT 1 OFFSET 1")).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	taskChan := make(chan Task, 128)
+	require.NoError(t, d.concurrentDumpTable(tctx, conn, meta, taskChan))
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	expectedWhereClauses := []string{
+		"`id` IS NULL OR (`id`<100)",
+		"`id`>=100 and `id`<1000000000",
+		"`id`>=1000000000",
+	}
+	require.Len(t, taskChan, len(expectedWhereClauses))
+	for i, w := range expectedWhereClauses {
+		task := (<-taskChan).(*TaskTableData)
+		require.Equal(t, i, task.ChunkIndex)
+		require.Equal(t, len(expectedWhereClauses), task.TotalChunks)
+		query := buildSelectQuery(database, table, "*", "", buildWhereCondition(d.conf, w), "ORDER BY `id`")
+		require.Equal(t, []string{query}, tableDataQueries(task.Data))
+	}
+
+	_, err = ParseChunkSplitMode("unknown")
+	require.Error(t, err)
+}
+
+func TestSelectKeyBoundariesWithDuplicatedValues(t *testing.T) {
+	t.Parallel()
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, db.Close())
+	}()
+	conn, err := db.Conn(context.Background())
+	require.NoError(t, err)
+
+	tctx := tcontext.Background().WithLogger(appLogger)
+	conf := DefaultConfig()
+	conf.Rows = 2
+	meta := &mockTableIR{dbName: database, tblName: table}
+	// the values of `v` are 1,1,1,1,1,2,2,3, the second boundary must not be 1 again
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT `v` FROM `foo`.`bar` WHERE `v` IS NOT NULL  ORDER BY `v` LIMIT 1 OFFSET 2")).
+		WillReturnRows(sqlmock.NewRows([]string{"v"}).AddRow(1))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT `v` FROM `foo`.`bar` WHERE `v`>1  ORDER BY `v` LIMIT 1 OFFSET 1")).
+		WillReturnRows(sqlmock.NewRows([]string{"v"}).AddRow(2))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT `v` FROM `foo`.`bar` WHERE `v`>2  ORDER BY `v` LIMIT 1 OFFSET 1")).
+		WillReturnRows(sqlmock.NewRows([]string{"v"}))
+
+	vals, err := selectKeyBoundaries(tctx, conn, conf, meta, []string{"v"}, []string{"BIGINT"}, "`v` IS NOT NULL")
+	require.NoError(t, err)
+	require.Equal(t, [][]string{{"1"}, {"2"}}, vals)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSelectKeyBoundariesEscapeBackslash(t *testing.T) {
+	t.Parallel()
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, db.Close())
+	}()
+	conn, err := db.Conn(context.Background())
+	require.NoError(t, err)
+
+	tctx := tcontext.Background().WithLogger(appLogger)
+	conf := DefaultConfig()
+	conf.Rows = 2
+	// the boundaries are sent back to the server, --escape-backslash=false only affects the dumped files
+	conf.EscapeBackslash = false
+	meta := &mockTableIR{dbName: database, tblName: table}
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT `v` FROM `foo`.`bar`  ORDER BY `v` LIMIT 1 OFFSET 2")).
+		WillReturnRows(sqlmock.NewRows([]string{"v"}).AddRow(`a\x`))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT `v` FROM `foo`.`bar` WHERE `v`>'a\\\\x'  ORDER BY `v` LIMIT 1 OFFSET 1")).
+		WillReturnRows(sqlmock.NewRows([]string{"v"}))
+
+	vals, err := selectKeyBoundaries(tctx, conn, conf, meta, []string{"v"}, []string{"VARCHAR"}, "")
+	require.NoError(t, err)
+	require.Equal(t, [][]string{{`'a\\x'`}}, vals)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
 func TestBuildWhereCondition(t *testing.T) {
 	t.Parallel()
 
@@ -1009,6 +1386,7 @@ func TestBuildRegionQueriesWithoutPartition(t *testing.T) {
 			}
 			mock.ExpectQuery(fmt.Sprintf("SHOW INDEX FROM `%s`.`%s`", database, table)).WillReturnRows(rows)
 			mock.ExpectQuery("SHOW INDEX FROM").WillReturnRows(sqlmock.NewRows(showIndexHeaders))
+			mock.ExpectQuery("SHOW INDEX FROM").WillReturnRows(sqlmock.NewRows(showIndexHeaders))
 		}
 		require.NoError(t, d.concurrentDumpTable(tctx, conn, meta, taskChan))
 		require.NoError(t, mock.ExpectationsWereMet())
@@ -1019,9 +1397,7 @@ func TestBuildRegionQueriesWithoutPartition(t *testing.T) {
 			taskTableData, ok := task.(*TaskTableData)
 			require.True(t, ok)
 			require.Equal(t, i, taskTableData.ChunkIndex)
-			data, ok := taskTableData.Data.(*tableData)
-			require.True(t, ok)
-			require.Equal(t, query, data.query)
+			require.Equal(t, []string{query}, tableDataQueries(taskTableData.Data))
 		}
 	}
 }
@@ -1221,9 +1597,7 @@ func TestBuildRegionQueriesWithPartitions(t *testing.T) {
 				taskTableData, ok := task.(*TaskTableData)
 				require.True(t, ok)
 				require.Equal(t, chunkIdx, taskTableData.ChunkIndex)
-				data, ok := taskTableData.Data.(*tableData)
-				require.True(t, ok)
-				require.Equal(t, query, data.query)
+				require.Equal(t, []string{query}, tableDataQueries(taskTableData.Data))
 				chunkIdx++
 			}
 		}
@@ -1531,9 +1905,7 @@ func TestBuildVersion3RegionQueries(t *testing.T) {
 			require.True(t, ok)
 			require.Equal(t, chunkIdx, taskTableData.ChunkIndex)
 
-			data, ok := taskTableData.Data.(*tableData)
-			require.True(t, ok)
-			require.Equal(t, query, data.query)
+			require.Equal(t, []string{query}, tableDataQueries(taskTableData.Data))
 
 			chunkIdx++
 		}
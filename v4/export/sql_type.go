@@ -5,13 +5,17 @@ package export
 import (
 	"bytes"
 	"database/sql"
+	"encoding/base64"
 	"fmt"
+	"sync"
+	"unicode/utf8"
 )
 
 var colTypeRowReceiverMap = map[string]func() RowReceiverStringer{}
 
 var (
 	nullValue           = "NULL"
+	jsonNullValue       = "null"
 	quotationMark       = []byte{'\''}
 	twoQuotationMarks   = []byte{'\'', '\''}
 	doubleQuotationMark = []byte{'"'}
@@ -23,7 +27,15 @@ var (
 // https://github.com/pingcap/parser/blob/8e8ed7927bde11c4cf0967afc5e05ab5aeb14cc7/types/etc.go#L44-70
 // The second is to be the receiver of select row type, which will use sql.DB's rows.DatabaseTypeName(), which is from
 // https://github.com/go-sql-driver/mysql/blob/v1.5.0/fields.go#L17-97
+// initColTypeRowReceiverMap is called by every Dump, the maps are filled only once because the dumps of
+// dumpling server may run concurrently
 func initColTypeRowReceiverMap() {
+	initColTypeRowReceiverMapOnce.Do(fillColTypeRowReceiverMap)
+}
+
+var initColTypeRowReceiverMapOnce sync.Once
+
+func fillColTypeRowReceiverMap() {
 	dataTypeStringArr := []string{
 		"CHAR", "NCHAR", "VARCHAR", "NVARCHAR", "CHARACTER", "VARCHARACTER",
 		"TIMESTAMP", "DATETIME", "DATE", "TIME", "YEAR", "SQL_TSI_YEAR",
@@ -37,6 +49,7 @@ func initColTypeRowReceiverMap() {
 		"FLOAT", "REAL", "DOUBLE", "DOUBLE PRECISION",
 		"DECIMAL", "NUMERIC", "FIXED",
 		"BOOL", "BOOLEAN",
+		"UNSIGNED TINYINT", "UNSIGNED SMALLINT", "UNSIGNED MEDIUMINT", "UNSIGNED INT", "UNSIGNED BIGINT",
 	}
 
 	dataTypeBinArr := []string{
@@ -154,6 +167,50 @@ func escapeCSV(s []byte, bf *bytes.Buffer, escapeBackslash bool, opt *csvOption)
 	}
 }
 
+const hexDigits = "0123456789abcdef"
+
+// escapeJSON writes s to bf as the content of a JSON string. Invalid UTF-8 sequences are
+// replaced by U+FFFD so that the output is always valid JSON.
+func escapeJSON(s []byte, bf *bytes.Buffer) {
+	last := 0
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c >= utf8.RuneSelf {
+			r, size := utf8.DecodeRune(s[i:])
+			if r == utf8.RuneError && size == 1 {
+				bf.Write(s[last:i])
+				bf.WriteString(`\ufffd`)
+				last = i + size
+			}
+			i += size
+			continue
+		}
+		if c >= 0x20 && c != '"' && c != '\\' {
+			i++
+			continue
+		}
+		bf.Write(s[last:i])
+		switch c {
+		case '"', '\\':
+			bf.WriteByte('\\')
+			bf.WriteByte(c)
+		case '\n':
+			bf.WriteString(`\n`)
+		case '\r':
+			bf.WriteString(`\r`)
+		case '\t':
+			bf.WriteString(`\t`)
+		default:
+			bf.WriteString(`\u00`)
+			bf.WriteByte(hexDigits[c>>4])
+			bf.WriteByte(hexDigits[c&0xF])
+		}
+		i++
+		last = i
+	}
+	bf.Write(s[last:])
+}
+
 // SQLTypeStringMaker returns a SQLTypeString
 func SQLTypeStringMaker() RowReceiverStringer {
 	return &SQLTypeString{}
@@ -224,6 +281,36 @@ func (r RowReceiverArr) WriteToBufferInCsv(bf *bytes.Buffer, escapeBackslash boo
 	}
 }
 
+// WriteToBufferInJSON writes the row as a JSON object. keys are the quoted and escaped column names.
+func (r RowReceiverArr) WriteToBufferInJSON(bf *bytes.Buffer, keys [][]byte) {
+	bf.WriteByte('{')
+	for i, receiver := range r.receivers {
+		bf.Write(keys[i])
+		bf.WriteByte(':')
+		if js, ok := receiver.(JSONStringer); ok {
+			js.WriteToBufferInJSON(bf)
+		} else {
+			writeToBufferInJSONString(bf, receiver)
+		}
+		if i != len(r.receivers)-1 {
+			bf.WriteByte(',')
+		}
+	}
+	bf.WriteByte('}')
+}
+
+// jsonStringCsvOption writes the csv values without delimiters and NULL as an empty string
+var jsonStringCsvOption = &csvOption{separator: []byte(","), delimiter: []byte{}}
+
+// writeToBufferInJSONString writes the csv value of a Stringer not implementing JSONStringer as a JSON string
+func writeToBufferInJSONString(bf *bytes.Buffer, s Stringer) {
+	var value bytes.Buffer
+	s.WriteToBufferInCsv(&value, false, jsonStringCsvOption)
+	bf.Write(doubleQuotationMark)
+	escapeJSON(value.Bytes(), bf)
+	bf.Write(doubleQuotationMark)
+}
+
 // SQLTypeNumber implements RowReceiverStringer which represents numeric type columns in database
 type SQLTypeNumber struct {
 	SQLTypeString
@@ -247,6 +334,15 @@ func (s SQLTypeNumber) WriteToBufferInCsv(bf *bytes.Buffer, _ bool, opt *csvOpti
 	}
 }
 
+// WriteToBufferInJSON implements JSONStringer.WriteToBufferInJSON
+func (s SQLTypeNumber) WriteToBufferInJSON(bf *bytes.Buffer) {
+	if s.RawBytes != nil {
+		bf.Write(s.RawBytes)
+	} else {
+		bf.WriteString(jsonNullValue)
+	}
+}
+
 // SQLTypeString implements RowReceiverStringer which represents string type columns in database
 type SQLTypeString struct {
 	sql.RawBytes
@@ -279,6 +375,17 @@ func (s *SQLTypeString) WriteToBufferInCsv(bf *bytes.Buffer, escapeBackslash boo
 	}
 }
 
+// WriteToBufferInJSON implements JSONStringer.WriteToBufferInJSON
+func (s *SQLTypeString) WriteToBufferInJSON(bf *bytes.Buffer) {
+	if s.RawBytes != nil {
+		bf.Write(doubleQuotationMark)
+		escapeJSON(s.RawBytes, bf)
+		bf.Write(doubleQuotationMark)
+	} else {
+		bf.WriteString(jsonNullValue)
+	}
+}
+
 // SQLTypeBytes implements RowReceiverStringer which represents bytes type columns in database
 type SQLTypeBytes struct {
 	sql.RawBytes
@@ -308,3 +415,16 @@ func (s *SQLTypeBytes) WriteToBufferInCsv(bf *bytes.Buffer, escapeBackslash bool
 		bf.WriteString(opt.nullValue)
 	}
 }
+
+// WriteToBufferInJSON implements JSONStringer.WriteToBufferInJSON. The value is encoded in base64.
+func (s *SQLTypeBytes) WriteToBufferInJSON(bf *bytes.Buffer) {
+	if s.RawBytes != nil {
+		bf.Write(doubleQuotationMark)
+		enc := base64.NewEncoder(base64.StdEncoding, bf)
+		_, _ = enc.Write(s.RawBytes)
+		_ = enc.Close()
+		bf.Write(doubleQuotationMark)
+	} else {
+		bf.WriteString(jsonNullValue)
+	}
+}
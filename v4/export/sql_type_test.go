@@ -55,3 +55,45 @@ func TestEscape(t *testing.T) {
 	escapeCSV(str, &bf, false, opt)
 	require.Equal(t, expectedStrWithoutDelimiter, bf.String())
 }
+
+func TestEscapeJSON(t *testing.T) {
+	t.Parallel()
+
+	var bf bytes.Buffer
+	escapeJSON([]byte("a\"b\\c\nd\te\x01f\xffg中文"), &bf)
+	require.Equal(t, `a\"b\\c\nd\te\u0001f\ufffdg中文`, bf.String())
+
+	bf.Reset()
+	keys := buildJSONKeys([]string{"a\"b"}, 2)
+	require.Equal(t, [][]byte{[]byte(`"a\"b"`), []byte(`"col1"`)}, keys)
+}
+
+// csvOnlyStringer is a Stringer implemented outside dumpling which doesn't implement JSONStringer
+type csvOnlyStringer struct {
+	s SQLTypeString
+}
+
+func (s *csvOnlyStringer) BindAddress(arg []interface{}) {
+	s.s.BindAddress(arg)
+}
+
+func (s *csvOnlyStringer) WriteToBuffer(bf *bytes.Buffer, escapeBackslash bool) {
+	s.s.WriteToBuffer(bf, escapeBackslash)
+}
+
+func (s *csvOnlyStringer) WriteToBufferInCsv(bf *bytes.Buffer, escapeBackslash bool, opt *csvOption) {
+	s.s.WriteToBufferInCsv(bf, escapeBackslash, opt)
+}
+
+func TestWriteToBufferInJSONWithoutJSONStringer(t *testing.T) {
+	t.Parallel()
+
+	var bf bytes.Buffer
+	row := RowReceiverArr{receivers: []RowReceiverStringer{
+		&SQLTypeNumber{SQLTypeString{RawBytes: []byte("1")}},
+		&csvOnlyStringer{SQLTypeString{RawBytes: []byte("2")}},
+		&csvOnlyStringer{SQLTypeString{RawBytes: []byte("a\"b")}},
+	}}
+	row.WriteToBufferInJSON(&bf, buildJSONKeys([]string{"id", "num", "name"}, 3))
+	require.Equal(t, `{"id":1,"num":"2","name":"a\"b"}`, bf.String())
+}
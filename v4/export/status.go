@@ -4,6 +4,9 @@ package export
 
 import (
 	"fmt"
+	"math"
+	"sort"
+	"sync"
 	"time"
 
 	tcontext "github.com/pingcap/dumpling/v4/context"
@@ -59,3 +62,239 @@ func calculateTableCount(m DatabaseTables) int {
 	}
 	return cnt
 }
+
+// The states of a dump reported by the status API
+const (
+	DumpStatePending  = "pending"
+	DumpStateRunning  = "running"
+	DumpStatePaused   = "paused"
+	DumpStateCanceled = "canceled"
+	DumpStateFailed   = "failed"
+	DumpStateFinished = "finished"
+)
+
+// pauserUser is the pauser of the throttle when the dump is paused by Dumper.Pause
+const pauserUser = "user"
+
+// TableProgress is the progress of dumping the data of a table
+type TableProgress struct {
+	Database    string `json:"database"`
+	Table       string `json:"table"`
+	ChunksDone  int    `json:"chunks-done"`
+	ChunksTotal int    `json:"chunks-total"`
+	Rows        uint64 `json:"rows"`
+	Bytes       int64  `json:"bytes"`
+}
+
+// WriterProgress is the task a writer is working on
+type WriterProgress struct {
+	ID        int64      `json:"id"`
+	Task      string     `json:"task,omitempty"`
+	StartTime *time.Time `json:"start-time,omitempty"`
+}
+
+// DumpStatus is the progress of a dump, it's the response of GET /api/v1/status
+type DumpStatus struct {
+	State             string           `json:"state"`
+	Error             string           `json:"error,omitempty"`
+	StartTime         time.Time        `json:"start-time"`
+	FinishedTables    int              `json:"finished-tables"`
+	TotalTables       int              `json:"total-tables"`
+	FinishedRows      uint64           `json:"finished-rows"`
+	EstimateTotalRows uint64           `json:"estimate-total-rows"`
+	FinishedBytes     uint64           `json:"finished-bytes"`
+	ETA               string           `json:"eta,omitempty"`
+	Threads           int              `json:"threads"`
+	Tables            []TableProgress  `json:"tables"`
+	Writers           []WriterProgress `json:"writers"`
+}
+
+// progressTracker collects the progress of every table and writer. All the methods are safe to be called on a nil
+// progressTracker.
+type progressTracker struct {
+	mu        sync.Mutex
+	state     string
+	err       error
+	startTime time.Time
+	tables    map[tableKey]*TableProgress
+	// tableOrder keeps the tables in the order of dispatching
+	tableOrder []tableKey
+	writers    map[int64]*WriterProgress
+}
+
+func newProgressTracker() *progressTracker {
+	return &progressTracker{
+		state:   DumpStatePending,
+		tables:  make(map[tableKey]*TableProgress),
+		writers: make(map[int64]*WriterProgress),
+	}
+}
+
+func (p *progressTracker) start() {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	// the dump may be paused or canceled before it starts
+	if p.state == DumpStatePending {
+		p.state = DumpStateRunning
+	}
+	p.startTime = time.Now()
+}
+
+// finish records the result of the dump, the state isn't changed if it's already canceled
+func (p *progressTracker) finish(err error) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	switch {
+	case p.state == DumpStateCanceled:
+	case err != nil:
+		p.state, p.err = DumpStateFailed, err
+	default:
+		p.state = DumpStateFinished
+	}
+}
+
+// setState changes the state of a running dump, returns false if the dump isn't running
+func (p *progressTracker) setState(state string) bool {
+	if p == nil {
+		return false
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	switch p.state {
+	case DumpStatePending, DumpStateRunning, DumpStatePaused:
+		p.state = state
+		return true
+	default:
+		return false
+	}
+}
+
+func (p *progressTracker) tableLocked(meta TableMeta) *TableProgress {
+	key := tableKey{database: meta.DatabaseName(), table: meta.TableName()}
+	tp, ok := p.tables[key]
+	if !ok {
+		tp = &TableProgress{Database: key.database, Table: key.table}
+		p.tables[key] = tp
+		p.tableOrder = append(p.tableOrder, key)
+	}
+	return tp
+}
+
+// addChunk records a table chunk dispatched to the writers
+func (p *progressTracker) addChunk(t *TaskTableData) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	tp := p.tableLocked(t.Meta)
+	// the split tails of work stealing are beyond TotalChunks
+	if tp.ChunksTotal < t.TotalChunks {
+		tp.ChunksTotal = t.TotalChunks
+	}
+	if tp.ChunksTotal <= t.ChunkIndex {
+		tp.ChunksTotal = t.ChunkIndex + 1
+	}
+}
+
+func (p *progressTracker) addWriter(writerID int64) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.writers[writerID] = &WriterProgress{ID: writerID}
+}
+
+func (p *progressTracker) removeWriter(writerID int64) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.writers, writerID)
+}
+
+func (p *progressTracker) startTask(writerID int64, task Task) {
+	if p == nil {
+		return
+	}
+	if t, ok := task.(*TaskTableData); ok {
+		p.addChunk(t)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	p.writers[writerID] = &WriterProgress{ID: writerID, Task: task.Brief(), StartTime: &now}
+}
+
+func (p *progressTracker) finishTask(writerID int64, task Task) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.writers[writerID] = &WriterProgress{ID: writerID}
+	t, ok := task.(*TaskTableData)
+	if !ok {
+		return
+	}
+	tp := p.tableLocked(t.Meta)
+	tp.ChunksDone++
+	for _, f := range t.Files {
+		tp.Rows += f.Rows
+		tp.Bytes += f.Size
+	}
+}
+
+func (p *progressTracker) status(conf *Config) *DumpStatus {
+	status := &DumpStatus{
+		State:             DumpStatePending,
+		TotalTables:       calculateTableCount(conf.Tables),
+		FinishedTables:    int(readMetric(ReadCounter(finishedTablesCounter, conf.Labels))),
+		FinishedRows:      uint64(readMetric(ReadGauge(finishedRowsGauge, conf.Labels))),
+		EstimateTotalRows: uint64(readMetric(ReadCounter(estimateTotalRowsCounter, conf.Labels))),
+		FinishedBytes:     uint64(readMetric(ReadGauge(finishedSizeGauge, conf.Labels))),
+		Tables:            []TableProgress{},
+		Writers:           []WriterProgress{},
+	}
+	if p == nil {
+		return status
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	status.State, status.StartTime = p.state, p.startTime
+	if p.err != nil {
+		status.Error = p.err.Error()
+	}
+	for _, key := range p.tableOrder {
+		status.Tables = append(status.Tables, *p.tables[key])
+	}
+	for _, wp := range p.writers {
+		status.Writers = append(status.Writers, *wp)
+	}
+	sort.Slice(status.Writers, func(i, j int) bool {
+		return status.Writers[i].ID < status.Writers[j].ID
+	})
+	if (p.state == DumpStateRunning || p.state == DumpStatePaused) &&
+		status.FinishedRows > 0 && status.EstimateTotalRows > status.FinishedRows {
+		elapsed := time.Since(p.startTime)
+		eta := time.Duration(float64(elapsed) * float64(status.EstimateTotalRows-status.FinishedRows) / float64(status.FinishedRows))
+		status.ETA = eta.Round(time.Second).String()
+	}
+	return status
+}
+
+// readMetric returns 0 for the metrics which aren't registered
+func readMetric(v float64) float64 {
+	if math.IsNaN(v) {
+		return 0
+	}
+	return v
+}
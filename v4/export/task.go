@@ -2,9 +2,13 @@
 
 package export
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
-// Task is a file dump task for dumpling, it could either be dumping database/table/view metadata, table data
+// Task is a file dump task for dumpling, it could either be dumping database/table/view/routine/trigger/event/
+// sequence/placement policy metadata, table data
 type Task interface {
 	// Brief is the brief for a dumping task
 	Brief() string
@@ -34,6 +38,53 @@ type TaskViewMeta struct {
 	CreateViewSQL  string
 }
 
+// TaskRoutineMeta is a dumping stored procedure or function metadata task
+type TaskRoutineMeta struct {
+	Task
+	DatabaseName     string
+	RoutineName      string
+	RoutineType      string
+	CreateRoutineSQL string
+}
+
+// TaskTriggerMeta is a dumping metadata task of all the triggers of a table
+type TaskTriggerMeta struct {
+	Task
+	DatabaseName     string
+	TableName        string
+	CreateTriggerSQL string
+}
+
+// TaskEventMeta is a dumping event metadata task
+type TaskEventMeta struct {
+	Task
+	DatabaseName   string
+	EventName      string
+	CreateEventSQL string
+}
+
+// TaskSequenceMeta is a dumping sequence metadata task
+type TaskSequenceMeta struct {
+	Task
+	DatabaseName      string
+	SequenceName      string
+	CreateSequenceSQL string
+}
+
+// TaskPolicyMeta is a dumping placement policy metadata task
+type TaskPolicyMeta struct {
+	Task
+	PolicyName      string
+	CreatePolicySQL string
+}
+
+// TaskResourceGroupMeta is a dumping resource group metadata task
+type TaskResourceGroupMeta struct {
+	Task
+	ResourceGroupName      string
+	CreateResourceGroupSQL string
+}
+
 // TaskTableData is a dumping table data task
 type TaskTableData struct {
 	Task
@@ -41,6 +92,10 @@ type TaskTableData struct {
 	Data        TableDataIR
 	ChunkIndex  int
 	TotalChunks int
+	// Files are the files written for this chunk, they are filled after the task is finished
+	Files []*ManifestFile
+	// Checksum is the row checksum of this chunk, it's filled after the task is finished if row checksum is enabled
+	Checksum *rowChecksum
 }
 
 // NewTaskDatabaseMeta returns a new dumping database metadata task
@@ -70,6 +125,59 @@ func NewTaskViewMeta(dbName, tblName, createTableSQL, createViewSQL string) *Tas
 	}
 }
 
+// NewTaskRoutineMeta returns a new dumping stored procedure or function metadata task
+func NewTaskRoutineMeta(dbName, routineName, routineType, createSQL string) *TaskRoutineMeta {
+	return &TaskRoutineMeta{
+		DatabaseName:     dbName,
+		RoutineName:      routineName,
+		RoutineType:      routineType,
+		CreateRoutineSQL: createSQL,
+	}
+}
+
+// NewTaskTriggerMeta returns a new dumping triggers metadata task
+func NewTaskTriggerMeta(dbName, tblName, createSQL string) *TaskTriggerMeta {
+	return &TaskTriggerMeta{
+		DatabaseName:     dbName,
+		TableName:        tblName,
+		CreateTriggerSQL: createSQL,
+	}
+}
+
+// NewTaskEventMeta returns a new dumping event metadata task
+func NewTaskEventMeta(dbName, eventName, createSQL string) *TaskEventMeta {
+	return &TaskEventMeta{
+		DatabaseName:   dbName,
+		EventName:      eventName,
+		CreateEventSQL: createSQL,
+	}
+}
+
+// NewTaskSequenceMeta returns a new dumping sequence metadata task
+func NewTaskSequenceMeta(dbName, sequenceName, createSQL string) *TaskSequenceMeta {
+	return &TaskSequenceMeta{
+		DatabaseName:      dbName,
+		SequenceName:      sequenceName,
+		CreateSequenceSQL: createSQL,
+	}
+}
+
+// NewTaskPolicyMeta returns a new dumping placement policy metadata task
+func NewTaskPolicyMeta(policyName, createSQL string) *TaskPolicyMeta {
+	return &TaskPolicyMeta{
+		PolicyName:      policyName,
+		CreatePolicySQL: createSQL,
+	}
+}
+
+// NewTaskResourceGroupMeta returns a new dumping resource group metadata task
+func NewTaskResourceGroupMeta(resourceGroupName, createSQL string) *TaskResourceGroupMeta {
+	return &TaskResourceGroupMeta{
+		ResourceGroupName:      resourceGroupName,
+		CreateResourceGroupSQL: createSQL,
+	}
+}
+
 // NewTaskTableData returns a new dumping table data task
 func NewTaskTableData(meta TableMeta, data TableDataIR, currentChunk, totalChunks int) *TaskTableData {
 	return &TaskTableData{
@@ -95,6 +203,36 @@ func (t *TaskViewMeta) Brief() string {
 	return fmt.Sprintf("meta of view '%s'.'%s'", t.DatabaseName, t.ViewName)
 }
 
+// Brief implements task.Brief
+func (t *TaskRoutineMeta) Brief() string {
+	return fmt.Sprintf("meta of %s '%s'.'%s'", strings.ToLower(t.RoutineType), t.DatabaseName, t.RoutineName)
+}
+
+// Brief implements task.Brief
+func (t *TaskTriggerMeta) Brief() string {
+	return fmt.Sprintf("meta of triggers of table '%s'.'%s'", t.DatabaseName, t.TableName)
+}
+
+// Brief implements task.Brief
+func (t *TaskEventMeta) Brief() string {
+	return fmt.Sprintf("meta of event '%s'.'%s'", t.DatabaseName, t.EventName)
+}
+
+// Brief implements task.Brief
+func (t *TaskSequenceMeta) Brief() string {
+	return fmt.Sprintf("meta of sequence '%s'.'%s'", t.DatabaseName, t.SequenceName)
+}
+
+// Brief implements task.Brief
+func (t *TaskPolicyMeta) Brief() string {
+	return fmt.Sprintf("meta of placement policy '%s'", t.PolicyName)
+}
+
+// Brief implements task.Brief
+func (t *TaskResourceGroupMeta) Brief() string {
+	return fmt.Sprintf("meta of resource group '%s'", t.ResourceGroupName)
+}
+
 // Brief implements task.Brief
 func (t *TaskTableData) Brief() string {
 	db, tbl := t.Meta.DatabaseName(), t.Meta.TableName()
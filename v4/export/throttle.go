@@ -0,0 +1,168 @@
+// Copyright 2021 PingCAP, Inc. Licensed under Apache-2.0.
+
+package export
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/docker/go-units"
+	"github.com/pingcap/errors"
+)
+
+// rateLimiter is a token bucket which holds at most the tokens of one second. A taker may owe tokens,
+// so that a request larger than the bucket is delayed by the time its tokens are refilled.
+type rateLimiter struct {
+	mu     sync.Mutex
+	rate   float64
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(rate uint64) *rateLimiter {
+	return &rateLimiter{
+		rate:   float64(rate),
+		tokens: float64(rate),
+		last:   time.Now(),
+	}
+}
+
+// wait takes n tokens from the bucket, and blocks until the tokens are refilled or ctx is done
+func (l *rateLimiter) wait(ctx context.Context, n uint64) error {
+	l.mu.Lock()
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.rate {
+		l.tokens = l.rate
+	}
+	l.last = now
+	l.tokens -= float64(n)
+	var delay time.Duration
+	if l.tokens < 0 {
+		delay = time.Duration(-l.tokens / l.rate * float64(time.Second))
+	}
+	l.mu.Unlock()
+	if delay == 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return errors.Trace(ctx.Err())
+	case <-timer.C:
+		return nil
+	}
+}
+
+// throttle limits the bytes written and the rows decoded per second by all the writers, and pauses them
+// while anyone pauses. The pauses only take effect between queries by waitResumed, because a long pause
+// with an open result set exceeds net_write_timeout and MySQL drops the connection.
+// All the methods are safe to be called on a nil throttle, which means no limit.
+type throttle struct {
+	bytes *rateLimiter
+	rows  *rateLimiter
+
+	// paused is 1 if anyone pauses the writers, it's read without the lock on every row
+	paused   int32
+	mu       sync.Mutex
+	pausedBy map[string]struct{}
+	// resumed is closed when the writers are resumed
+	resumed chan struct{}
+}
+
+func newThrottle(maxBytesPerSec, maxRowsPerSec uint64) *throttle {
+	t := &throttle{pausedBy: make(map[string]struct{})}
+	if maxBytesPerSec > 0 {
+		t.bytes = newRateLimiter(maxBytesPerSec)
+	}
+	if maxRowsPerSec > 0 {
+		t.rows = newRateLimiter(maxRowsPerSec)
+	}
+	return t
+}
+
+// pause pauses the writers until all the pausers resume them, returns false if they're already paused by pauser
+func (t *throttle) pause(pauser string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.pausedBy[pauser]; ok {
+		return false
+	}
+	if len(t.pausedBy) == 0 {
+		t.resumed = make(chan struct{})
+		atomic.StoreInt32(&t.paused, 1)
+	}
+	t.pausedBy[pauser] = struct{}{}
+	return true
+}
+
+// resume cancels the pause of pauser, returns false if the writers aren't paused by pauser
+func (t *throttle) resume(pauser string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.pausedBy[pauser]; !ok {
+		return false
+	}
+	delete(t.pausedBy, pauser)
+	if len(t.pausedBy) == 0 {
+		atomic.StoreInt32(&t.paused, 0)
+		close(t.resumed)
+	}
+	return true
+}
+
+// waitResumed blocks until the writers are resumed, it must not be called while reading a result set
+func (t *throttle) waitResumed(ctx context.Context) error {
+	if t == nil || atomic.LoadInt32(&t.paused) == 0 {
+		return nil
+	}
+	t.mu.Lock()
+	resumed := t.resumed
+	t.mu.Unlock()
+	if resumed == nil {
+		return nil
+	}
+	select {
+	case <-ctx.Done():
+		return errors.Trace(ctx.Err())
+	case <-resumed:
+		return nil
+	}
+}
+
+func (t *throttle) waitBytes(ctx context.Context, n int) error {
+	if t == nil || t.bytes == nil {
+		return nil
+	}
+	return t.bytes.wait(ctx, uint64(n))
+}
+
+func (t *throttle) waitRows(ctx context.Context, n uint64) error {
+	if t == nil || t.rows == nil {
+		return nil
+	}
+	return t.rows.wait(ctx, n)
+}
+
+func adjustThrottle(conf *Config) error {
+	if needGovernor(conf) && conf.ThrottleCheckInterval <= 0 {
+		return errors.Errorf("invalid throttle check interval %s", conf.ThrottleCheckInterval)
+	}
+	conf.throttle = newThrottle(conf.MaxBytesPerSec, conf.MaxRowsPerSec)
+	return nil
+}
+
+// ParseBytesPerSec parses the bandwidth limit like '100MiB', 0 or an empty string means no limit
+func ParseBytesPerSec(s string) (uint64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	n, err := units.RAMInBytes(s)
+	if err != nil || n < 0 {
+		return 0, errors.Errorf("failed to parse bandwidth limit %s", s)
+	}
+	return uint64(n), nil
+}
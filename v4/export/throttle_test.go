@@ -0,0 +1,87 @@
+// Copyright 2021 PingCAP, Inc. Licensed under Apache-2.0.
+
+package export
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pingcap/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestThrottle(t *testing.T) {
+	t.Parallel()
+
+	var th *throttle
+	require.NoError(t, th.waitBytes(context.Background(), 1<<30))
+	require.NoError(t, th.waitRows(context.Background(), 1<<30))
+
+	th = newThrottle(0, 100)
+	require.Nil(t, th.bytes)
+	require.NoError(t, th.waitBytes(context.Background(), 1<<30))
+
+	// the bucket is full at first
+	start := time.Now()
+	require.NoError(t, th.waitRows(context.Background(), 100))
+	require.Less(t, time.Since(start), 50*time.Millisecond)
+	// the owed tokens are refilled in 100ms
+	require.NoError(t, th.waitRows(context.Background(), 10))
+	require.GreaterOrEqual(t, time.Since(start), 80*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := th.waitRows(ctx, 100)
+	require.Equal(t, context.Canceled, errors.Cause(err))
+}
+
+func TestThrottlePause(t *testing.T) {
+	t.Parallel()
+
+	th := newThrottle(0, 0)
+	require.True(t, th.pause("governor"))
+	require.False(t, th.pause("governor"))
+	require.True(t, th.pause("user"))
+
+	resumed := make(chan error, 1)
+	go func() {
+		resumed <- th.waitResumed(context.Background())
+	}()
+	require.True(t, th.resume("governor"))
+	select {
+	case <-resumed:
+		require.FailNow(t, "writers are resumed before all the pausers resume them")
+	case <-time.After(50 * time.Millisecond):
+	}
+	require.False(t, th.resume("governor"))
+	require.True(t, th.resume("user"))
+	require.NoError(t, <-resumed)
+
+	th.pause("user")
+	// the pause doesn't block the rows and bytes of the running queries
+	require.NoError(t, th.waitRows(context.Background(), 1))
+	require.NoError(t, th.waitBytes(context.Background(), 1))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := th.waitResumed(ctx)
+	require.Equal(t, context.Canceled, errors.Cause(err))
+}
+
+func TestParseBytesPerSec(t *testing.T) {
+	t.Parallel()
+
+	for s, expected := range map[string]uint64{
+		"":       0,
+		"0":      0,
+		"1024":   1024,
+		"100MiB": 100 << 20,
+		"1GB":    1 << 30,
+	} {
+		n, err := ParseBytesPerSec(s)
+		require.NoError(t, err)
+		require.Equal(t, expected, n)
+	}
+	_, err := ParseBytesPerSec("fast")
+	require.Error(t, err)
+}
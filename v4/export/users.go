@@ -0,0 +1,237 @@
+// Copyright 2021 PingCAP, Inc. Licensed under Apache-2.0.
+
+package export
+
+import (
+	"database/sql"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	tcontext "github.com/pingcap/dumpling/v4/context"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/pingcap/errors"
+	"go.uber.org/zap"
+)
+
+const usersFileName = "users.sql"
+
+// account is a user or a role identified by its name and host
+type account struct {
+	user string
+	host string
+}
+
+// String returns the quoted account which can be used in SQL, like 'user'@'host'
+func (a account) String() string {
+	escape := strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+	return fmt.Sprintf("'%s'@'%s'", escape.Replace(a.user), escape.Replace(a.host))
+}
+
+type userFilterRule struct {
+	user    string
+	host    string
+	exclude bool
+}
+
+// userFilter selects the accounts to dump. Every rule is a pattern like `user@host` which may contain the
+// wildcards of path.Match, the host matches any host if it's omitted, and the rule excludes the matched accounts
+// if it starts with `!`. Like the table filter, the last rule matching an account decides whether it's dumped.
+type userFilter []userFilterRule
+
+func parseUserFilter(patterns []string) (userFilter, error) {
+	f := make(userFilter, 0, len(patterns))
+	for _, pattern := range patterns {
+		rule := userFilterRule{host: "*"}
+		p := strings.TrimSpace(pattern)
+		if strings.HasPrefix(p, "!") {
+			rule.exclude = true
+			p = p[1:]
+		}
+		rule.user = p
+		if i := strings.LastIndexByte(p, '@'); i >= 0 {
+			rule.user, rule.host = p[:i], p[i+1:]
+		}
+		for _, s := range []string{rule.user, rule.host} {
+			if _, err := path.Match(s, ""); err != nil {
+				return nil, errors.Annotatef(err, "invalid users filter '%s'", pattern)
+			}
+		}
+		f = append(f, rule)
+	}
+	return f, nil
+}
+
+func (f userFilter) match(a account) bool {
+	for i := len(f) - 1; i >= 0; i-- {
+		rule := f[i]
+		userMatched, _ := path.Match(rule.user, a.user)
+		hostMatched, _ := path.Match(rule.host, a.host)
+		if userMatched && hostMatched {
+			return !rule.exclude
+		}
+	}
+	return false
+}
+
+func adjustUsersFilter(conf *Config) error {
+	if !conf.DumpUsers {
+		return nil
+	}
+	f, err := parseUserFilter(conf.UsersFilter)
+	if err != nil {
+		return err
+	}
+	conf.usersFilter = f
+	return nil
+}
+
+// dumpUsers writes the accounts matched by the users filter, their privileges and their roles to users.sql.
+// The roles granted to other accounts are created before the other accounts so that the default roles of
+// CREATE USER exist, and all the privileges are granted after all the accounts are created.
+func (d *Dumper) dumpUsers(tctx *tcontext.Context, conn *sql.Conn) error {
+	conf := d.conf
+	accounts, err := listAccounts(conn, conf.usersFilter)
+	if err != nil {
+		return err
+	}
+	// MySQL 8.0.17+ prints the binary password hashes of caching_sha2_password in hex so that they can be
+	// written into a text file, the variable doesn't exist in older MySQL and TiDB
+	if _, err = conn.ExecContext(tctx, "SET SESSION print_identified_with_as_hex = 1"); err != nil && !isUnknownSystemVariableErr(err) {
+		return errors.Annotate(err, "sql: SET SESSION print_identified_with_as_hex = 1")
+	}
+
+	var b strings.Builder
+	for _, a := range accounts {
+		createUserSQL, err := showCreateUser(conn, a)
+		if err != nil {
+			return err
+		}
+		b.WriteString(createUserSQL)
+		b.WriteString(";\n")
+	}
+	for _, a := range accounts {
+		grants, err := showGrants(conn, a)
+		if err != nil {
+			return err
+		}
+		for _, grant := range grants {
+			b.WriteString(grant)
+			b.WriteString(";\n")
+		}
+	}
+	// the default roles are a part of SHOW CREATE USER in MySQL, but not in TiDB
+	if conf.ServerInfo.ServerType == ServerTypeTiDB {
+		defaultRoles, err := listTiDBDefaultRoles(conn)
+		if err != nil {
+			return err
+		}
+		for _, a := range accounts {
+			roles := defaultRoles[a]
+			if len(roles) == 0 {
+				continue
+			}
+			quoted := make([]string, 0, len(roles))
+			for _, role := range roles {
+				quoted = append(quoted, role.String())
+			}
+			fmt.Fprintf(&b, "SET DEFAULT ROLE %s TO %s;\n", strings.Join(quoted, ", "), a)
+		}
+	}
+
+	if err = writeMetaToFile(tctx, "users", b.String(), d.extStore, usersFileName, conf.compressOption()); err != nil {
+		return err
+	}
+	d.manifest.addFile(&ManifestFile{
+		Name: usersFileName + compressFileSuffix(conf.compressType()),
+		Type: ManifestFileTypeUsers,
+	})
+	tctx.L().Info("finish dumping users", zap.Int("accounts", len(accounts)))
+	return nil
+}
+
+// listAccounts lists the accounts matched by f, the roles granted to other accounts are listed first
+func listAccounts(conn *sql.Conn, f userFilter) ([]account, error) {
+	var accounts []account
+	handleOneRow := func(rows *sql.Rows) error {
+		var a account
+		if err := rows.Scan(&a.user, &a.host); err != nil {
+			return errors.Trace(err)
+		}
+		if f.match(a) {
+			accounts = append(accounts, a)
+		}
+		return nil
+	}
+	if err := simpleQuery(conn, "SELECT User, Host FROM mysql.user ORDER BY User, Host", handleOneRow); err != nil {
+		return nil, err
+	}
+
+	roles := make(map[account]struct{})
+	handleRoleRow := func(rows *sql.Rows) error {
+		var a account
+		if err := rows.Scan(&a.user, &a.host); err != nil {
+			return errors.Trace(err)
+		}
+		roles[a] = struct{}{}
+		return nil
+	}
+	// mysql.role_edges doesn't exist before MySQL 8.0
+	err := simpleQuery(conn, "SELECT DISTINCT FROM_USER, FROM_HOST FROM mysql.role_edges", handleRoleRow)
+	if err != nil && !isNoSuchTableErr(err) {
+		return nil, err
+	}
+	sort.SliceStable(accounts, func(i, j int) bool {
+		_, iIsRole := roles[accounts[i]]
+		_, jIsRole := roles[accounts[j]]
+		return iIsRole && !jIsRole
+	})
+	return accounts, nil
+}
+
+// listTiDBDefaultRoles returns the default roles of every account in TiDB
+func listTiDBDefaultRoles(conn *sql.Conn) (map[account][]account, error) {
+	defaultRoles := make(map[account][]account)
+	handleOneRow := func(rows *sql.Rows) error {
+		var a, role account
+		if err := rows.Scan(&a.user, &a.host, &role.user, &role.host); err != nil {
+			return errors.Trace(err)
+		}
+		defaultRoles[a] = append(defaultRoles[a], role)
+		return nil
+	}
+	query := "SELECT USER, HOST, DEFAULT_ROLE_USER, DEFAULT_ROLE_HOST FROM mysql.default_roles ORDER BY DEFAULT_ROLE_USER, DEFAULT_ROLE_HOST"
+	return defaultRoles, simpleQuery(conn, query, handleOneRow)
+}
+
+func isNoSuchTableErr(err error) bool {
+	mysqlErr, ok := errors.Cause(err).(*mysql.MySQLError)
+	return ok && mysqlErr.Number == 1146
+}
+
+// showCreateUser constructs the create user SQL for a specified account, it keeps the authentication plugin and
+// the password hash of the account, and doesn't fail if the account exists
+// returns (createUserSQL, error)
+func showCreateUser(conn *sql.Conn, a account) (string, error) {
+	var createUserSQL string
+	handleOneRow := func(rows *sql.Rows) error {
+		return rows.Scan(&createUserSQL)
+	}
+	query := fmt.Sprintf("SHOW CREATE USER %s", a)
+	if err := simpleQuery(conn, query, handleOneRow); err != nil {
+		return "", err
+	}
+	if !strings.HasPrefix(createUserSQL, "CREATE USER ") {
+		return "", errors.Errorf("unexpected result of %s: %s", query, createUserSQL)
+	}
+	return "CREATE USER IF NOT EXISTS " + strings.TrimPrefix(createUserSQL, "CREATE USER "), nil
+}
+
+// showGrants returns the GRANT statements of the privileges and the roles of a specified account
+func showGrants(conn *sql.Conn, a account) ([]string, error) {
+	grants := oneStrColumnTable{}
+	err := simpleQuery(conn, fmt.Sprintf("SHOW GRANTS FOR %s", a), grants.handleOneRow)
+	return grants.data, err
+}
@@ -0,0 +1,93 @@
+// Copyright 2021 PingCAP, Inc. Licensed under Apache-2.0.
+
+package export
+
+import (
+	"context"
+	"io/ioutil"
+	"path"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	tcontext "github.com/pingcap/dumpling/v4/context"
+	"github.com/pingcap/tidb/br/pkg/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserFilter(t *testing.T) {
+	t.Parallel()
+
+	f, err := parseUserFilter([]string{"*@*", DefaultUsersFilter, "!app_*@localhost", "app_admin"})
+	require.NoError(t, err)
+	testCases := []struct {
+		account account
+		matched bool
+	}{
+		{account{"root", "%"}, true},
+		{account{"mysql.sys", "localhost"}, false},
+		{account{"app_reader", "%"}, true},
+		{account{"app_reader", "localhost"}, false},
+		{account{"app_admin", "localhost"}, true},
+	}
+	for _, testCase := range testCases {
+		require.Equalf(t, testCase.matched, f.match(testCase.account), "account: %s", testCase.account)
+	}
+
+	_, err = parseUserFilter([]string{"app_[@%"})
+	require.Error(t, err)
+
+	require.Equal(t, `'o\'neil'@'%'`, account{"o'neil", "%"}.String())
+}
+
+func TestDumpUsers(t *testing.T) {
+	t.Parallel()
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, db.Close())
+	}()
+	conn, err := db.Conn(context.Background())
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	extStore, err := storage.NewLocalStorage(dir)
+	require.NoError(t, err)
+	conf := defaultConfigForTest(t)
+	conf.DumpUsers = true
+	conf.ServerInfo = ServerInfo{ServerType: ServerTypeMySQL}
+	require.NoError(t, adjustUsersFilter(conf))
+	d := &Dumper{tctx: tcontext.Background().WithLogger(appLogger), conf: conf, extStore: extStore}
+
+	mock.ExpectQuery("SELECT User, Host FROM mysql.user").
+		WillReturnRows(sqlmock.NewRows([]string{"User", "Host"}).
+			AddRow("app", "%").AddRow("mysql.sys", "localhost").AddRow("reader", "%"))
+	mock.ExpectQuery("SELECT DISTINCT FROM_USER, FROM_HOST FROM mysql.role_edges").
+		WillReturnRows(sqlmock.NewRows([]string{"FROM_USER", "FROM_HOST"}).AddRow("reader", "%"))
+	mock.ExpectExec("SET SESSION print_identified_with_as_hex = 1").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta("SHOW CREATE USER 'reader'@'%'")).
+		WillReturnRows(sqlmock.NewRows([]string{"CREATE USER for reader@%"}).
+			AddRow("CREATE USER `reader`@`%` IDENTIFIED WITH 'mysql_native_password' ACCOUNT LOCK"))
+	mock.ExpectQuery(regexp.QuoteMeta("SHOW CREATE USER 'app'@'%'")).
+		WillReturnRows(sqlmock.NewRows([]string{"CREATE USER for app@%"}).
+			AddRow("CREATE USER `app`@`%` IDENTIFIED WITH 'caching_sha2_password' AS 0x24412430 DEFAULT ROLE `reader`@`%`"))
+	mock.ExpectQuery(regexp.QuoteMeta("SHOW GRANTS FOR 'reader'@'%'")).
+		WillReturnRows(sqlmock.NewRows([]string{"Grants for reader@%"}).
+			AddRow("GRANT SELECT ON `test`.* TO `reader`@`%`"))
+	mock.ExpectQuery(regexp.QuoteMeta("SHOW GRANTS FOR 'app'@'%'")).
+		WillReturnRows(sqlmock.NewRows([]string{"Grants for app@%"}).
+			AddRow("GRANT USAGE ON *.* TO `app`@`%`").AddRow("GRANT `reader`@`%` TO `app`@`%`"))
+
+	require.NoError(t, d.dumpUsers(d.tctx, conn))
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	bytes, err := ioutil.ReadFile(path.Join(dir, usersFileName))
+	require.NoError(t, err)
+	require.Equal(t, "/*!40101 SET NAMES binary*/;\n"+
+		"CREATE USER IF NOT EXISTS `reader`@`%` IDENTIFIED WITH 'mysql_native_password' ACCOUNT LOCK;\n"+
+		"CREATE USER IF NOT EXISTS `app`@`%` IDENTIFIED WITH 'caching_sha2_password' AS 0x24412430 DEFAULT ROLE `reader`@`%`;\n"+
+		"GRANT SELECT ON `test`.* TO `reader`@`%`;\n"+
+		"GRANT USAGE ON *.* TO `app`@`%`;\n"+
+		"GRANT `reader`@`%` TO `app`@`%`;\n", string(bytes))
+}
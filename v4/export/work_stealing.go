@@ -0,0 +1,533 @@
+// Copyright 2021 PingCAP, Inc. Licensed under Apache-2.0.
+
+package export
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"strings"
+	"sync"
+
+	tcontext "github.com/pingcap/dumpling/v4/context"
+
+	"github.com/pingcap/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+// keyRangePageRows is the number of rows selected by a query of keyRangeTableData if work stealing is enabled.
+// The remaining range of a chunk can only be split between two queries.
+const keyRangePageRows = 10000
+
+// workStealer hands the tails of running table chunks to idle writers after all the tasks are dispatched.
+// The methods called by writers are safe to be called on a nil workStealer, which means work stealing is disabled.
+type workStealer struct {
+	mu       sync.Mutex
+	taskChan chan<- Task
+	labels   prometheus.Labels
+	listener EventListener
+	// dispatched is set after the dumper sends all the tasks, taskChan is closed when no running chunk can be split
+	dispatched bool
+	closed     bool
+	// running is the number of splittable chunks which are not finished yet
+	running int
+	// idle is the number of writers waiting for tasks
+	idle int
+	// nextChunkIndex allocates the chunk indexes of the split tails of every table
+	nextChunkIndex map[tableKey]int
+	// finishedChunks is the number of finished chunks of every table, including the split tails
+	finishedChunks map[tableKey]int
+}
+
+func newWorkStealer(taskChan chan<- Task, labels prometheus.Labels, listener EventListener) *workStealer {
+	return &workStealer{
+		taskChan:       taskChan,
+		labels:         labels,
+		listener:       listener,
+		nextChunkIndex: make(map[tableKey]int),
+		finishedChunks: make(map[tableKey]int),
+	}
+}
+
+// addChunk is called when a splittable chunk is created
+func (s *workStealer) addChunk() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.running++
+	s.mu.Unlock()
+}
+
+// finishChunk is called after a writer handles a table chunk
+func (s *workStealer) finishChunk(t *TaskTableData) {
+	if s == nil {
+		return
+	}
+	if _, ok := t.Data.(*keyRangeTableData); !ok {
+		return
+	}
+	s.mu.Lock()
+	s.running--
+	s.closeIfDoneLocked()
+	s.mu.Unlock()
+}
+
+// tableFinished is called after a writer finishes a table chunk, it returns whether all the chunks of the table
+// are finished. The split tails have chunk indexes beyond TotalChunks, so they are counted here.
+func (s *workStealer) tableFinished(t *TaskTableData) bool {
+	if s == nil {
+		return t.ChunkIndex+1 == t.TotalChunks
+	}
+	key := tableKey{database: t.Meta.DatabaseName(), table: t.Meta.TableName()}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.finishedChunks[key]++
+	totalChunks, ok := s.nextChunkIndex[key]
+	if !ok {
+		totalChunks = t.TotalChunks
+	}
+	if s.finishedChunks[key] < totalChunks {
+		return false
+	}
+	delete(s.finishedChunks, key)
+	delete(s.nextChunkIndex, key)
+	return true
+}
+
+// finishDispatching is called after the dumper sends all the tasks to taskChan
+func (s *workStealer) finishDispatching() {
+	s.mu.Lock()
+	s.dispatched = true
+	s.closeIfDoneLocked()
+	s.mu.Unlock()
+}
+
+func (s *workStealer) closeIfDoneLocked() {
+	if s.dispatched && s.running == 0 && !s.closed {
+		close(s.taskChan)
+		s.closed = true
+	}
+}
+
+func (s *workStealer) writerIdle() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.idle++
+	s.mu.Unlock()
+}
+
+func (s *workStealer) writerBusy() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.idle--
+	s.mu.Unlock()
+}
+
+// shouldSplit returns whether there are idle writers waiting for more tasks than those in taskChan
+func (s *workStealer) shouldSplit() bool {
+	if s == nil {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.dispatched && !s.closed && s.idle > len(s.taskChan)
+}
+
+// sendTail sends the split tail of a running chunk to taskChan without blocking, returns false if it's not sent
+func (s *workStealer) sendTail(tctx *tcontext.Context, meta TableMeta, td *keyRangeTableData) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return false
+	}
+	key := tableKey{database: meta.DatabaseName(), table: meta.TableName()}
+	chunkIndex, ok := s.nextChunkIndex[key]
+	if !ok {
+		chunkIndex = td.totalChunks
+	}
+	task := NewTaskTableData(meta, td, chunkIndex, td.totalChunks)
+	select {
+	case s.taskChan <- task:
+	default:
+		return false
+	}
+	s.nextChunkIndex[key] = chunkIndex + 1
+	s.running++
+	DecGauge(taskChannelCapacity, s.labels)
+	s.listener.OnTaskDispatched(task)
+	tctx.L().Info("send the tail of a running table chunk to idle writers",
+		zap.String("database", meta.DatabaseName()),
+		zap.String("table", meta.TableName()),
+		zap.Int("chunkIdx", chunkIndex))
+	return true
+}
+
+// newTableChunkData returns the TableDataIR of a table chunk selecting the rows matching where. If the rows are
+// ordered by the non-null keyFields, the chunk can be resumed after the last written row when its query breaks,
+// and if work stealing is enabled, the remaining range of the chunk can be split and handed to idle writers.
+func (d *Dumper) newTableChunkData(meta TableMeta, partition, where, orderByClause string, keyFields []string, totalChunks int) TableDataIR {
+	selectField, selectLen := meta.SelectedField(), meta.SelectedLen()
+	if len(keyFields) == 0 || selectField == "" {
+		query := buildSelectQuery(meta.DatabaseName(), meta.TableName(), selectField, partition, buildWhereCondition(d.conf, where), orderByClause)
+		return newTableData(query, selectLen, false)
+	}
+	d.stealer.addChunk()
+	return newKeyRangeTableData(d.conf, meta, d.stealer, partition, where, orderByClause, keyFields, totalChunks)
+}
+
+// keyRangeTableData is a TableDataIR which selects the rows of a chunk ordered by its keys, and remembers the keys
+// of the last decoded row. If the query breaks between two rows, the chunk is resumed after the last decoded key.
+// With work stealing, the rows are selected by pages. Between two pages the remaining range after the last emitted
+// key can be split, the tail is sent to taskChan as a new chunk and this chunk stops at the split key.
+type keyRangeTableData struct {
+	conf          *Config
+	meta          TableMeta
+	stealer       *workStealer
+	partition     string
+	where         string
+	orderByClause string
+	keyFields     []string
+	keyColTypes   []string
+	quotaKeys     []string
+	// keyPos is the index of every key in the selected fields, -1 means the key is appended to the selected fields
+	keyPos       []int
+	appendedKeys []string
+	totalChunks  int
+	// pageRows is the number of rows selected by a query, 0 means all the rows are selected by one query
+	pageRows int
+	// upper is the exclusive upper bound of keys set by splitting, nil means the chunk isn't split
+	upper []string
+	// resumeAfter is the keys of the last written row when the last attempt is interrupted
+	resumeAfter []string
+	iter        *keyRangeRowIter
+}
+
+func newKeyRangeTableData(conf *Config, meta TableMeta, stealer *workStealer, partition, where, orderByClause string, keyFields []string, totalChunks int) *keyRangeTableData {
+	colName2Type := string2Map(meta.ColumnNames(), meta.ColumnTypes())
+	colName2Pos := make(map[string]int)
+	for i, name := range meta.ColumnNames() {
+		colName2Pos[name] = i
+	}
+	keyColTypes := make([]string, len(keyFields))
+	quotaKeys := make([]string, len(keyFields))
+	keyPos := make([]int, len(keyFields))
+	var appendedKeys []string
+	for i, field := range keyFields {
+		keyColTypes[i] = colName2Type[field]
+		if field == "_tidb_rowid" {
+			keyColTypes[i] = "BIGINT"
+		}
+		quotaKeys[i] = fmt.Sprintf("`%s`", escapeString(field))
+		pos, ok := colName2Pos[field]
+		if !ok {
+			pos = -1
+			appendedKeys = append(appendedKeys, quotaKeys[i])
+		}
+		keyPos[i] = pos
+	}
+	pageRows := 0
+	if stealer != nil {
+		pageRows = keyRangePageRows
+	}
+	return &keyRangeTableData{
+		conf:          conf,
+		meta:          meta,
+		stealer:       stealer,
+		partition:     partition,
+		where:         where,
+		orderByClause: orderByClause,
+		keyFields:     keyFields,
+		keyColTypes:   keyColTypes,
+		quotaKeys:     quotaKeys,
+		keyPos:        keyPos,
+		appendedKeys:  appendedKeys,
+		totalChunks:   totalChunks,
+		pageRows:      pageRows,
+	}
+}
+
+// buildWhere restricts the where of the chunk to keys >= from, keys > after and keys < upper. Nil bounds are ignored.
+func (td *keyRangeTableData) buildWhere(from, after, upper []string) string {
+	var parts []string
+	if td.where != "" {
+		parts = append(parts, td.where)
+	}
+	buf := new(bytes.Buffer)
+	addBound := func(bound []string, compare byte, writeEqual bool) { // revive:disable-line:flag-parameter
+		if bound == nil {
+			return
+		}
+		buildCompareClause(buf, td.quotaKeys, bound, compare, writeEqual)
+		parts = append(parts, buf.String())
+		buf.Reset()
+	}
+	addBound(from, greater, true)
+	addBound(after, greater, false)
+	addBound(upper, less, false)
+	if len(parts) <= 1 {
+		return strings.Join(parts, "")
+	}
+	return "(" + strings.Join(parts, ")and(") + ")"
+}
+
+// query returns the query selecting all the rows of the chunk, which is recorded in checkpoint
+func (td *keyRangeTableData) query() string {
+	return buildSelectQuery(td.meta.DatabaseName(), td.meta.TableName(), td.meta.SelectedField(), td.partition,
+		buildWhereCondition(td.conf, td.buildWhere(nil, nil, td.upper)), td.orderByClause)
+}
+
+// pageQuery returns the query selecting the next page after the key after, the keys which aren't selected
+// are appended to the selected fields
+func (td *keyRangeTableData) pageQuery(after []string) string {
+	fields := td.meta.SelectedField()
+	if len(td.appendedKeys) > 0 {
+		fields += "," + strings.Join(td.appendedKeys, ",")
+	}
+	query := buildSelectQuery(td.meta.DatabaseName(), td.meta.TableName(), fields, td.partition,
+		buildWhereCondition(td.conf, td.buildWhere(nil, after, td.upper)), buildOrderByClauseString(td.keyFields))
+	if td.pageRows == 0 {
+		return query
+	}
+	return fmt.Sprintf("%s LIMIT %d", query, td.pageRows)
+}
+
+// trySplit splits the remaining range after the key after at its middle key if there are idle writers.
+// The tail is sent to idle writers and this chunk stops at the middle key.
+func (td *keyRangeTableData) trySplit(tctx *tcontext.Context, conn *sql.Conn, after []string) error {
+	if !td.stealer.shouldSplit() {
+		return nil
+	}
+	db, tbl := td.meta.DatabaseName(), td.meta.TableName()
+	keys := strings.Join(td.quotaKeys, ",")
+	where := buildWhereCondition(td.conf, td.buildWhere(nil, after, td.upper))
+	remaining := detectEstimateRows(tctx, conn, "EXPLAIN "+buildSelectQuery(db, tbl, keys, td.partition, where, ""),
+		[]string{"rows", "estRows", "count"})
+	if remaining < 2*uint64(td.pageRows) {
+		return nil
+	}
+	query := buildSelectQuery(db, tbl, keys, td.partition, where, buildOrderByClauseString(td.keyFields))
+	query = fmt.Sprintf("%s LIMIT 1 OFFSET %d", query, remaining/2)
+	mid, err := selectHandleVals(tctx, conn, query, td.keyColTypes)
+	if err != nil || len(mid) == 0 {
+		return err
+	}
+	tail := newKeyRangeTableData(td.conf, td.meta, td.stealer, td.partition, td.buildWhere(mid[0], nil, td.upper),
+		td.orderByClause, td.keyFields, td.totalChunks)
+	if td.stealer.sendTail(tctx, td.meta, tail) {
+		td.upper = mid[0]
+	}
+	return nil
+}
+
+// interruptedBy returns whether err is the error which breaks the query of the chunk between two rows.
+// All the rows decoded before the error are written if err is returned by WriteInsert.
+func (td *keyRangeTableData) interruptedBy(err error) bool {
+	if td == nil || td.iter == nil || td.iter.err == nil {
+		return false
+	}
+	return errors.Cause(err) == errors.Cause(td.iter.err)
+}
+
+// prepareResume is called after an attempt to write the chunk fails with err. It returns true if the chunk can be
+// resumed after the last written row by the next Start, otherwise the chunk will be selected from the beginning.
+func (td *keyRangeTableData) prepareResume(err error) bool {
+	if !td.interruptedBy(err) {
+		if td != nil {
+			td.resumeAfter = nil
+		}
+		return false
+	}
+	if td.iter.decoded {
+		td.resumeAfter = td.iter.lastKeyVals()
+	}
+	return true
+}
+
+// Start implements TableDataIR.Start. The chunk is selected after resumeAfter if the last attempt is interrupted.
+func (td *keyRangeTableData) Start(tctx *tcontext.Context, conn *sql.Conn) error {
+	colLen := td.meta.SelectedLen()
+	keyRec := MakeRowReceiver(td.keyColTypes)
+	iter := &keyRangeRowIter{
+		tctx:    tctx,
+		conn:    conn,
+		td:      td,
+		colLen:  colLen,
+		args:    make([]interface{}, colLen+len(td.appendedKeys)),
+		keyArgs: make([]interface{}, len(td.keyFields)),
+		keyRec:  keyRec,
+		lastKey: make([][]byte, len(td.keyFields)),
+	}
+	keyRec.BindAddress(iter.keyArgs)
+	appended := colLen
+	for i, pos := range td.keyPos {
+		if pos < 0 {
+			iter.args[appended] = iter.keyArgs[i]
+			appended++
+		}
+	}
+	td.iter = iter
+	iter.nextPage()
+	return iter.err
+}
+
+// Rows implements TableDataIR.Rows
+func (td *keyRangeTableData) Rows() SQLRowIter {
+	return td.iter
+}
+
+// Close implements TableDataIR.Close
+func (td *keyRangeTableData) Close() error {
+	return td.iter.Close()
+}
+
+// RawRows implements TableDataIR.RawRows
+func (td *keyRangeTableData) RawRows() *sql.Rows {
+	return td.iter.rows
+}
+
+// keyRangeRowIter implements the SQLRowIter interface, it selects the next page after the last emitted key
+// when a page is finished.
+type keyRangeRowIter struct {
+	tctx    *tcontext.Context
+	conn    *sql.Conn
+	td      *keyRangeTableData
+	rows    *sql.Rows
+	hasNext bool
+	colLen  int
+	// args are the addresses of the selected fields followed by the appended keys
+	args []interface{}
+	// keyArgs are the addresses of keyRec, which are used to format the keys
+	keyArgs []interface{}
+	keyRec  RowReceiverArr
+	// lastKey is the raw values of the keys of the last decoded row
+	lastKey  [][]byte
+	decoded  bool
+	pageRead int
+	// err is the error of the queries, which always breaks the chunk between two rows
+	err error
+}
+
+func (iter *keyRangeRowIter) nextPage() {
+	var err error
+	defer func() {
+		if err != nil {
+			iter.hasNext = false
+			iter.err = errors.Trace(err)
+		}
+	}()
+	after := iter.td.resumeAfter
+	if iter.rows != nil {
+		if err = iter.rows.Close(); err != nil {
+			return
+		}
+		if err = iter.rows.Err(); err != nil {
+			return
+		}
+		iter.rows = nil
+		if iter.td.pageRows == 0 || iter.pageRead < iter.td.pageRows {
+			iter.hasNext = false
+			return
+		}
+		after = iter.lastKeyVals()
+		if err = iter.td.trySplit(iter.tctx, iter.conn, after); err != nil {
+			return
+		}
+		// a paged chunk can be paused between two pages
+		if err = iter.td.conf.throttle.waitResumed(iter.tctx); err != nil {
+			return
+		}
+	}
+	query := iter.td.pageQuery(after)
+	iter.tctx.L().Debug("try to start next page of table chunk", zap.String("query", query))
+	rows, err := iter.conn.QueryContext(iter.tctx, query)
+	if err != nil {
+		err = errors.Annotatef(err, "sql: %s", query)
+		return
+	}
+	if err = rows.Err(); err != nil {
+		err = errors.Annotatef(err, "sql: %s", query)
+		return
+	}
+	iter.rows = rows
+	iter.pageRead = 0
+	iter.hasNext = rows.Next()
+}
+
+// lastKeyVals returns the keys of the last decoded row as SQL literals. The literals are sent back to the server,
+// so they are always escaped no matter what conf.EscapeBackslash is.
+func (iter *keyRangeRowIter) lastKeyVals() []string {
+	buf := new(bytes.Buffer)
+	vals := make([]string, len(iter.lastKey))
+	for i, rec := range iter.keyRec.receivers {
+		*iter.keyArgs[i].(*sql.RawBytes) = iter.lastKey[i]
+		rec.WriteToBuffer(buf, true)
+		vals[i] = buf.String()
+		buf.Reset()
+	}
+	return vals
+}
+
+// Decode implements SQLRowIter.Decode
+func (iter *keyRangeRowIter) Decode(row RowReceiver) error {
+	if iter.err != nil {
+		return iter.err
+	}
+	row.BindAddress(iter.args[:iter.colLen])
+	if err := iter.rows.Scan(iter.args...); err != nil {
+		iter.rows.Close()
+		return errors.Trace(err)
+	}
+	for i, pos := range iter.td.keyPos {
+		arg := iter.keyArgs[i]
+		if pos >= 0 {
+			arg = iter.args[pos]
+		}
+		iter.lastKey[i] = append(iter.lastKey[i][:0], boundBytes(arg)...)
+	}
+	iter.decoded = true
+	iter.pageRead++
+	return nil
+}
+
+// Next implements SQLRowIter.Next
+func (iter *keyRangeRowIter) Next() {
+	if iter.err == nil {
+		iter.hasNext = iter.rows.Next()
+		if !iter.hasNext {
+			iter.nextPage()
+		}
+	}
+}
+
+// Error implements SQLRowIter.Error
+func (iter *keyRangeRowIter) Error() error {
+	if iter.err != nil {
+		return iter.err
+	}
+	if iter.rows != nil {
+		return errors.Trace(iter.rows.Err())
+	}
+	return nil
+}
+
+// HasNext implements SQLRowIter.HasNext
+func (iter *keyRangeRowIter) HasNext() bool {
+	return iter.hasNext
+}
+
+// Close implements SQLRowIter.Close
+func (iter *keyRangeRowIter) Close() error {
+	if iter.err != nil {
+		return iter.err
+	}
+	if iter.rows != nil {
+		return iter.rows.Close()
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+// Copyright 2021 PingCAP, Inc. Licensed under Apache-2.0.
+
+package export
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorkStealingSplitRunningChunk(t *testing.T) {
+	t.Parallel()
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, db.Close())
+	}()
+	conn, err := db.Conn(context.Background())
+	require.NoError(t, err)
+
+	conf := defaultConfigForTest(t)
+	conf.OutputDirPath = t.TempDir()
+	writer, clean := createTestWriter(conf, t)
+	defer clean()
+	writer.conn = conn
+
+	taskChan := make(chan Task, 8)
+	stealer := newWorkStealer(taskChan, conf.Labels, NopEventListener{})
+	writer.stealer = stealer
+	finishedTables := 0
+	writer.setFinishTableCallBack(func(Task) { finishedTables++ })
+	meta := &mockTableIR{
+		dbName:        database,
+		tblName:       table,
+		selectedField: "*",
+		selectedLen:   2,
+		colNames:      []string{"id", "v"},
+		colTypes:      []string{"INT", "VARCHAR"},
+		specCmt: []string{
+			"/*!40101 SET NAMES binary*/;",
+		},
+	}
+	d := &Dumper{conf: conf, stealer: stealer}
+	td := d.newTableChunkData(meta, "", "`id`>=0", "ORDER BY `id`", []string{"id"}, 1)
+	require.IsType(t, &keyRangeTableData{}, td)
+	task := NewTaskTableData(meta, td, 0, 1)
+	stealer.finishDispatching()
+	// another writer is waiting for tasks
+	stealer.writerIdle()
+	require.True(t, stealer.shouldSplit())
+
+	firstPage := sqlmock.NewRows([]string{"id", "v"})
+	for i := 1; i <= keyRangePageRows; i++ {
+		firstPage.AddRow(i, "a")
+	}
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `foo`.`bar` WHERE `id`>=0  ORDER BY `id` LIMIT 10000")).
+		WillReturnRows(firstPage)
+	mock.ExpectQuery(regexp.QuoteMeta("EXPLAIN SELECT `id` FROM `foo`.`bar` WHERE (`id`>=0)and(`id`>10000)")).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "select_type", "table", "type", "rows"}).
+			AddRow(1, "SIMPLE", table, "range", 50000))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT `id` FROM `foo`.`bar` WHERE (`id`>=0)and(`id`>10000)  ORDER BY `id` LIMIT 1 OFFSET 25000")).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(35000))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `foo`.`bar` WHERE (`id`>=0)and(`id`>10000)and(`id`<35000)  ORDER BY `id` LIMIT 10000")).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "v"}).AddRow(10001, "b"))
+
+	require.NoError(t, writer.handleTask(task))
+	require.NoError(t, mock.ExpectationsWereMet())
+	require.Len(t, task.Files, 1)
+	require.Equal(t, uint64(keyRangePageRows+1), task.Files[0].Rows)
+	// the chunk stops at the split key, which is recorded in checkpoint
+	require.Equal(t, []string{"SELECT * FROM `foo`.`bar` WHERE (`id`>=0)and(`id`<35000)  ORDER BY `id`"}, tableDataQueries(task.Data))
+
+	// the tail is sent to the idle writer as a new chunk
+	require.Len(t, taskChan, 1)
+	tail := (<-taskChan).(*TaskTableData)
+	require.Equal(t, 1, tail.ChunkIndex)
+	require.Equal(t, 1, tail.TotalChunks)
+	require.Equal(t, []string{"SELECT * FROM `foo`.`bar` WHERE (`id`>=0)and(`id`>=35000)  ORDER BY `id`"}, tableDataQueries(tail.Data))
+	// the table isn't finished until its tail finishes
+	require.Equal(t, 0, finishedTables)
+
+	// taskChan is closed after the tail finishes
+	stealer.writerBusy()
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `foo`.`bar` WHERE (`id`>=0)and(`id`>=35000)  ORDER BY `id` LIMIT 10000")).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "v"}).AddRow(35000, "c"))
+	require.NoError(t, writer.handleTask(tail))
+	require.NoError(t, mock.ExpectationsWereMet())
+	require.Equal(t, fmt.Sprintf("%s.%s.000000001.sql", database, table), tail.Files[0].Name)
+	require.Equal(t, 1, finishedTables)
+	_, ok := <-taskChan
+	require.False(t, ok)
+
+	// chunks are selected by one query without work stealing
+	d.stealer = nil
+	td = d.newTableChunkData(meta, "", "`id`>=0", "ORDER BY `id`", []string{"id"}, 1)
+	require.Equal(t, 0, td.(*keyRangeTableData).pageRows)
+}
+
+func TestWorkStealingEscapeBackslashInKeys(t *testing.T) {
+	t.Parallel()
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, db.Close())
+	}()
+	conn, err := db.Conn(context.Background())
+	require.NoError(t, err)
+
+	conf := defaultConfigForTest(t)
+	conf.OutputDirPath = t.TempDir()
+	// the keys are sent back to the server, --escape-backslash=false only affects the dumped files
+	conf.EscapeBackslash = false
+	writer, clean := createTestWriter(conf, t)
+	defer clean()
+	writer.conn = conn
+
+	taskChan := make(chan Task, 8)
+	stealer := newWorkStealer(taskChan, conf.Labels, NopEventListener{})
+	writer.stealer = stealer
+	meta := &mockTableIR{
+		dbName:        database,
+		tblName:       table,
+		selectedField: "*",
+		selectedLen:   2,
+		colNames:      []string{"id", "v"},
+		colTypes:      []string{"VARCHAR", "INT"},
+		specCmt: []string{
+			"/*!40101 SET NAMES binary*/;",
+		},
+	}
+	d := &Dumper{conf: conf, stealer: stealer}
+	td := d.newTableChunkData(meta, "", "", "ORDER BY `id`", []string{"id"}, 1)
+	task := NewTaskTableData(meta, td, 0, 1)
+	stealer.finishDispatching()
+	stealer.writerIdle()
+
+	firstPage := sqlmock.NewRows([]string{"id", "v"})
+	for i := 1; i < keyRangePageRows; i++ {
+		firstPage.AddRow(fmt.Sprintf("a%05d", i), i)
+	}
+	firstPage.AddRow(`a\x`, keyRangePageRows)
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `foo`.`bar`  ORDER BY `id` LIMIT 10000")).
+		WillReturnRows(firstPage)
+	mock.ExpectQuery(regexp.QuoteMeta("EXPLAIN SELECT `id` FROM `foo`.`bar` WHERE `id`>'a\\\\x'")).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "select_type", "table", "type", "rows"}).
+			AddRow(1, "SIMPLE", table, "range", 50000))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT `id` FROM `foo`.`bar` WHERE `id`>'a\\\\x'  ORDER BY `id` LIMIT 1 OFFSET 25000")).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(`m\x`))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `foo`.`bar` WHERE (`id`>'a\\\\x')and(`id`<'m\\\\x')  ORDER BY `id` LIMIT 10000")).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "v"}).AddRow("b", 10001))
+
+	require.NoError(t, writer.handleTask(task))
+	require.NoError(t, mock.ExpectationsWereMet())
+	require.Equal(t, uint64(keyRangePageRows+1), task.Files[0].Rows)
+	require.Len(t, taskChan, 1)
+	tail := (<-taskChan).(*TaskTableData)
+	require.Equal(t, []string{"SELECT * FROM `foo`.`bar` WHERE `id`>='m\\\\x'  ORDER BY `id`"}, tableDataQueries(tail.Data))
+}
@@ -10,6 +10,7 @@ import (
 	"text/template"
 
 	tcontext "github.com/pingcap/dumpling/v4/context"
+	"github.com/pingcap/dumpling/v4/log"
 
 	"github.com/pingcap/errors"
 	"github.com/pingcap/tidb/br/pkg/storage"
@@ -28,6 +29,13 @@ type Writer struct {
 	fileFmt    FileFormat
 
 	receivedTaskCount int
+	checkpoint        *checkpoint
+	manifest          *manifestCollector
+	checksums         *rowChecksumCollector
+	stealer           *workStealer
+	progress          *progressTracker
+	// retire is closed to make the writer exit after the current task
+	retire chan struct{}
 
 	rebuildConnFn       func(*sql.Conn) (*sql.Conn, error)
 	finishTaskCallBack  func(Task)
@@ -50,6 +58,12 @@ func NewWriter(tctx *tcontext.Context, id int64, config *Config, conn *sql.Conn,
 		sw.fileFmt = FileFormatSQLText
 	case FileFormatCSVString:
 		sw.fileFmt = FileFormatCSV
+	case FileFormatParquetString:
+		sw.fileFmt = FileFormatParquet
+	case FileFormatJSONLString:
+		sw.fileFmt = FileFormatJSONL
+	case FileFormatAvroString:
+		sw.fileFmt = FileFormatAvro
 	}
 	return sw
 }
@@ -72,25 +86,55 @@ func countTotalTask(writers []*Writer) int {
 
 func (w *Writer) run(taskStream <-chan Task) error {
 	for {
+		// select chooses randomly among the ready cases, so the retirement is checked first,
+		// otherwise a retired writer may keep taking tasks while the task stream isn't empty
+		if w.checkRetired() {
+			return nil
+		}
+		w.stealer.writerIdle()
 		select {
 		case <-w.tctx.Done():
 			w.tctx.L().Warn("context has been done, the writer will exit",
 				zap.Int64("writer ID", w.id))
 			return nil
+		case <-w.retire:
+			// the retired writer is no longer idle
+			w.stealer.writerBusy()
+			w.exitRetired()
+			return nil
 		case task, ok := <-taskStream:
 			if !ok {
 				return nil
 			}
+			w.stealer.writerBusy()
 			w.receivedTaskCount++
+			w.progress.startTask(w.id, task)
 			err := w.handleTask(task)
 			if err != nil {
 				return err
 			}
+			w.progress.finishTask(w.id, task)
 			w.finishTaskCallBack(task)
 		}
 	}
 }
 
+// checkRetired returns whether w is retired without blocking, the retired writer is removed from the progress
+func (w *Writer) checkRetired() bool {
+	select {
+	case <-w.retire:
+		w.exitRetired()
+		return true
+	default:
+		return false
+	}
+}
+
+func (w *Writer) exitRetired() {
+	w.progress.removeWriter(w.id)
+	w.tctx.L().Info("writer is retired", zap.Int64("writer ID", w.id))
+}
+
 func (w *Writer) handleTask(task Task) error {
 	switch t := task.(type) {
 	case *TaskDatabaseMeta:
@@ -99,12 +143,46 @@ func (w *Writer) handleTask(task Task) error {
 		return w.WriteTableMeta(t.DatabaseName, t.TableName, t.CreateTableSQL)
 	case *TaskViewMeta:
 		return w.WriteViewMeta(t.DatabaseName, t.ViewName, t.CreateTableSQL, t.CreateViewSQL)
+	case *TaskRoutineMeta:
+		return w.WriteRoutineMeta(t.DatabaseName, t.RoutineName, t.RoutineType, t.CreateRoutineSQL)
+	case *TaskTriggerMeta:
+		return w.WriteTriggerMeta(t.DatabaseName, t.TableName, t.CreateTriggerSQL)
+	case *TaskEventMeta:
+		return w.WriteEventMeta(t.DatabaseName, t.EventName, t.CreateEventSQL)
+	case *TaskSequenceMeta:
+		return w.WriteSequenceMeta(t.DatabaseName, t.SequenceName, t.CreateSequenceSQL)
+	case *TaskPolicyMeta:
+		return w.WritePolicyMeta(t.PolicyName, t.CreatePolicySQL)
+	case *TaskResourceGroupMeta:
+		return w.WriteResourceGroupMeta(t.ResourceGroupName, t.CreateResourceGroupSQL)
 	case *TaskTableData:
-		err := w.WriteTableData(t.Meta, t.Data, t.ChunkIndex)
-		if err != nil {
-			return err
+		defer w.stealer.finishChunk(t)
+		if chunk, ok := w.checkpoint.finishedChunk(t); ok {
+			w.tctx.L().Debug("skip table chunk finished in checkpoint",
+				zap.String("database", t.Meta.DatabaseName()),
+				zap.String("table", t.Meta.TableName()),
+				zap.Int("chunkIdx", t.ChunkIndex))
+			for _, f := range chunk.Files {
+				w.manifest.addFile(f)
+			}
+			t.Files, t.Checksum = chunk.Files, chunk.Checksum
+		} else {
+			err := w.checkpoint.startChunk(w.tctx, t)
+			if err != nil {
+				return err
+			}
+			w.conf.EventListener.OnChunkStarted(t)
+			t.Files, t.Checksum, err = w.writeTableData(t.Meta, t.Data, t.ChunkIndex)
+			if err != nil {
+				return err
+			}
+			if err = w.checkpoint.finishChunk(w.tctx, t); err != nil {
+				return err
+			}
 		}
-		if t.ChunkIndex+1 == t.TotalChunks {
+		w.checksums.add(t)
+		w.conf.EventListener.OnChunkFinished(t)
+		if w.stealer.tableFinished(t) {
 			w.finishTableCallBack(task)
 		}
 		return nil
@@ -116,27 +194,27 @@ func (w *Writer) handleTask(task Task) error {
 
 // WriteDatabaseMeta writes database meta to a file
 func (w *Writer) WriteDatabaseMeta(db, createSQL string) error {
-	tctx, conf := w.tctx, w.conf
+	conf := w.conf
 	fileName, err := (&outputFileNamer{DB: db}).render(conf.OutputFileTemplate, outputFileTemplateSchema)
 	if err != nil {
 		return err
 	}
-	return writeMetaToFile(tctx, db, createSQL, w.extStorage, fileName+".sql", conf.CompressType)
+	return w.writeSchemaFile(outputFileTemplateSchema, db, "", createSQL, fileName)
 }
 
 // WriteTableMeta writes table meta to a file
 func (w *Writer) WriteTableMeta(db, table, createSQL string) error {
-	tctx, conf := w.tctx, w.conf
+	conf := w.conf
 	fileName, err := (&outputFileNamer{DB: db, Table: table}).render(conf.OutputFileTemplate, outputFileTemplateTable)
 	if err != nil {
 		return err
 	}
-	return writeMetaToFile(tctx, db, createSQL, w.extStorage, fileName+".sql", conf.CompressType)
+	return w.writeSchemaFile(outputFileTemplateTable, db, table, createSQL, fileName)
 }
 
 // WriteViewMeta writes view meta to a file
 func (w *Writer) WriteViewMeta(db, view, createTableSQL, createViewSQL string) error {
-	tctx, conf := w.tctx, w.conf
+	conf := w.conf
 	fileNameTable, err := (&outputFileNamer{DB: db, Table: view}).render(conf.OutputFileTemplate, outputFileTemplateTable)
 	if err != nil {
 		return err
@@ -145,19 +223,124 @@ func (w *Writer) WriteViewMeta(db, view, createTableSQL, createViewSQL string) e
 	if err != nil {
 		return err
 	}
-	err = writeMetaToFile(tctx, db, createTableSQL, w.extStorage, fileNameTable+".sql", conf.CompressType)
+	err = w.writeSchemaFile(outputFileTemplateTable, db, view, createTableSQL, fileNameTable)
+	if err != nil {
+		return err
+	}
+	return w.writeSchemaFile(outputFileTemplateView, db, view, createViewSQL, fileNameView)
+}
+
+// WriteRoutineMeta writes stored procedure or function meta to a file, routineType is PROCEDURE or FUNCTION
+func (w *Writer) WriteRoutineMeta(db, routine, routineType, createSQL string) error {
+	conf := w.conf
+	tmpl := outputFileTemplateProcedure
+	if routineType == "FUNCTION" {
+		tmpl = outputFileTemplateFunction
+	}
+	fileName, err := (&outputFileNamer{DB: db, Table: routine}).render(conf.OutputFileTemplate, tmpl)
+	if err != nil {
+		return err
+	}
+	return w.writeSchemaFile(tmpl, db, "", createSQL, fileName)
+}
+
+// WriteTriggerMeta writes the meta of all the triggers of a table to a file
+func (w *Writer) WriteTriggerMeta(db, table, createSQL string) error {
+	conf := w.conf
+	fileName, err := (&outputFileNamer{DB: db, Table: table}).render(conf.OutputFileTemplate, outputFileTemplateTrigger)
+	if err != nil {
+		return err
+	}
+	return w.writeSchemaFile(outputFileTemplateTrigger, db, table, createSQL, fileName)
+}
+
+// WriteEventMeta writes event meta to a file
+func (w *Writer) WriteEventMeta(db, event, createSQL string) error {
+	conf := w.conf
+	fileName, err := (&outputFileNamer{DB: db, Table: event}).render(conf.OutputFileTemplate, outputFileTemplateEvent)
+	if err != nil {
+		return err
+	}
+	return w.writeSchemaFile(outputFileTemplateEvent, db, "", createSQL, fileName)
+}
+
+// WriteSequenceMeta writes sequence meta to a file
+func (w *Writer) WriteSequenceMeta(db, sequence, createSQL string) error {
+	conf := w.conf
+	fileName, err := (&outputFileNamer{DB: db, Table: sequence}).render(conf.OutputFileTemplate, outputFileTemplateSequence)
+	if err != nil {
+		return err
+	}
+	return w.writeSchemaFile(outputFileTemplateSequence, db, sequence, createSQL, fileName)
+}
+
+// WriteResourceGroupMeta writes resource group meta to a file
+func (w *Writer) WriteResourceGroupMeta(resourceGroup, createSQL string) error {
+	conf := w.conf
+	fileName, err := (&outputFileNamer{ResourceGroup: resourceGroup}).render(conf.OutputFileTemplate, outputFileTemplateResGroup)
+	if err != nil {
+		return err
+	}
+	return w.writeSchemaFile(outputFileTemplateResGroup, "", "", createSQL, fileName)
+}
+
+// WritePolicyMeta writes placement policy meta to a file
+func (w *Writer) WritePolicyMeta(policy, createSQL string) error {
+	conf := w.conf
+	fileName, err := (&outputFileNamer{Policy: policy}).render(conf.OutputFileTemplate, outputFileTemplatePolicy)
+	if err != nil {
+		return err
+	}
+	return w.writeSchemaFile(outputFileTemplatePolicy, "", "", createSQL, fileName)
+}
+
+// writeSchemaFile writes a schema file and adds it to the manifest, object is the name of the template of the file
+func (w *Writer) writeSchemaFile(object, db, table, createSQL, fileName string) error {
+	conf := w.conf
+	err := writeMetaToFile(w.tctx, db, createSQL, w.extStorage, fileName+".sql", conf.compressOption())
 	if err != nil {
 		return err
 	}
-	return writeMetaToFile(tctx, db, createViewSQL, w.extStorage, fileNameView+".sql", conf.CompressType)
+	w.manifest.addFile(&ManifestFile{
+		Name:     fileName + ".sql" + compressFileSuffix(conf.compressType()),
+		Type:     ManifestFileTypeSchema,
+		Object:   object,
+		Database: db,
+		Table:    table,
+	})
+	return nil
 }
 
 // WriteTableData writes table data to a file with retry
 func (w *Writer) WriteTableData(meta TableMeta, ir TableDataIR, currentChunk int) error {
+	_, _, err := w.writeTableData(meta, ir, currentChunk)
+	return err
+}
+
+// writeTableData writes table data with retry and returns the written files,
+// and the row checksum of the chunk if row checksum is enabled
+func (w *Writer) writeTableData(meta TableMeta, ir TableDataIR, currentChunk int) ([]*ManifestFile, *rowChecksum, error) {
 	tctx, conf, conn := w.tctx, w.conf, w.conn
 	retryTime := 0
-	var lastErr error
-	return utils.WithRetry(tctx, func() (err error) {
+	var (
+		lastErr  error
+		files    []*ManifestFile
+		checksum *rowChecksum
+	)
+	// a chunk ordered by its keys is resumed after the last written row if its query breaks between two rows,
+	// the files written before are kept, otherwise the chunk is dumped again from the beginning
+	resumable, _ := ir.(*keyRangeTableData)
+	resumeOrReset := func(err error) {
+		if resumable.prepareResume(err) {
+			tctx.L().Info("table chunk will be resumed after the last written row",
+				zap.String("db", meta.DatabaseName()), zap.String("table", meta.TableName()),
+				zap.Int("chunkIndex", currentChunk), zap.Int("writtenFiles", len(files)))
+			return
+		}
+		w.removeFiles(tctx, files)
+		files, checksum = nil, nil
+	}
+	err := utils.WithRetry(tctx, func() (err error) {
 		defer func() {
 			lastErr = err
 			if err != nil {
@@ -169,14 +352,20 @@ func (w *Writer) WriteTableData(meta TableMeta, ir TableDataIR, currentChunk int
 			zap.String("table", meta.TableName()), zap.Int("chunkIndex", currentChunk), zap.NamedError("lastError", lastErr))
 		// don't rebuild connection when dump for the first time
 		if retryTime > 1 {
+			conf.EventListener.OnChunkRetried(meta, currentChunk, retryTime, lastErr)
 			conn, err = w.rebuildConnFn(conn)
 			w.conn = conn
 			if err != nil {
 				return
 			}
 		}
+		// the pauses of the governor and the user take effect before the query of the chunk
+		if err = conf.throttle.waitResumed(tctx); err != nil {
+			return
+		}
 		err = ir.Start(tctx, conn)
 		if err != nil {
+			resumeOrReset(err)
 			return
 		}
 		if conf.SQL != "" {
@@ -186,25 +375,70 @@ func (w *Writer) WriteTableData(meta TableMeta, ir TableDataIR, currentChunk int
 			}
 		}
 		defer ir.Close()
-		return w.tryToWriteTableData(tctx, meta, ir, currentChunk)
-	}, newDumpChunkBackoffer(canRebuildConn(conf.Consistency, conf.TransactionalConsistency)))
+		dataIR := ir
+		if w.checksums != nil {
+			// the checksum is calculated again unless the chunk is resumed
+			if checksum == nil {
+				checksum = &rowChecksum{}
+			}
+			dataIR = newChecksumTableData(ir, checksum)
+		}
+		newFiles, err := w.tryToWriteTableData(tctx, meta, dataIR, currentChunk, len(files), resumable)
+		files = append(files, newFiles...)
+		if err != nil {
+			resumeOrReset(err)
+		}
+		return err
+	}, newDumpChunkBackoffer(&conf.RetryPolicy, canRebuildConn(conf.Consistency, conf.TransactionalConsistency)))
+	return files, checksum, err
 }
 
-func (w *Writer) tryToWriteTableData(tctx *tcontext.Context, meta TableMeta, ir TableDataIR, curChkIdx int) error {
+// tryToWriteTableData writes the rows of ir to the files of the chunk starting from fileIndex, or sends them to
+// conf.RowSink if it's set. If it fails, the files written before are returned with the error, including the last
+// file if resumable is interrupted.
+func (w *Writer) tryToWriteTableData(tctx *tcontext.Context, meta TableMeta, ir TableDataIR, curChkIdx, fileIndex int, resumable *keyRangeTableData) ([]*ManifestFile, error) {
 	conf, format := w.conf, w.fileFmt
+	if conf.RowSink != nil {
+		n, err := writeToRowSink(tctx, conf, meta, ir, curChkIdx)
+		if err == nil {
+			tctx.L().Debug("finish sending table(chunk) to row sink",
+				zap.String("database", meta.DatabaseName()),
+				zap.String("table", meta.TableName()),
+				zap.Int("chunkIdx", curChkIdx),
+				zap.Uint64("total rows", n))
+		}
+		return nil, err
+	}
 	namer := newOutputFileNamer(meta, curChkIdx, conf.Rows != UnspecifiedSize, conf.FileSize != UnspecifiedSize)
+	namer.FileIndex = fileIndex
 	fileName, err := namer.NextName(conf.OutputFileTemplate, w.fileFmt.Extension())
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	somethingIsWritten := false
+	var files []*ManifestFile
+	addFile := func(n uint64) {
+		files = append(files, w.manifest.addFile(&ManifestFile{
+			Name:       fileName + compressFileSuffix(conf.compressType()),
+			Type:       ManifestFileTypeData,
+			Database:   meta.DatabaseName(),
+			Table:      meta.TableName(),
+			ChunkIndex: curChkIdx,
+			Rows:       n,
+		}))
+	}
 	for {
-		fileWriter, tearDown := buildInterceptFileWriter(tctx, w.extStorage, fileName, conf.CompressType)
+		if err = w.checkpoint.createFile(tctx, fileName+compressFileSuffix(conf.compressType())); err != nil {
+			return files, err
+		}
+		fileWriter, tearDown := buildInterceptFileWriter(tctx, w.extStorage, fileName, conf.compressOption())
 		n, err := format.WriteInsert(tctx, conf, meta, ir, fileWriter)
 		tearDown(tctx)
 		if err != nil {
-			return err
+			if n > 0 && resumable.interruptedBy(err) {
+				addFile(n)
+			}
+			return files, err
 		}
 
 		if w, ok := fileWriter.(*InterceptFileWriter); ok && !w.SomethingIsWritten {
@@ -216,27 +450,37 @@ func (w *Writer) tryToWriteTableData(tctx *tcontext.Context, meta TableMeta, ir
 			zap.String("table", meta.TableName()),
 			zap.Int("chunkIdx", curChkIdx),
 			zap.Uint64("total rows", n))
-		somethingIsWritten = true
+		addFile(n)
 
 		if conf.FileSize == UnspecifiedSize {
 			break
 		}
 		fileName, err = namer.NextName(conf.OutputFileTemplate, w.fileFmt.Extension())
 		if err != nil {
-			return err
+			return files, err
 		}
 	}
-	if !somethingIsWritten {
+	if len(files) == 0 {
 		tctx.L().Warn("no data written in table chunk",
 			zap.String("database", meta.DatabaseName()),
 			zap.String("table", meta.TableName()),
 			zap.Int("chunkIdx", curChkIdx))
 	}
-	return nil
+	return files, nil
 }
 
-func writeMetaToFile(tctx *tcontext.Context, target, metaSQL string, s storage.ExternalStorage, path string, compressType storage.CompressType) error {
-	fileWriter, tearDown, err := buildFileWriter(tctx, s, path, compressType)
+// removeFiles removes the data files written by the failed attempts of a table chunk
+func (w *Writer) removeFiles(tctx *tcontext.Context, files []*ManifestFile) {
+	for _, f := range files {
+		if err := w.extStorage.DeleteFile(tctx, f.Name); err != nil {
+			tctx.L().Warn("fail to remove data file", zap.String("file", f.Name), log.ShortError(err))
+		}
+		w.manifest.removeFile(f.Name)
+	}
+}
+
+func writeMetaToFile(tctx *tcontext.Context, target, metaSQL string, s storage.ExternalStorage, path string, opt compressOption) error {
+	fileWriter, tearDown, err := buildFileWriter(tctx, s, path, opt)
 	if err != nil {
 		return errors.Trace(err)
 	}
@@ -252,11 +496,13 @@ func writeMetaToFile(tctx *tcontext.Context, target, metaSQL string, s storage.E
 }
 
 type outputFileNamer struct {
-	ChunkIndex int
-	FileIndex  int
-	DB         string
-	Table      string
-	format     string
+	ChunkIndex    int
+	FileIndex     int
+	DB            string
+	Table         string
+	Policy        string
+	ResourceGroup string
+	format        string
 }
 
 type csvOption struct {
@@ -292,7 +538,12 @@ func (namer *outputFileNamer) render(tmpl *template.Template, subName string) (s
 }
 
 func (namer *outputFileNamer) Index() string {
-	return fmt.Sprintf(namer.format, namer.ChunkIndex, namer.FileIndex)
+	format := namer.format
+	if namer.FileIndex > 0 && format == "%09[1]d" {
+		// a resumed chunk continues in a new file even if the file size isn't limited
+		format = "%09d%04d"
+	}
+	return fmt.Sprintf(format, namer.ChunkIndex, namer.FileIndex)
 }
 
 func (namer *outputFileNamer) NextName(tmpl *template.Template, fileType string) (string, error) {
@@ -0,0 +1,405 @@
+// Copyright 2021 PingCAP, Inc. Licensed under Apache-2.0.
+
+package export
+
+import (
+	"bytes"
+	"compress/flate"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"math"
+	"strconv"
+	"sync"
+
+	tcontext "github.com/pingcap/dumpling/v4/context"
+	"github.com/pingcap/dumpling/v4/log"
+
+	"github.com/golang/snappy"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tidb/br/pkg/storage"
+	"go.uber.org/zap"
+)
+
+const (
+	// AvroCodecNull writes avro data blocks without compression
+	AvroCodecNull = "null"
+	// AvroCodecDeflate compresses avro data blocks with raw deflate
+	AvroCodecDeflate = "deflate"
+	// AvroCodecSnappy compresses avro data blocks with snappy
+	AvroCodecSnappy = "snappy"
+
+	avroSyncMarkerSize = 16
+)
+
+var avroMagic = []byte{'O', 'b', 'j', 1}
+
+// ParseAvroCodec checks whether the given codec is supported by avro object container files
+func ParseAvroCodec(codec string) (string, error) {
+	switch codec {
+	case "", AvroCodecNull:
+		return AvroCodecNull, nil
+	case AvroCodecDeflate, AvroCodecSnappy:
+		return codec, nil
+	default:
+		return "", errors.Errorf("unknown avro codec %s", codec)
+	}
+}
+
+type avroKind int8
+
+const (
+	avroKindString avroKind = iota
+	avroKindBytes
+	avroKindLong
+	avroKindDouble
+)
+
+func (k avroKind) String() string {
+	switch k {
+	case avroKindBytes:
+		return "bytes"
+	case avroKindLong:
+		return "long"
+	case avroKindDouble:
+		return "double"
+	default:
+		return "string"
+	}
+}
+
+// avroColumn describes how a database column is stored in an avro record
+type avroColumn struct {
+	name string
+	kind avroKind
+}
+
+// buildAvroColumns maps the column types from TableMeta.ColumnTypes() to avro fields.
+// DECIMAL columns are kept as strings because their precision and scale are unknown here,
+// and so are BIGINT UNSIGNED columns because avro long can't hold all their values.
+// The field names are made unique by numeric suffixes if the column names are the same after avroName.
+func buildAvroColumns(colNames, colTypes []string) []avroColumn {
+	columns := make([]avroColumn, len(colTypes))
+	usedNames := make(map[string]struct{}, len(colTypes))
+	for i, colType := range colTypes {
+		name := fmt.Sprintf("col%d", i)
+		if i < len(colNames) {
+			name = avroName(colNames[i])
+		}
+		uniqueName := name
+		for suffix := 2; ; suffix++ {
+			if _, ok := usedNames[uniqueName]; !ok {
+				break
+			}
+			uniqueName = fmt.Sprintf("%s_%d", name, suffix)
+		}
+		usedNames[uniqueName] = struct{}{}
+		columns[i].name = uniqueName
+		switch colType {
+		case "FLOAT", "REAL", "DOUBLE", "DOUBLE PRECISION":
+			columns[i].kind = avroKindDouble
+		case "DECIMAL", "NUMERIC", "FIXED", "UNSIGNED BIGINT":
+			columns[i].kind = avroKindString
+		default:
+			if _, ok := dataTypeNum[colType]; ok {
+				columns[i].kind = avroKindLong
+			} else if _, ok := dataTypeBin[colType]; ok {
+				columns[i].kind = avroKindBytes
+			} else {
+				columns[i].kind = avroKindString
+			}
+		}
+	}
+	return columns
+}
+
+// avroName converts an identifier to a valid avro name, which must match [A-Za-z_][A-Za-z0-9_]*
+func avroName(identifier string) string {
+	name := []byte(identifier)
+	for i, c := range name {
+		if !(c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')) {
+			name[i] = '_'
+		}
+	}
+	if len(name) == 0 || (name[0] >= '0' && name[0] <= '9') {
+		return "_" + string(name)
+	}
+	return string(name)
+}
+
+type avroField struct {
+	Name    string      `json:"name"`
+	Type    []string    `json:"type"`
+	Default interface{} `json:"default"`
+}
+
+type avroRecordSchema struct {
+	Type      string      `json:"type"`
+	Name      string      `json:"name"`
+	Namespace string      `json:"namespace,omitempty"`
+	Fields    []avroField `json:"fields"`
+}
+
+// buildAvroSchema returns the avro record schema of a table. Every field is a union
+// with "null" because every column in a database table may contain NULL values.
+func buildAvroSchema(dbName, tblName string, columns []avroColumn) ([]byte, error) {
+	schema := avroRecordSchema{
+		Type:   "record",
+		Name:   avroName(tblName),
+		Fields: make([]avroField, 0, len(columns)),
+	}
+	if dbName != "" {
+		schema.Namespace = avroName(dbName)
+	}
+	for _, col := range columns {
+		schema.Fields = append(schema.Fields, avroField{
+			Name: col.name,
+			Type: []string{"null", col.kind.String()},
+		})
+	}
+	b, err := json.Marshal(schema)
+	return b, errors.Trace(err)
+}
+
+func writeAvroLong(bf *bytes.Buffer, v int64) {
+	var b [binary.MaxVarintLen64]byte
+	n := binary.PutVarint(b[:], v)
+	bf.Write(b[:n])
+}
+
+func writeAvroBytes(bf *bytes.Buffer, p []byte) {
+	writeAvroLong(bf, int64(len(p)))
+	bf.Write(p)
+}
+
+// encode appends the avro binary encoding of a column value to bf
+func (c avroColumn) encode(bf *bytes.Buffer, raw []byte) error {
+	if raw == nil {
+		writeAvroLong(bf, 0)
+		return nil
+	}
+	writeAvroLong(bf, 1)
+	switch c.kind {
+	case avroKindLong:
+		v, err := strconv.ParseInt(string(raw), 10, 64)
+		if err != nil {
+			return errors.Annotatef(err, "column %s can't be stored as avro long", c.name)
+		}
+		writeAvroLong(bf, v)
+	case avroKindDouble:
+		v, err := strconv.ParseFloat(string(raw), 64)
+		if err != nil {
+			return errors.Annotatef(err, "column %s can't be stored as avro double", c.name)
+		}
+		var b [8]byte
+		binary.LittleEndian.PutUint64(b[:], math.Float64bits(v))
+		bf.Write(b[:])
+	default:
+		writeAvroBytes(bf, raw)
+	}
+	return nil
+}
+
+// avroBlockEncoder compresses avro data blocks with the configured codec
+type avroBlockEncoder struct {
+	codec string
+	fw    *flate.Writer
+	dst   []byte
+}
+
+func newAvroBlockEncoder(codec string) (*avroBlockEncoder, error) {
+	e := &avroBlockEncoder{codec: codec}
+	if codec == AvroCodecDeflate {
+		fw, err := flate.NewWriter(nil, flate.DefaultCompression)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		e.fw = fw
+	}
+	return e, nil
+}
+
+// writeBlock writes a data block containing count objects to bf
+func (e *avroBlockEncoder) writeBlock(bf *bytes.Buffer, count int64, data []byte, sync []byte) error {
+	writeAvroLong(bf, count)
+	switch e.codec {
+	case AvroCodecDeflate:
+		var compressed bytes.Buffer
+		e.fw.Reset(&compressed)
+		if _, err := e.fw.Write(data); err != nil {
+			return errors.Trace(err)
+		}
+		if err := e.fw.Close(); err != nil {
+			return errors.Trace(err)
+		}
+		writeAvroBytes(bf, compressed.Bytes())
+	case AvroCodecSnappy:
+		e.dst = snappy.Encode(e.dst[:cap(e.dst)], data)
+		writeAvroLong(bf, int64(len(e.dst)+crc32.Size))
+		bf.Write(e.dst)
+		var checksum [crc32.Size]byte
+		binary.BigEndian.PutUint32(checksum[:], crc32.ChecksumIEEE(data))
+		bf.Write(checksum[:])
+	default:
+		writeAvroBytes(bf, data)
+	}
+	bf.Write(sync)
+	return nil
+}
+
+// writeAvroHeader writes the header of an avro object container file to bf
+func writeAvroHeader(bf *bytes.Buffer, schema []byte, codec string, sync []byte) {
+	bf.Write(avroMagic)
+	writeAvroLong(bf, 2)
+	writeAvroBytes(bf, []byte("avro.schema"))
+	writeAvroBytes(bf, schema)
+	writeAvroBytes(bf, []byte("avro.codec"))
+	writeAvroBytes(bf, []byte(codec))
+	writeAvroLong(bf, 0)
+	bf.Write(sync)
+}
+
+// WriteInsertInAvro writes TableDataIR to a storage.ExternalFileWriter in avro object container file type
+func WriteInsertInAvro(pCtx *tcontext.Context, cfg *Config, meta TableMeta, tblIR TableDataIR, w storage.ExternalFileWriter) (n uint64, err error) {
+	fileRowIter := tblIR.Rows()
+	if !fileRowIter.HasNext() {
+		return 0, fileRowIter.Error()
+	}
+	if meta.SelectedField() == "" {
+		pCtx.L().Warn("skip dumping table(chunk) in avro because it has no selectable column",
+			zap.String("database", meta.DatabaseName()),
+			zap.String("table", meta.TableName()))
+		return 0, nil
+	}
+
+	codec, err := ParseAvroCodec(cfg.AvroCodec)
+	if err != nil {
+		return 0, err
+	}
+	encoder, err := newAvroBlockEncoder(codec)
+	if err != nil {
+		return 0, err
+	}
+	columns := buildAvroColumns(meta.ColumnNames(), meta.ColumnTypes())
+	schema, err := buildAvroSchema(meta.DatabaseName(), meta.TableName(), columns)
+	if err != nil {
+		return 0, err
+	}
+	syncMarker := make([]byte, avroSyncMarkerSize)
+	if _, err = rand.Read(syncMarker); err != nil {
+		return 0, errors.Trace(err)
+	}
+
+	bf := pool.Get().(*bytes.Buffer)
+	if bfCap := bf.Cap(); bfCap < lengthLimit {
+		bf.Grow(lengthLimit - bfCap)
+	}
+
+	wp := newWriterPipe(w, cfg.FileSize, UnspecifiedSize, cfg.Labels, cfg.throttle)
+
+	// use context.Background here to make sure writerPipe can deplete all the chunks in pipeline
+	ctx, cancel := tcontext.Background().WithLogger(pCtx.L()).WithCancel()
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		wp.Run(ctx)
+		wg.Done()
+	}()
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+
+	var (
+		row         = make(rawBytesRow, len(columns))
+		block       bytes.Buffer
+		blockCount  int64
+		counter     uint64
+		lastCounter uint64
+	)
+
+	defer func() {
+		if err != nil {
+			pCtx.L().Warn("fail to dumping table(chunk), will revert some metrics and start a retry if possible",
+				zap.String("database", meta.DatabaseName()),
+				zap.String("table", meta.TableName()),
+				zap.Uint64("finished rows", lastCounter),
+				zap.Uint64("finished size", wp.finishedFileSize),
+				log.ShortError(err))
+			SubGauge(finishedRowsGauge, cfg.Labels, float64(lastCounter))
+			SubGauge(finishedSizeGauge, cfg.Labels, float64(wp.finishedFileSize))
+		} else {
+			pCtx.L().Debug("finish dumping table(chunk)",
+				zap.String("database", meta.DatabaseName()),
+				zap.String("table", meta.TableName()),
+				zap.Uint64("finished rows", counter),
+				zap.Uint64("finished size", wp.finishedFileSize))
+			collectChunkSummary(cfg, wp.finishedFileSize, counter)
+		}
+	}()
+
+	writeAvroHeader(bf, schema, codec, syncMarker)
+	wp.currentFileSize += uint64(bf.Len())
+
+	for fileRowIter.HasNext() {
+		if err = fileRowIter.Decode(row); err != nil {
+			return counter, errors.Trace(err)
+		}
+		if err = cfg.throttle.waitRows(pCtx, 1); err != nil {
+			return counter, err
+		}
+		for i, col := range columns {
+			if err = col.encode(&block, row[i]); err != nil {
+				return counter, err
+			}
+		}
+		counter++
+		blockCount++
+
+		fileRowIter.Next()
+		if block.Len() < lengthLimit && fileRowIter.HasNext() {
+			continue
+		}
+
+		lastBfSize := bf.Len()
+		if err = encoder.writeBlock(bf, blockCount, block.Bytes(), syncMarker); err != nil {
+			return counter, err
+		}
+		wp.currentFileSize += uint64(bf.Len() - lastBfSize)
+		block.Reset()
+		blockCount = 0
+
+		if bf.Len() >= lengthLimit {
+			select {
+			case <-pCtx.Done():
+				return counter, pCtx.Err()
+			case err = <-wp.errCh:
+				return counter, err
+			case wp.input <- bf:
+				bf = pool.Get().(*bytes.Buffer)
+				if bfCap := bf.Cap(); bfCap < lengthLimit {
+					bf.Grow(lengthLimit - bfCap)
+				}
+				AddGauge(finishedRowsGauge, cfg.Labels, float64(counter-lastCounter))
+				lastCounter = counter
+			}
+		}
+
+		if wp.ShouldSwitchFile() {
+			break
+		}
+	}
+	if bf.Len() > 0 {
+		wp.input <- bf
+	}
+	close(wp.input)
+	<-wp.closed
+	AddGauge(finishedRowsGauge, cfg.Labels, float64(counter-lastCounter))
+	lastCounter = counter
+	if err = wp.Error(); err != nil {
+		return counter, err
+	}
+	return counter, errors.Trace(fileRowIter.Error())
+}
@@ -0,0 +1,248 @@
+// Copyright 2021 PingCAP, Inc. Licensed under Apache-2.0.
+
+package export
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+
+	tcontext "github.com/pingcap/dumpling/v4/context"
+	"github.com/pingcap/dumpling/v4/log"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tidb/br/pkg/storage"
+	"github.com/xitongsys/parquet-go/writer"
+	"go.uber.org/zap"
+)
+
+const (
+	// defaultParquetRowGroupSize is the row group size used when --filesize is not specified
+	defaultParquetRowGroupSize = 128 * 1024 * 1024
+	// parquetRowsPerGaugeUpdate controls how often finishedRowsGauge is updated when writing parquet files
+	parquetRowsPerGaugeUpdate = 1024
+)
+
+type parquetKind int8
+
+const (
+	parquetKindString parquetKind = iota
+	parquetKindBinary
+	parquetKindInt64
+	parquetKindUint64
+	parquetKindDouble
+)
+
+var parquetNameReplacer = strings.NewReplacer(",", "_", "=", "_", ".", "_")
+
+// parquetColumn describes how a database column is stored in a parquet file
+type parquetColumn struct {
+	name string
+	kind parquetKind
+}
+
+// metadata returns the parquet-go schema tag of this column. All the columns are optional
+// because every column in a database table may contain NULL values.
+func (c parquetColumn) metadata() string {
+	var tp string
+	switch c.kind {
+	case parquetKindInt64:
+		tp = "type=INT64"
+	case parquetKindUint64:
+		tp = "type=INT64, convertedtype=UINT_64"
+	case parquetKindDouble:
+		tp = "type=DOUBLE"
+	case parquetKindBinary:
+		tp = "type=BYTE_ARRAY"
+	default:
+		tp = "type=BYTE_ARRAY, convertedtype=UTF8"
+	}
+	return fmt.Sprintf("name=%s, %s, repetitiontype=OPTIONAL", parquetNameReplacer.Replace(c.name), tp)
+}
+
+// convert converts the raw bytes read from database to the go value parquet-go expects
+func (c parquetColumn) convert(raw sql.RawBytes) (interface{}, error) {
+	if raw == nil {
+		return nil, nil
+	}
+	switch c.kind {
+	case parquetKindInt64:
+		v, err := strconv.ParseInt(string(raw), 10, 64)
+		if err != nil {
+			return nil, errors.Annotatef(err, "column %s can't be stored as parquet INT64", c.name)
+		}
+		return v, nil
+	case parquetKindUint64:
+		v, err := strconv.ParseUint(string(raw), 10, 64)
+		if err != nil {
+			return nil, errors.Annotatef(err, "column %s can't be stored as parquet UINT_64", c.name)
+		}
+		// UINT_64 is stored in the bits of INT64
+		return int64(v), nil
+	case parquetKindDouble:
+		v, err := strconv.ParseFloat(string(raw), 64)
+		if err != nil {
+			return nil, errors.Annotatef(err, "column %s can't be stored as parquet DOUBLE", c.name)
+		}
+		return v, nil
+	default:
+		return string(raw), nil
+	}
+}
+
+// buildParquetColumns maps the column types from TableMeta.ColumnTypes() to parquet columns.
+// DECIMAL columns are kept as strings to avoid losing precision.
+func buildParquetColumns(colNames, colTypes []string) []parquetColumn {
+	columns := make([]parquetColumn, len(colTypes))
+	for i, colType := range colTypes {
+		if i < len(colNames) {
+			columns[i].name = colNames[i]
+		} else {
+			columns[i].name = fmt.Sprintf("col%d", i)
+		}
+		switch colType {
+		case "FLOAT", "REAL", "DOUBLE", "DOUBLE PRECISION":
+			columns[i].kind = parquetKindDouble
+		case "DECIMAL", "NUMERIC", "FIXED":
+			columns[i].kind = parquetKindString
+		case "UNSIGNED BIGINT":
+			columns[i].kind = parquetKindUint64
+		default:
+			if _, ok := dataTypeNum[colType]; ok {
+				columns[i].kind = parquetKindInt64
+			} else if _, ok := dataTypeBin[colType]; ok {
+				columns[i].kind = parquetKindBinary
+			} else {
+				columns[i].kind = parquetKindString
+			}
+		}
+	}
+	return columns
+}
+
+// rawBytesRow implements RowReceiver which receives a row as raw bytes
+type rawBytesRow []sql.RawBytes
+
+// BindAddress implements RowReceiver.BindAddress
+func (r rawBytesRow) BindAddress(args []interface{}) {
+	for i := range args {
+		args[i] = &r[i]
+	}
+}
+
+// externalFileIOWriter adapts storage.ExternalFileWriter to io.Writer
+type externalFileIOWriter struct {
+	tctx *tcontext.Context
+	w    storage.ExternalFileWriter
+	cfg  *Config
+	size uint64
+}
+
+// Write implements io.Writer
+func (ew *externalFileIOWriter) Write(p []byte) (int, error) {
+	err := ew.cfg.throttle.waitBytes(ew.tctx, len(p))
+	if err == nil {
+		err = writeBytes(ew.tctx, ew.w, p)
+	}
+	if err != nil {
+		return 0, err
+	}
+	AddGauge(finishedSizeGauge, ew.cfg.Labels, float64(len(p)))
+	ew.size += uint64(len(p))
+	return len(p), nil
+}
+
+// WriteInsertInParquet writes TableDataIR to a storage.ExternalFileWriter in parquet type
+func WriteInsertInParquet(pCtx *tcontext.Context, cfg *Config, meta TableMeta, tblIR TableDataIR, w storage.ExternalFileWriter) (n uint64, err error) {
+	fileRowIter := tblIR.Rows()
+	if !fileRowIter.HasNext() {
+		return 0, fileRowIter.Error()
+	}
+	if meta.SelectedField() == "" {
+		pCtx.L().Warn("skip dumping table(chunk) in parquet because it has no selectable column",
+			zap.String("database", meta.DatabaseName()),
+			zap.String("table", meta.TableName()))
+		return 0, nil
+	}
+
+	columns := buildParquetColumns(meta.ColumnNames(), meta.ColumnTypes())
+	metadata := make([]string, len(columns))
+	for i, col := range columns {
+		metadata[i] = col.metadata()
+	}
+
+	var (
+		row         = make(rawBytesRow, len(columns))
+		fw          = &externalFileIOWriter{tctx: pCtx, w: w, cfg: cfg}
+		counter     uint64
+		lastCounter uint64
+	)
+
+	defer func() {
+		if err != nil {
+			pCtx.L().Warn("fail to dumping table(chunk), will revert some metrics and start a retry if possible",
+				zap.String("database", meta.DatabaseName()),
+				zap.String("table", meta.TableName()),
+				zap.Uint64("finished rows", lastCounter),
+				zap.Uint64("finished size", fw.size),
+				log.ShortError(err))
+			SubGauge(finishedRowsGauge, cfg.Labels, float64(lastCounter))
+			SubGauge(finishedSizeGauge, cfg.Labels, float64(fw.size))
+		} else {
+			pCtx.L().Debug("finish dumping table(chunk)",
+				zap.String("database", meta.DatabaseName()),
+				zap.String("table", meta.TableName()),
+				zap.Uint64("finished rows", counter),
+				zap.Uint64("finished size", fw.size))
+			collectChunkSummary(cfg, fw.size, counter)
+		}
+	}()
+
+	pw, err := writer.NewCSVWriterFromWriter(metadata, fw, 1)
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	pw.RowGroupSize = defaultParquetRowGroupSize
+	if cfg.FileSize != UnspecifiedSize {
+		pw.RowGroupSize = int64(cfg.FileSize)
+	}
+
+	for fileRowIter.HasNext() {
+		if err = fileRowIter.Decode(row); err != nil {
+			return counter, errors.Trace(err)
+		}
+		if err = cfg.throttle.waitRows(pCtx, 1); err != nil {
+			return counter, err
+		}
+		rec := make([]interface{}, len(columns))
+		for i, col := range columns {
+			if rec[i], err = col.convert(row[i]); err != nil {
+				return counter, err
+			}
+		}
+		if err = pw.Write(rec); err != nil {
+			return counter, errors.Trace(err)
+		}
+		counter++
+
+		fileRowIter.Next()
+		if counter-lastCounter >= parquetRowsPerGaugeUpdate {
+			if err = pCtx.Err(); err != nil {
+				return counter, err
+			}
+			AddGauge(finishedRowsGauge, cfg.Labels, float64(counter-lastCounter))
+			lastCounter = counter
+		}
+	}
+	// the file is finished before checking the error of the query, so the written rows are kept if only the query breaks
+	if err = pw.WriteStop(); err != nil {
+		return counter, errors.Trace(err)
+	}
+	if err = fileRowIter.Error(); err != nil {
+		return counter, errors.Trace(err)
+	}
+	AddGauge(finishedRowsGauge, cfg.Labels, float64(counter-lastCounter))
+	lastCounter = counter
+	return counter, nil
+}
@@ -0,0 +1,97 @@
+// Copyright 2021 PingCAP, Inc. Licensed under Apache-2.0.
+
+package export
+
+import (
+	"database/sql"
+	"sync"
+
+	tcontext "github.com/pingcap/dumpling/v4/context"
+
+	"github.com/pingcap/errors"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+)
+
+// writerPool runs the writers of a dump. The writers can be added or retired while dumping, a retired writer
+// exits after it finishes the current task.
+type writerPool struct {
+	mu       sync.Mutex
+	tctx     *tcontext.Context
+	wg       *errgroup.Group
+	taskChan <-chan Task
+	// newWriter sets up a writer with the given connection
+	newWriter func(id int64, conn *sql.Conn) *Writer
+	// connect creates a consistent connection for the writers added while dumping
+	connect func() (*sql.Conn, error)
+
+	active  []*Writer
+	retired []*Writer
+	nextID  int64
+	// closed is set after the connections of the writers are closed
+	closed bool
+}
+
+func (p *writerPool) startLocked(conn *sql.Conn) {
+	w := p.newWriter(p.nextID, conn)
+	p.nextID++
+	w.retire = make(chan struct{})
+	w.progress.addWriter(w.id)
+	p.active = append(p.active, w)
+	p.wg.Go(func() error {
+		return w.run(p.taskChan)
+	})
+}
+
+// resize adds or retires writers to make n writers take tasks
+func (p *writerPool) resize(n int) error {
+	if n <= 0 {
+		return errors.Errorf("invalid threads %d, it should be positive", n)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return errors.New("the writers are stopped")
+	}
+	for len(p.active) < n {
+		conn, err := p.connect()
+		if err != nil {
+			return errors.Annotate(err, "fail to add writer")
+		}
+		p.startLocked(conn)
+		p.tctx.L().Info("add writer", zap.Int64("writer ID", p.nextID-1), zap.Int("threads", len(p.active)))
+	}
+	for len(p.active) > n {
+		w := p.active[len(p.active)-1]
+		p.active = p.active[:len(p.active)-1]
+		close(w.retire)
+		p.retired = append(p.retired, w)
+		p.tctx.L().Info("retire writer", zap.Int64("writer ID", w.id), zap.Int("threads", len(p.active)))
+	}
+	return nil
+}
+
+func (p *writerPool) size() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.active)
+}
+
+func (p *writerPool) countTotalTask() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return countTotalTask(p.active) + countTotalTask(p.retired)
+}
+
+// tearDown closes the connections of all the writers, no writer can be added after it
+func (p *writerPool) tearDown() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+	for _, w := range p.active {
+		w.conn.Close()
+	}
+	for _, w := range p.retired {
+		w.conn.Close()
+	}
+}
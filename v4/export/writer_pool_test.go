@@ -0,0 +1,100 @@
+// Copyright 2021 PingCAP, Inc. Licensed under Apache-2.0.
+
+package export
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	tcontext "github.com/pingcap/dumpling/v4/context"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sync/errgroup"
+)
+
+type briefTask string
+
+func (t briefTask) Brief() string {
+	return string(t)
+}
+
+func TestWriterPoolResize(t *testing.T) {
+	t.Parallel()
+
+	db, _, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() {
+		_ = db.Close()
+	}()
+
+	conf := defaultConfigForTest(t)
+	tctx := tcontext.Background().WithLogger(appLogger)
+	progress := newProgressTracker()
+	taskChan := make(chan Task)
+	handled := make(chan int64, 16)
+	wg := new(errgroup.Group)
+	p := &writerPool{
+		tctx:     tctx,
+		wg:       wg,
+		taskChan: taskChan,
+		newWriter: func(id int64, conn *sql.Conn) *Writer {
+			w := NewWriter(tctx, id, conf, conn, nil)
+			w.progress = progress
+			w.setFinishTaskCallBack(func(Task) {
+				handled <- id
+			})
+			return w
+		},
+		connect: func() (*sql.Conn, error) {
+			return db.Conn(context.Background())
+		},
+	}
+
+	require.Error(t, p.resize(0))
+	require.NoError(t, p.resize(3))
+	require.Equal(t, 3, p.size())
+	require.Len(t, progress.status(conf).Writers, 3)
+
+	// the retired writers exit without taking more tasks
+	require.NoError(t, p.resize(1))
+	require.Equal(t, 1, p.size())
+	require.Eventually(t, func() bool {
+		return len(progress.status(conf).Writers) == 1
+	}, time.Second, 10*time.Millisecond)
+	for i := 0; i < 4; i++ {
+		taskChan <- briefTask("task")
+		require.Equal(t, int64(0), <-handled)
+	}
+	require.NoError(t, p.resize(2))
+	require.Equal(t, int64(3), p.active[1].id)
+
+	close(taskChan)
+	require.NoError(t, wg.Wait())
+	require.Equal(t, 4, p.countTotalTask())
+	writers := progress.status(conf).Writers
+	require.Equal(t, []WriterProgress{{ID: 0}, {ID: 3}}, writers)
+
+	p.tearDown()
+	require.Error(t, p.resize(3))
+}
+
+func TestRetiredWriterTakesNoTask(t *testing.T) {
+	t.Parallel()
+
+	conf := defaultConfigForTest(t)
+	tctx := tcontext.Background().WithLogger(appLogger)
+	taskChan := make(chan Task, 16)
+	for i := 0; i < cap(taskChan); i++ {
+		taskChan <- briefTask("task")
+	}
+	w := NewWriter(tctx, 0, conf, nil, nil)
+	w.progress = newProgressTracker()
+	w.retire = make(chan struct{})
+	close(w.retire)
+	// both the retirement and the tasks are ready, the writer must always choose to retire
+	require.NoError(t, w.run(taskChan))
+	require.Equal(t, 0, w.receivedTaskCount)
+	require.Len(t, taskChan, cap(taskChan))
+}
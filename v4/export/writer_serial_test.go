@@ -3,16 +3,24 @@
 package export
 
 import (
+	"bytes"
+	"compress/flate"
 	"database/sql/driver"
+	"encoding/binary"
 	"fmt"
+	"hash/crc32"
+	"io"
 	"strings"
 	"testing"
 
 	tcontext "github.com/pingcap/dumpling/v4/context"
 	"github.com/stretchr/testify/require"
 
+	"github.com/golang/snappy"
 	"github.com/pingcap/errors"
 	"github.com/pingcap/tidb/br/pkg/storage"
+	"github.com/xitongsys/parquet-go-source/buffer"
+	"github.com/xitongsys/parquet-go/reader"
 )
 
 func TestWriteMeta(t *testing.T) {
@@ -322,3 +330,234 @@ func createMockConfig(t *testing.T) (cfg *Config, clean func()) {
 
 	return
 }
+
+func TestWriteInsertInParquet(t *testing.T) {
+	cfg, clean := createMockConfig(t)
+	defer clean()
+
+	data := [][]driver.Value{
+		{"1", "male", "bob@mail.com", "1.5", nil},
+		{"2", "female", "sarah@mail.com", "2.25", "healthy"},
+		{"3", "male", "john@mail.com", nil, "healthy"},
+	}
+	colTypes := []string{"INT", "SET", "VARCHAR", "DOUBLE", "BLOB"}
+	tableIR := newMockTableIR("test", "employee", data, nil, colTypes)
+	tableIR.colNames = []string{"id", "gender", "email", "score", "status"}
+	bf := storage.NewBufferWriter()
+
+	conf := configForWriteSQL(cfg, UnspecifiedSize, UnspecifiedSize)
+	n, err := WriteInsertInParquet(tcontext.Background(), conf, tableIR, tableIR, bf)
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), n)
+	require.True(t, strings.HasPrefix(bf.String(), "PAR1"))
+	require.True(t, strings.HasSuffix(bf.String(), "PAR1"))
+	require.Equal(t, float64(len(data)), ReadGauge(finishedRowsGauge, conf.Labels))
+	require.Equal(t, float64(len(bf.Bytes())), ReadGauge(finishedSizeGauge, conf.Labels))
+
+	pf, err := buffer.NewBufferFile(bf.Bytes())
+	require.NoError(t, err)
+	pr, err := reader.NewParquetColumnReader(pf, 1)
+	require.NoError(t, err)
+	require.Equal(t, int64(3), pr.GetNumRows())
+	ids, _, _, err := pr.ReadColumnByIndex(0, 3)
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{int64(1), int64(2), int64(3)}, ids)
+	scores, _, _, err := pr.ReadColumnByIndex(3, 3)
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{1.5, 2.25, nil}, scores)
+	status, _, _, err := pr.ReadColumnByIndex(4, 3)
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{nil, "healthy", "healthy"}, status)
+	pr.ReadStop()
+
+	RemoveLabelValuesWithTaskInMetrics(conf.Labels)
+}
+
+func TestBuildParquetColumns(t *testing.T) {
+	columns := buildParquetColumns(
+		[]string{"a", "b,c", "d", "e", "f", "g"},
+		[]string{"BIGINT", "DECIMAL", "FLOAT", "VARBINARY", "DATETIME", "UNSIGNED BIGINT"})
+	expected := []string{
+		"name=a, type=INT64, repetitiontype=OPTIONAL",
+		"name=b_c, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL",
+		"name=d, type=DOUBLE, repetitiontype=OPTIONAL",
+		"name=e, type=BYTE_ARRAY, repetitiontype=OPTIONAL",
+		"name=f, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL",
+		"name=g, type=INT64, convertedtype=UINT_64, repetitiontype=OPTIONAL",
+	}
+	for i, col := range columns {
+		require.Equal(t, expected[i], col.metadata())
+	}
+
+	v, err := columns[5].convert([]byte("18446744073709551615"))
+	require.NoError(t, err)
+	require.Equal(t, int64(-1), v)
+	_, err = columns[0].convert([]byte("18446744073709551615"))
+	require.Error(t, err)
+}
+
+func TestWriteInsertInJSONL(t *testing.T) {
+	cfg, clean := createMockConfig(t)
+	defer clean()
+
+	data := [][]driver.Value{
+		{"1", "male", "bob@mail.com", "1.5", nil},
+		{"2", "female", "sarah \"s\"\n@mail.com", nil, []byte{0x00, 0xff}},
+		{"3", "", nil, "-2e+10", []byte("healthy")},
+	}
+	colTypes := []string{"INT", "SET", "VARCHAR", "DECIMAL", "BLOB"}
+	tableIR := newMockTableIR("test", "employee", data, nil, colTypes)
+	tableIR.colNames = []string{"id", "gender", "email", "score", "status"}
+	bf := storage.NewBufferWriter()
+
+	conf := configForWriteSQL(cfg, UnspecifiedSize, UnspecifiedSize)
+	n, err := WriteInsertInJSONL(tcontext.Background(), conf, tableIR, tableIR, bf)
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), n)
+
+	expected := `{"id":1,"gender":"male","email":"bob@mail.com","score":1.5,"status":null}` + "\n" +
+		`{"id":2,"gender":"female","email":"sarah \"s\"\n@mail.com","score":null,"status":"AP8="}` + "\n" +
+		`{"id":3,"gender":"","email":null,"score":-2e+10,"status":"aGVhbHRoeQ=="}` + "\n"
+	require.Equal(t, expected, bf.String())
+	require.Equal(t, float64(len(data)), ReadGauge(finishedRowsGauge, conf.Labels))
+	require.Equal(t, float64(len(expected)), ReadGauge(finishedSizeGauge, conf.Labels))
+
+	RemoveLabelValuesWithTaskInMetrics(conf.Labels)
+}
+
+func TestWriteInsertInAvro(t *testing.T) {
+	cfg, clean := createMockConfig(t)
+	defer clean()
+
+	data := [][]driver.Value{
+		{"1", "male", "bob@mail.com", "1.5", nil},
+		{"-2", "female", nil, nil, []byte{0x00, 0xff}},
+	}
+	colTypes := []string{"INT", "SET", "VARCHAR", "DOUBLE", "BLOB"}
+
+	for _, codec := range []string{AvroCodecNull, AvroCodecDeflate, AvroCodecSnappy} {
+		tableIR := newMockTableIR("test", "employee", data, nil, colTypes)
+		tableIR.colNames = []string{"id", "gender", "e-mail", "score", "status"}
+		bf := storage.NewBufferWriter()
+
+		conf := configForWriteSQL(cfg, UnspecifiedSize, UnspecifiedSize)
+		conf.AvroCodec = codec
+		n, err := WriteInsertInAvro(tcontext.Background(), conf, tableIR, tableIR, bf)
+		require.NoError(t, err)
+		require.Equal(t, uint64(2), n)
+		require.Equal(t, float64(len(data)), ReadGauge(finishedRowsGauge, conf.Labels))
+		require.Equal(t, float64(len(bf.Bytes())), ReadGauge(finishedSizeGauge, conf.Labels))
+
+		header, count, block := readAvroContainerForTest(t, bf.Bytes())
+		require.Equal(t, codec, header["avro.codec"])
+		require.Equal(t, `{"type":"record","name":"employee","namespace":"test","fields":[`+
+			`{"name":"id","type":["null","long"],"default":null},`+
+			`{"name":"gender","type":["null","string"],"default":null},`+
+			`{"name":"e_mail","type":["null","string"],"default":null},`+
+			`{"name":"score","type":["null","double"],"default":null},`+
+			`{"name":"status","type":["null","bytes"],"default":null}]}`, header["avro.schema"])
+		require.Equal(t, int64(2), count)
+
+		var expected bytes.Buffer
+		columns := buildAvroColumns(tableIR.colNames, colTypes)
+		for _, row := range data {
+			for i, v := range row {
+				var raw []byte
+				switch v := v.(type) {
+				case string:
+					raw = []byte(v)
+				case []byte:
+					raw = v
+				}
+				require.NoError(t, columns[i].encode(&expected, raw))
+			}
+		}
+		require.Equal(t, expected.Bytes(), block)
+		RemoveLabelValuesWithTaskInMetrics(conf.Labels)
+	}
+}
+
+func TestAvroColumnEncode(t *testing.T) {
+	var bf bytes.Buffer
+	require.NoError(t, avroColumn{kind: avroKindLong}.encode(&bf, []byte("-65")))
+	require.Equal(t, []byte{0x02, 0x81, 0x01}, bf.Bytes())
+
+	bf.Reset()
+	require.NoError(t, avroColumn{kind: avroKindString}.encode(&bf, []byte("ab")))
+	require.Equal(t, []byte{0x02, 0x04, 'a', 'b'}, bf.Bytes())
+
+	bf.Reset()
+	require.NoError(t, avroColumn{kind: avroKindDouble}.encode(&bf, nil))
+	require.Equal(t, []byte{0x00}, bf.Bytes())
+
+	require.Equal(t, "_1a_b", avroName("1a.b"))
+}
+
+func TestBuildAvroColumns(t *testing.T) {
+	columns := buildAvroColumns(
+		[]string{"a-b", "a_b", "a.b", "a_b_2", "id"},
+		[]string{"VARCHAR", "VARCHAR", "VARCHAR", "VARCHAR", "UNSIGNED BIGINT"})
+	names := make([]string, 0, len(columns))
+	for _, col := range columns {
+		names = append(names, col.name)
+	}
+	require.Equal(t, []string{"a_b", "a_b_2", "a_b_3", "a_b_2_2", "id"}, names)
+
+	require.Equal(t, avroKindString, columns[4].kind)
+	var bf bytes.Buffer
+	require.NoError(t, columns[4].encode(&bf, []byte("18446744073709551615")))
+	require.Equal(t, append([]byte{0x02, 0x28}, "18446744073709551615"...), bf.Bytes())
+}
+
+// readAvroContainerForTest reads the header and the only data block of an avro object container file
+func readAvroContainerForTest(t *testing.T, p []byte) (map[string]string, int64, []byte) {
+	r := bytes.NewReader(p)
+	readBytes := func() []byte {
+		l, err := binary.ReadVarint(r)
+		require.NoError(t, err)
+		b := make([]byte, l)
+		_, err = io.ReadFull(r, b)
+		require.NoError(t, err)
+		return b
+	}
+
+	magic := make([]byte, 4)
+	_, err := io.ReadFull(r, magic)
+	require.NoError(t, err)
+	require.Equal(t, avroMagic, magic)
+	header := make(map[string]string)
+	for {
+		cnt, err := binary.ReadVarint(r)
+		require.NoError(t, err)
+		if cnt == 0 {
+			break
+		}
+		for i := int64(0); i < cnt; i++ {
+			k := readBytes()
+			header[string(k)] = string(readBytes())
+		}
+	}
+	syncMarker := make([]byte, avroSyncMarkerSize)
+	_, err = io.ReadFull(r, syncMarker)
+	require.NoError(t, err)
+
+	count, err := binary.ReadVarint(r)
+	require.NoError(t, err)
+	block := readBytes()
+	switch header["avro.codec"] {
+	case AvroCodecDeflate:
+		block, err = io.ReadAll(flate.NewReader(bytes.NewReader(block)))
+		require.NoError(t, err)
+	case AvroCodecSnappy:
+		checksum := block[len(block)-crc32.Size:]
+		block, err = snappy.Decode(nil, block[:len(block)-crc32.Size])
+		require.NoError(t, err)
+		require.Equal(t, crc32.ChecksumIEEE(block), binary.BigEndian.Uint32(checksum))
+	}
+	blockSync := make([]byte, avroSyncMarkerSize)
+	_, err = io.ReadFull(r, blockSync)
+	require.NoError(t, err)
+	require.Equal(t, syncMarker, blockSync)
+	require.Equal(t, 0, r.Len())
+	return header, count, block
+}
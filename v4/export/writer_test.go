@@ -4,10 +4,13 @@ package export
 
 import (
 	"context"
+	"database/sql"
 	"database/sql/driver"
+	"encoding/json"
 	"io/ioutil"
 	"os"
 	"path"
+	"regexp"
 	"sync"
 	"testing"
 
@@ -90,6 +93,65 @@ func TestWriteViewMeta(t *testing.T) {
 	require.Equal(t, specCmt+createViewSQL, string(bytes))
 }
 
+func TestWriteRoutineTriggerEventMeta(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	config := defaultConfigForTest(t)
+	config.OutputDirPath = dir
+
+	writer, clean := createTestWriter(config, t)
+	defer clean()
+
+	specCmt := "/*!40101 SET NAMES binary*/;\n"
+	// the procedure, function and event of the same name don't overwrite each other
+	files := map[string]string{
+		"test.p-procedure-schema-post.sql": "DELIMITER ;;\nCREATE PROCEDURE `p`() SELECT 1;;\nDELIMITER ;\n",
+		"test.p-function-schema-post.sql":  "DELIMITER ;;\nCREATE FUNCTION `p`() RETURNS int RETURN 1;;\nDELIMITER ;\n",
+		"test.t-schema-triggers.sql":       "DELIMITER ;;\nCREATE TRIGGER `tr1` ...;;\nDELIMITER ;\nDELIMITER ;;\nCREATE TRIGGER `tr2` ...;;\nDELIMITER ;\n",
+		"test.p-event-schema-post.sql":     "DELIMITER ;;\nCREATE EVENT `p` ...;;\nDELIMITER ;\n",
+	}
+	require.NoError(t, writer.WriteRoutineMeta("test", "p", "PROCEDURE", files["test.p-procedure-schema-post.sql"]))
+	require.NoError(t, writer.WriteRoutineMeta("test", "p", "FUNCTION", files["test.p-function-schema-post.sql"]))
+	require.NoError(t, writer.WriteTriggerMeta("test", "t", files["test.t-schema-triggers.sql"]))
+	require.NoError(t, writer.WriteEventMeta("test", "p", files["test.p-event-schema-post.sql"]))
+
+	for fileName, createSQL := range files {
+		bytes, err := ioutil.ReadFile(path.Join(dir, fileName))
+		require.NoError(t, err)
+		require.Equal(t, specCmt+createSQL, string(bytes))
+	}
+}
+
+func TestWriteSequenceAndPolicyMeta(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	config := defaultConfigForTest(t)
+	config.OutputDirPath = dir
+
+	writer, clean := createTestWriter(config, t)
+	defer clean()
+
+	specCmt := "/*!40101 SET NAMES binary*/;\n"
+	createSequenceSQL := "DROP SEQUENCE IF EXISTS `s`;\nCREATE SEQUENCE `s` start with 1;\nSELECT SETVAL(`s`,1001);\n"
+	createPolicySQL := "CREATE PLACEMENT POLICY IF NOT EXISTS `p` PRIMARY_REGION=\"us-east-1\" REGIONS=\"us-east-1\";\n"
+	createResourceGroupSQL := "CREATE RESOURCE GROUP IF NOT EXISTS `rg` RU_PER_SEC=1000;\n"
+	require.NoError(t, writer.WriteSequenceMeta("test", "s", createSequenceSQL))
+	require.NoError(t, writer.WritePolicyMeta("p", createPolicySQL))
+	require.NoError(t, writer.WriteResourceGroupMeta("rg", createResourceGroupSQL))
+
+	bytes, err := ioutil.ReadFile(path.Join(dir, "test.s-schema-sequence.sql"))
+	require.NoError(t, err)
+	require.Equal(t, specCmt+createSequenceSQL, string(bytes))
+	bytes, err = ioutil.ReadFile(path.Join(dir, "p-placement-policy-create.sql"))
+	require.NoError(t, err)
+	require.Equal(t, specCmt+createPolicySQL, string(bytes))
+	bytes, err = ioutil.ReadFile(path.Join(dir, "rg-resource-group-create.sql"))
+	require.NoError(t, err)
+	require.Equal(t, specCmt+createResourceGroupSQL, string(bytes))
+}
+
 func TestWriteTableData(t *testing.T) {
 	t.Parallel()
 
@@ -129,6 +191,144 @@ func TestWriteTableData(t *testing.T) {
 		"(3,'male','john@mail.com','020-1256','healthy'),\n" +
 		"(4,'female','sarah@mail.com','020-1235','healthy');\n"
 	require.Equal(t, expected, string(bytes))
+
+	// the data file is recorded in the checkpoint before it's written
+	writer.checkpoint = newCheckpoint(writer.extStorage, config)
+	tableIR = newMockTableIR("test", "employee", data, specCmts, colTypes)
+	require.NoError(t, writer.WriteTableData(tableIR, tableIR, 1))
+	// the files created later are flushed with the next flush
+	tableIR = newMockTableIR("test", "employee", data, specCmts, colTypes)
+	require.NoError(t, writer.WriteTableData(tableIR, tableIR, 2))
+	require.NoError(t, writer.checkpoint.flush(tcontext.Background()))
+	content, err := ioutil.ReadFile(path.Join(dir, checkpointFileName))
+	require.NoError(t, err)
+	var cp checkpointData
+	require.NoError(t, json.Unmarshal(content, &cp))
+	require.Equal(t, []string{"test.employee.000000001.sql", "test.employee.000000002.sql"}, cp.CreatedFiles)
+}
+
+func TestWriteTableDataResumeInterruptedChunk(t *testing.T) {
+	t.Parallel()
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, db.Close())
+	}()
+	conn, err := db.Conn(context.Background())
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	conf := defaultConfigForTest(t)
+	conf.OutputDirPath = dir
+	conf.Consistency = consistencyTypeNone
+	writer, clean := createTestWriter(conf, t)
+	defer clean()
+	writer.conn = conn
+	writer.rebuildConnFn = func(conn *sql.Conn) (*sql.Conn, error) {
+		return conn, nil
+	}
+	writer.checksums = newRowChecksumCollector()
+
+	meta := &mockTableIR{
+		dbName:        database,
+		tblName:       table,
+		selectedField: "*",
+		selectedLen:   2,
+		colNames:      []string{"id", "v"},
+		colTypes:      []string{"INT", "VARCHAR"},
+		specCmt: []string{
+			"/*!40101 SET NAMES binary*/;",
+		},
+	}
+	d := &Dumper{conf: conf}
+	task := NewTaskTableData(meta, d.newTableChunkData(meta, "", "", "ORDER BY `id`", []string{"id"}, 1), 0, 1)
+
+	// the connection breaks after two rows are sent
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `foo`.`bar` ORDER BY `id`")).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "v"}).
+			AddRow(1, "a").AddRow(2, "b").AddRow(3, "c").
+			RowError(2, driver.ErrBadConn))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `foo`.`bar` WHERE `id`>2 ORDER BY `id`")).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "v"}).AddRow(3, "c"))
+
+	require.NoError(t, writer.handleTask(task))
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	// the rows written before the connection breaks are kept, the chunk continues in a new file
+	require.Len(t, task.Files, 2)
+	expected := []struct {
+		name    string
+		rows    uint64
+		content string
+	}{
+		{"foo.bar.000000000.sql", 2, "/*!40101 SET NAMES binary*/;\nINSERT INTO `bar` VALUES\n(1,'a'),\n(2,'b');\n"},
+		{"foo.bar.0000000000001.sql", 1, "/*!40101 SET NAMES binary*/;\nINSERT INTO `bar` VALUES\n(3,'c');\n"},
+	}
+	for i, f := range expected {
+		require.Equal(t, f.name, task.Files[i].Name)
+		require.Equal(t, f.rows, task.Files[i].Rows)
+		content, err := ioutil.ReadFile(path.Join(dir, f.name))
+		require.NoError(t, err)
+		require.Equal(t, f.content, string(content))
+	}
+	require.Equal(t, uint64(3), task.Checksum.Rows)
+}
+
+func TestWriteTableDataResumeAfterBackslashKey(t *testing.T) {
+	t.Parallel()
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, db.Close())
+	}()
+	conn, err := db.Conn(context.Background())
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	conf := defaultConfigForTest(t)
+	conf.OutputDirPath = dir
+	conf.Consistency = consistencyTypeNone
+	// the key to resume after is sent back to the server, --escape-backslash=false only affects the dumped files
+	conf.EscapeBackslash = false
+	writer, clean := createTestWriter(conf, t)
+	defer clean()
+	writer.conn = conn
+	writer.rebuildConnFn = func(conn *sql.Conn) (*sql.Conn, error) {
+		return conn, nil
+	}
+
+	meta := &mockTableIR{
+		dbName:        database,
+		tblName:       table,
+		selectedField: "*",
+		selectedLen:   1,
+		colNames:      []string{"id"},
+		colTypes:      []string{"VARCHAR"},
+		specCmt: []string{
+			"/*!40101 SET NAMES binary*/;",
+		},
+	}
+	d := &Dumper{conf: conf}
+	task := NewTaskTableData(meta, d.newTableChunkData(meta, "", "", "ORDER BY `id`", []string{"id"}, 1), 0, 1)
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `foo`.`bar` ORDER BY `id`")).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).
+			AddRow(`a\x`).AddRow("ax").
+			RowError(1, driver.ErrBadConn))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `foo`.`bar` WHERE `id`>'a\\\\x' ORDER BY `id`")).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("ax"))
+
+	require.NoError(t, writer.handleTask(task))
+	require.NoError(t, mock.ExpectationsWereMet())
+	require.Len(t, task.Files, 2)
+	content, err := ioutil.ReadFile(path.Join(dir, task.Files[0].Name))
+	require.NoError(t, err)
+	require.Equal(t, "/*!40101 SET NAMES binary*/;\nINSERT INTO `bar` VALUES\n('a\\x');\n", string(content))
+	content, err = ioutil.ReadFile(path.Join(dir, task.Files[1].Name))
+	require.NoError(t, err)
+	require.Equal(t, "/*!40101 SET NAMES binary*/;\nINSERT INTO `bar` VALUES\n('ax');\n", string(content))
 }
 
 func TestWriteTableDataWithFileSize(t *testing.T) {
@@ -34,6 +34,8 @@ type writerPipe struct {
 	closed chan struct{}
 	errCh  chan error
 	labels prometheus.Labels
+	// throttle limits the bytes written by all the writers
+	throttle *throttle
 
 	finishedFileSize     uint64
 	currentFileSize      uint64
@@ -45,13 +47,14 @@ type writerPipe struct {
 	w storage.ExternalFileWriter
 }
 
-func newWriterPipe(w storage.ExternalFileWriter, fileSizeLimit, statementSizeLimit uint64, labels prometheus.Labels) *writerPipe {
+func newWriterPipe(w storage.ExternalFileWriter, fileSizeLimit, statementSizeLimit uint64, labels prometheus.Labels, throttle *throttle) *writerPipe {
 	return &writerPipe{
-		input:  make(chan *bytes.Buffer, 8),
-		closed: make(chan struct{}),
-		errCh:  make(chan error, 1),
-		w:      w,
-		labels: labels,
+		input:    make(chan *bytes.Buffer, 8),
+		closed:   make(chan struct{}),
+		errCh:    make(chan error, 1),
+		w:        w,
+		labels:   labels,
+		throttle: throttle,
 
 		currentFileSize:      0,
 		currentStatementSize: 0,
@@ -74,8 +77,11 @@ func (b *writerPipe) Run(tctx *tcontext.Context) {
 				continue
 			}
 			ObserveHistogram(receiveWriteChunkTimeHistogram, b.labels, time.Since(receiveChunkTime).Seconds())
+			err := b.throttle.waitBytes(tctx, s.Len())
 			receiveChunkTime = time.Now()
-			err := writeBytes(tctx, b.w, s.Bytes())
+			if err == nil {
+				err = writeBytes(tctx, b.w, s.Bytes())
+			}
 			ObserveHistogram(writeTimeHistogram, b.labels, time.Since(receiveChunkTime).Seconds())
 			AddGauge(finishedSizeGauge, b.labels, float64(s.Len()))
 			b.finishedFileSize += uint64(s.Len())
@@ -146,7 +152,7 @@ func WriteInsert(pCtx *tcontext.Context, cfg *Config, meta TableMeta, tblIR Tabl
 		bf.Grow(lengthLimit - bfCap)
 	}
 
-	wp := newWriterPipe(w, cfg.FileSize, cfg.StatementSize, cfg.Labels)
+	wp := newWriterPipe(w, cfg.FileSize, cfg.StatementSize, cfg.Labels, cfg.throttle)
 
 	// use context.Background here to make sure writerPipe can deplete all the chunks in pipeline
 	ctx, cancel := tcontext.Background().WithLogger(pCtx.L()).WithCancel()
@@ -192,8 +198,7 @@ func WriteInsert(pCtx *tcontext.Context, cfg *Config, meta TableMeta, tblIR Tabl
 				zap.String("table", meta.TableName()),
 				zap.Uint64("finished rows", counter),
 				zap.Uint64("finished size", wp.finishedFileSize))
-			summary.CollectSuccessUnit(summary.TotalBytes, 1, wp.finishedFileSize)
-			summary.CollectSuccessUnit("total rows", 1, counter)
+			collectChunkSummary(cfg, wp.finishedFileSize, counter)
 		}
 	}()
 
@@ -220,6 +225,9 @@ func WriteInsert(pCtx *tcontext.Context, cfg *Config, meta TableMeta, tblIR Tabl
 				if err = fileRowIter.Decode(row); err != nil {
 					return counter, errors.Trace(err)
 				}
+				if err = cfg.throttle.waitRows(pCtx, 1); err != nil {
+					return counter, err
+				}
 				row.WriteToBuffer(bf, escapeBackslash)
 			} else {
 				bf.WriteString("()")
@@ -268,10 +276,11 @@ func WriteInsert(pCtx *tcontext.Context, cfg *Config, meta TableMeta, tblIR Tabl
 	<-wp.closed
 	AddGauge(finishedRowsGauge, cfg.Labels, float64(counter-lastCounter))
 	lastCounter = counter
-	if err = fileRowIter.Error(); err != nil {
-		return counter, errors.Trace(err)
+	// the file is complete if only the query breaks, so the error of the writer pipe is checked first
+	if err = wp.Error(); err != nil {
+		return counter, err
 	}
-	return counter, wp.Error()
+	return counter, errors.Trace(fileRowIter.Error())
 }
 
 // WriteInsertInCsv writes TableDataIR to a storage.ExternalFileWriter in csv type
@@ -286,7 +295,7 @@ func WriteInsertInCsv(pCtx *tcontext.Context, cfg *Config, meta TableMeta, tblIR
 		bf.Grow(lengthLimit - bfCap)
 	}
 
-	wp := newWriterPipe(w, cfg.FileSize, UnspecifiedSize, cfg.Labels)
+	wp := newWriterPipe(w, cfg.FileSize, UnspecifiedSize, cfg.Labels, cfg.throttle)
 	opt := &csvOption{
 		nullValue: cfg.CsvNullValue,
 		separator: []byte(cfg.CsvSeparator),
@@ -330,8 +339,7 @@ func WriteInsertInCsv(pCtx *tcontext.Context, cfg *Config, meta TableMeta, tblIR
 				zap.String("table", meta.TableName()),
 				zap.Uint64("finished rows", counter),
 				zap.Uint64("finished size", wp.finishedFileSize))
-			summary.CollectSuccessUnit(summary.TotalBytes, 1, wp.finishedFileSize)
-			summary.CollectSuccessUnit("total rows", 1, counter)
+			collectChunkSummary(cfg, wp.finishedFileSize, counter)
 		}
 	}()
 
@@ -354,6 +362,9 @@ func WriteInsertInCsv(pCtx *tcontext.Context, cfg *Config, meta TableMeta, tblIR
 			if err = fileRowIter.Decode(row); err != nil {
 				return counter, errors.Trace(err)
 			}
+			if err = cfg.throttle.waitRows(pCtx, 1); err != nil {
+				return counter, err
+			}
 			row.WriteToBufferInCsv(bf, escapeBackslash, opt)
 		}
 		counter++
@@ -389,10 +400,145 @@ func WriteInsertInCsv(pCtx *tcontext.Context, cfg *Config, meta TableMeta, tblIR
 	<-wp.closed
 	AddGauge(finishedRowsGauge, cfg.Labels, float64(counter-lastCounter))
 	lastCounter = counter
-	if err = fileRowIter.Error(); err != nil {
-		return counter, errors.Trace(err)
+	if err = wp.Error(); err != nil {
+		return counter, err
+	}
+	return counter, errors.Trace(fileRowIter.Error())
+}
+
+// buildJSONKeys returns the quoted and escaped JSON object keys for the given columns
+func buildJSONKeys(colNames []string, colCount int) [][]byte {
+	keys := make([][]byte, colCount)
+	for i := range keys {
+		var bf bytes.Buffer
+		bf.Write(doubleQuotationMark)
+		if i < len(colNames) {
+			escapeJSON([]byte(colNames[i]), &bf)
+		} else {
+			fmt.Fprintf(&bf, "col%d", i)
+		}
+		bf.Write(doubleQuotationMark)
+		keys[i] = bf.Bytes()
+	}
+	return keys
+}
+
+// WriteInsertInJSONL writes TableDataIR to a storage.ExternalFileWriter in jsonl type, one JSON object per row
+func WriteInsertInJSONL(pCtx *tcontext.Context, cfg *Config, meta TableMeta, tblIR TableDataIR, w storage.ExternalFileWriter) (n uint64, err error) {
+	fileRowIter := tblIR.Rows()
+	if !fileRowIter.HasNext() {
+		return 0, fileRowIter.Error()
+	}
+
+	bf := pool.Get().(*bytes.Buffer)
+	if bfCap := bf.Cap(); bfCap < lengthLimit {
+		bf.Grow(lengthLimit - bfCap)
+	}
+
+	wp := newWriterPipe(w, cfg.FileSize, UnspecifiedSize, cfg.Labels, cfg.throttle)
+
+	// use context.Background here to make sure writerPipe can deplete all the chunks in pipeline
+	ctx, cancel := tcontext.Background().WithLogger(pCtx.L()).WithCancel()
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		wp.Run(ctx)
+		wg.Done()
+	}()
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+
+	var (
+		row            = MakeRowReceiver(meta.ColumnTypes())
+		keys           = buildJSONKeys(meta.ColumnNames(), len(meta.ColumnTypes()))
+		counter        uint64
+		lastCounter    uint64
+		selectedFields = meta.SelectedField()
+	)
+
+	defer func() {
+		if err != nil {
+			pCtx.L().Warn("fail to dumping table(chunk), will revert some metrics and start a retry if possible",
+				zap.String("database", meta.DatabaseName()),
+				zap.String("table", meta.TableName()),
+				zap.Uint64("finished rows", lastCounter),
+				zap.Uint64("finished size", wp.finishedFileSize),
+				log.ShortError(err))
+			SubGauge(finishedRowsGauge, cfg.Labels, float64(lastCounter))
+			SubGauge(finishedSizeGauge, cfg.Labels, float64(wp.finishedFileSize))
+		} else {
+			pCtx.L().Debug("finish dumping table(chunk)",
+				zap.String("database", meta.DatabaseName()),
+				zap.String("table", meta.TableName()),
+				zap.Uint64("finished rows", counter),
+				zap.Uint64("finished size", wp.finishedFileSize))
+			collectChunkSummary(cfg, wp.finishedFileSize, counter)
+		}
+	}()
+
+	for fileRowIter.HasNext() {
+		lastBfSize := bf.Len()
+		if selectedFields != "" {
+			if err = fileRowIter.Decode(row); err != nil {
+				return counter, errors.Trace(err)
+			}
+			if err = cfg.throttle.waitRows(pCtx, 1); err != nil {
+				return counter, err
+			}
+			row.WriteToBufferInJSON(bf, keys)
+		} else {
+			bf.WriteString("{}")
+		}
+		counter++
+		wp.currentFileSize += uint64(bf.Len()-lastBfSize) + 1 // 1 is for "\n"
+
+		bf.WriteByte('\n')
+		if bf.Len() >= lengthLimit {
+			select {
+			case <-pCtx.Done():
+				return counter, pCtx.Err()
+			case err = <-wp.errCh:
+				return counter, err
+			case wp.input <- bf:
+				bf = pool.Get().(*bytes.Buffer)
+				if bfCap := bf.Cap(); bfCap < lengthLimit {
+					bf.Grow(lengthLimit - bfCap)
+				}
+				AddGauge(finishedRowsGauge, cfg.Labels, float64(counter-lastCounter))
+				lastCounter = counter
+			}
+		}
+
+		fileRowIter.Next()
+		if wp.ShouldSwitchFile() {
+			break
+		}
+	}
+
+	if bf.Len() > 0 {
+		wp.input <- bf
+	}
+	close(wp.input)
+	<-wp.closed
+	AddGauge(finishedRowsGauge, cfg.Labels, float64(counter-lastCounter))
+	lastCounter = counter
+	if err = wp.Error(); err != nil {
+		return counter, err
+	}
+	return counter, errors.Trace(fileRowIter.Error())
+}
+
+// collectChunkSummary collects the size and rows of a finished chunk into the summary of the dump.
+// The global collector of br is used if the chunk isn't written by Dumper.Dump.
+func collectChunkSummary(cfg *Config, size, rows uint64) {
+	collect := summary.CollectSuccessUnit
+	if cfg.summary != nil {
+		collect = cfg.summary.CollectSuccessUnit
 	}
-	return counter, wp.Error()
+	collect(summary.TotalBytes, 1, size)
+	collect("total rows", 1, rows)
 }
 
 func write(tctx *tcontext.Context, writer storage.ExternalFileWriter, str string) error {
@@ -429,10 +575,10 @@ func writeBytes(tctx *tcontext.Context, writer storage.ExternalFileWriter, p []b
 	return errors.Trace(err)
 }
 
-func buildFileWriter(tctx *tcontext.Context, s storage.ExternalStorage, fileName string, compressType storage.CompressType) (storage.ExternalFileWriter, func(ctx context.Context), error) {
-	fileName += compressFileSuffix(compressType)
+func buildFileWriter(tctx *tcontext.Context, s storage.ExternalStorage, fileName string, opt compressOption) (storage.ExternalFileWriter, func(ctx context.Context), error) {
+	fileName += compressFileSuffix(opt.compressType)
 	fullPath := path.Join(s.URI(), fileName)
-	writer, err := storage.WithCompression(s, compressType).Create(tctx, fileName)
+	writer, err := createFile(tctx, s, fileName, opt)
 	if err != nil {
 		tctx.L().Error("open file failed",
 			zap.String("path", fullPath),
@@ -453,15 +599,15 @@ func buildFileWriter(tctx *tcontext.Context, s storage.ExternalStorage, fileName
 	return writer, tearDownRoutine, nil
 }
 
-func buildInterceptFileWriter(pCtx *tcontext.Context, s storage.ExternalStorage, fileName string, compressType storage.CompressType) (storage.ExternalFileWriter, func(context.Context)) {
-	fileName += compressFileSuffix(compressType)
+func buildInterceptFileWriter(pCtx *tcontext.Context, s storage.ExternalStorage, fileName string, opt compressOption) (storage.ExternalFileWriter, func(context.Context)) {
+	fileName += compressFileSuffix(opt.compressType)
 	var writer storage.ExternalFileWriter
 	fullPath := path.Join(s.URI(), fileName)
 	fileWriter := &InterceptFileWriter{}
 	initRoutine := func() error {
 		// use separated context pCtx here to make sure context used in ExternalFile won't be canceled before close,
 		// which will cause a context canceled error when closing gcs's Writer
-		w, err := storage.WithCompression(s, compressType).Create(pCtx, fileName)
+		w, err := createFile(pCtx, s, fileName, opt)
 		if err != nil {
 			pCtx.L().Error("open file failed",
 				zap.String("path", fullPath),
@@ -563,18 +709,24 @@ func wrapStringWith(str string, wrapper string) string {
 	return fmt.Sprintf("%s%s%s", wrapper, str, wrapper)
 }
 
-func compressFileSuffix(compressType storage.CompressType) string {
+func compressFileSuffix(compressType CompressType) string {
 	switch compressType {
-	case storage.NoCompression:
+	case CompressNone:
 		return ""
-	case storage.Gzip:
+	case CompressGzip:
 		return ".gz"
+	case CompressZstd:
+		return ".zst"
+	case CompressSnappy:
+		return ".snappy"
+	case CompressLZ4:
+		return ".lz4"
 	default:
 		return ""
 	}
 }
 
-// FileFormat is the format that output to file. Currently we support SQL text and CSV file format.
+// FileFormat is the format that output to file. Currently we support SQL text, CSV, parquet, JSON Lines and avro file format.
 type FileFormat int32
 
 const (
@@ -584,6 +736,12 @@ const (
 	FileFormatSQLText
 	// FileFormatCSV indicates the given file type is csv type
 	FileFormatCSV
+	// FileFormatParquet indicates the given file type is parquet type
+	FileFormatParquet
+	// FileFormatJSONL indicates the given file type is jsonl type
+	FileFormatJSONL
+	// FileFormatAvro indicates the given file type is avro type
+	FileFormatAvro
 )
 
 const (
@@ -591,6 +749,12 @@ const (
 	FileFormatSQLTextString = "sql"
 	// FileFormatCSVString indicates the string/suffix of csv type file
 	FileFormatCSVString = "csv"
+	// FileFormatParquetString indicates the string/suffix of parquet type file
+	FileFormatParquetString = "parquet"
+	// FileFormatJSONLString indicates the string/suffix of jsonl type file
+	FileFormatJSONLString = "jsonl"
+	// FileFormatAvroString indicates the string/suffix of avro type file
+	FileFormatAvroString = "avro"
 )
 
 // String implement Stringer.String method.
@@ -600,32 +764,53 @@ func (f FileFormat) String() string {
 		return strings.ToUpper(FileFormatSQLTextString)
 	case FileFormatCSV:
 		return strings.ToUpper(FileFormatCSVString)
+	case FileFormatParquet:
+		return strings.ToUpper(FileFormatParquetString)
+	case FileFormatJSONL:
+		return strings.ToUpper(FileFormatJSONLString)
+	case FileFormatAvro:
+		return strings.ToUpper(FileFormatAvroString)
 	default:
 		return "unknown"
 	}
 }
 
 // Extension returns the extension for specific format.
-//  text -> "sql"
-//  csv  -> "csv"
+//  text    -> "sql"
+//  csv     -> "csv"
+//  parquet -> "parquet"
+//  jsonl   -> "jsonl"
+//  avro    -> "avro"
 func (f FileFormat) Extension() string {
 	switch f {
 	case FileFormatSQLText:
 		return FileFormatSQLTextString
 	case FileFormatCSV:
 		return FileFormatCSVString
+	case FileFormatParquet:
+		return FileFormatParquetString
+	case FileFormatJSONL:
+		return FileFormatJSONLString
+	case FileFormatAvro:
+		return FileFormatAvroString
 	default:
 		return "unknown_format"
 	}
 }
 
-// WriteInsert writes TableDataIR to a storage.ExternalFileWriter in sql/csv type
+// WriteInsert writes TableDataIR to a storage.ExternalFileWriter in sql/csv/parquet/jsonl/avro type
 func (f FileFormat) WriteInsert(pCtx *tcontext.Context, cfg *Config, meta TableMeta, tblIR TableDataIR, w storage.ExternalFileWriter) (uint64, error) {
 	switch f {
 	case FileFormatSQLText:
 		return WriteInsert(pCtx, cfg, meta, tblIR, w)
 	case FileFormatCSV:
 		return WriteInsertInCsv(pCtx, cfg, meta, tblIR, w)
+	case FileFormatParquet:
+		return WriteInsertInParquet(pCtx, cfg, meta, tblIR, w)
+	case FileFormatJSONL:
+		return WriteInsertInJSONL(pCtx, cfg, meta, tblIR, w)
+	case FileFormatAvro:
+		return WriteInsertInAvro(pCtx, cfg, meta, tblIR, w)
 	default:
 		return 0, errors.Errorf("unknown file format")
 	}